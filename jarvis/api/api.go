@@ -0,0 +1,315 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/blendlabs/go-util/collections"
+	"github.com/blendlabs/go-web"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/modules"
+)
+
+const (
+	// EnvironmentToken is the environment variable for the token the api's POST endpoints require.
+	EnvironmentToken = "JARVIS_API_TOKEN"
+
+	// Prefix is the path the api is served under.
+	Prefix = "/api/v1"
+
+	// AuditUser is the user recorded in the audit log for changes made through the api.
+	AuditUser = "api"
+)
+
+// New returns a new api for the bots `bots` returns. POST endpoints require `token` as a bearer token,
+// and are disabled if it's empty.
+func New(bots func() []core.Bot, token string) *API {
+	return &API{bots: bots, token: token}
+}
+
+// API is the versioned json api for inspecting and operating the bots.
+//
+//	GET  /api/v1/bots
+//	GET  /api/v1/bots/:bot
+//	POST /api/v1/bots/:bot/modules/:module/load
+//	POST /api/v1/bots/:bot/modules/:module/unload
+//	POST /api/v1/bots/:bot/config                 {"key": "option.passive", "value": "on"}
+//	POST /api/v1/bots/:bot/jobs/:job/run
+//	POST /api/v1/bots/:bot/jobs/:job/enable
+//	POST /api/v1/bots/:bot/jobs/:job/disable
+//
+// `:bot` is a bot's id or organization name.
+type API struct {
+	bots  func() []core.Bot
+	token string
+}
+
+// Handler returns the api as an http handler.
+func (a *API) Handler() http.Handler {
+	app := web.New()
+	app.Register(a)
+	return app
+}
+
+// Register implements web.Controller.
+func (a *API) Register(app *web.App) {
+	app.GET(Prefix+"/bots", a.getBots, web.APIProviderAsDefault)
+	app.GET(Prefix+"/bots/:bot", a.getBot, web.APIProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/modules/:module/load", a.loadModule, a.requireToken, web.APIProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/modules/:module/unload", a.unloadModule, a.requireToken, web.APIProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/config", a.setConfig, a.requireToken, web.APIProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/jobs/:job/run", a.runJob, a.requireToken, web.APIProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/jobs/:job/enable", a.enableJob, a.requireToken, web.APIProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/jobs/:job/disable", a.disableJob, a.requireToken, web.APIProviderAsDefault)
+}
+
+// BotSummary is a bot in the bot list.
+type BotSummary struct {
+	ID               string `json:"id"`
+	OrganizationName string `json:"organization_name"`
+	Connected        bool   `json:"connected"`
+}
+
+// BotStatus is the detail of a bot.
+type BotStatus struct {
+	BotSummary
+	LoadedModules     []string          `json:"loaded_modules"`
+	RegisteredModules []string          `json:"registered_modules"`
+	Actions           []ActionStatus    `json:"actions"`
+	Configuration     map[string]string `json:"configuration"`
+	Jobs              []JobStatus       `json:"jobs"`
+	ActiveChannels    []ChannelStatus   `json:"active_channels"`
+}
+
+// ActionStatus is an action a bot has loaded.
+type ActionStatus struct {
+	ID             string `json:"id"`
+	MessagePattern string `json:"message_pattern,omitempty"`
+	Reaction       string `json:"reaction,omitempty"`
+	Description    string `json:"description"`
+	Passive        bool   `json:"passive,omitempty"`
+	Priority       int    `json:"priority"`
+}
+
+// JobStatus is a job a bot has loaded.
+type JobStatus struct {
+	Name       string       `json:"name"`
+	State      string       `json:"state"`
+	Disabled   bool         `json:"disabled"`
+	RunningFor string       `json:"running_for,omitempty"`
+	NextRun    string       `json:"next_run,omitempty"`
+	LastRun    *core.JobRun `json:"last_run,omitempty"`
+}
+
+// ChannelStatus is a channel a bot is listening to.
+type ChannelStatus struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// ConfigRequest is the body of a config change.
+type ConfigRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ConfigResponse is the config entry after a change.
+type ConfigResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// NewBotSummary returns the summary of a bot.
+func NewBotSummary(b core.Bot) BotSummary {
+	return BotSummary{ID: b.ID(), OrganizationName: b.OrganizationName(), Connected: b.IsConnected()}
+}
+
+// NewBotStatus returns the detail of a bot.
+func NewBotStatus(b core.Bot) BotStatus {
+	status := BotStatus{
+		BotSummary:        NewBotSummary(b),
+		LoadedModules:     sortedStrings(b.LoadedModules()),
+		RegisteredModules: sortedStrings(b.RegisteredModules()),
+		Actions:           []ActionStatus{},
+		Configuration:     modules.RedactConfiguration(b.Configuration()),
+		Jobs:              []JobStatus{},
+		ActiveChannels:    []ChannelStatus{},
+	}
+	for _, action := range b.Actions() {
+		status.Actions = append(status.Actions, ActionStatus{
+			ID:             action.ID,
+			MessagePattern: action.MessagePattern,
+			Reaction:       action.Reaction,
+			Description:    action.Description,
+			Passive:        action.Passive,
+			Priority:       action.Priority,
+		})
+	}
+	for _, task := range b.JobManager().Status() {
+		status.Jobs = append(status.Jobs, JobStatus{
+			Name:       task.Name,
+			State:      task.State,
+			Disabled:   b.JobManager().IsDisabled(task.Name),
+			RunningFor: task.RunningFor,
+			NextRun:    task.NextRunTime,
+			LastRun:    b.JobHistory().Last(task.Name),
+		})
+	}
+	for _, channelID := range b.ActiveChannels() {
+		channel := ChannelStatus{ID: channelID}
+		if found := b.FindChannel(channelID); found != nil {
+			channel.Name = found.Name
+		}
+		status.ActiveChannels = append(status.ActiveChannels, channel)
+	}
+	return status
+}
+
+func (a *API) getBots(ctx *web.Ctx) web.Result {
+	summaries := []BotSummary{}
+	for _, b := range a.bots() {
+		summaries = append(summaries, NewBotSummary(b))
+	}
+	return ctx.API().Result(summaries)
+}
+
+func (a *API) getBot(ctx *web.Ctx) web.Result {
+	b := a.findBot(ctx)
+	if b == nil {
+		return ctx.API().NotFound()
+	}
+	return ctx.API().Result(NewBotStatus(b))
+}
+
+func (a *API) loadModule(ctx *web.Ctx) web.Result {
+	return a.change(ctx, modules.ActionModuleLoad, "module", func(b core.Bot, name string) (interface{}, error) {
+		if err := modules.LoadModule(b, name); err != nil {
+			return nil, err
+		}
+		return NewBotStatus(b), nil
+	})
+}
+
+func (a *API) unloadModule(ctx *web.Ctx) web.Result {
+	return a.change(ctx, modules.ActionModuleUnload, "module", func(b core.Bot, name string) (interface{}, error) {
+		if err := modules.UnloadModule(b, name); err != nil {
+			return nil, err
+		}
+		return NewBotStatus(b), nil
+	})
+}
+
+func (a *API) setConfig(ctx *web.Ctx) web.Result {
+	var req ConfigRequest
+	if err := ctx.PostBodyAsJSON(&req); err != nil {
+		return ctx.API().BadRequest("the body should be json, e.g. {\"key\": \"option.passive\", \"value\": \"on\"}")
+	}
+	if core.IsEmpty(req.Key) {
+		return ctx.API().BadRequest("`key` is required")
+	}
+	return a.record(ctx, modules.ActionConfigSet, modules.AuditConfigArguments(req.Key, req.Value), func(b core.Bot) (interface{}, error) {
+		value := modules.SetConfig(b, req.Key, req.Value)
+		if modules.IsSecretConfig(req.Key) {
+			value = modules.RedactedConfigValue
+		}
+		return ConfigResponse{Key: req.Key, Value: value}, nil
+	})
+}
+
+func (a *API) runJob(ctx *web.Ctx) web.Result {
+	return a.change(ctx, modules.ActionJobRun, "job", func(b core.Bot, name string) (interface{}, error) {
+		return nil, modules.RunJob(b, name)
+	})
+}
+
+func (a *API) enableJob(ctx *web.Ctx) web.Result {
+	return a.change(ctx, modules.ActionJobEnable, "job", func(b core.Bot, name string) (interface{}, error) {
+		return nil, modules.EnableJob(b, name)
+	})
+}
+
+func (a *API) disableJob(ctx *web.Ctx) web.Result {
+	return a.change(ctx, modules.ActionJobDisable, "job", func(b core.Bot, name string) (interface{}, error) {
+		return nil, modules.DisableJob(b, name)
+	})
+}
+
+// change runs a change against the bot in the route, with the `param` route parameter (if any),
+// and records it in the bot's audit log as if the matching chat action had been run.
+func (a *API) change(ctx *web.Ctx, actionID, param string, action func(b core.Bot, name string) (interface{}, error)) web.Result {
+	var name string
+	if len(param) != 0 {
+		name, _ = ctx.RouteParam(param)
+	}
+	return a.record(ctx, actionID, name, func(b core.Bot) (interface{}, error) {
+		return action(b, name)
+	})
+}
+
+// record runs a change against the bot in the route and records it in the bot's audit log with `arguments`.
+func (a *API) record(ctx *web.Ctx, actionID, arguments string, action func(b core.Bot) (interface{}, error)) web.Result {
+	b := a.findBot(ctx)
+	if b == nil {
+		return ctx.API().NotFound()
+	}
+
+	var response interface{}
+	err := b.AuditLog().Record(AuditUser, actionID, arguments, func() (err error) {
+		response, err = action(b)
+		return
+	})
+	if err != nil {
+		return ctx.API().BadRequest(err.Error())
+	}
+	if response == nil {
+		return ctx.API().OK()
+	}
+	return ctx.API().Result(response)
+}
+
+func (a *API) findBot(ctx *web.Ctx) core.Bot {
+	id, err := ctx.RouteParam("bot")
+	if err != nil {
+		return nil
+	}
+	return FindBot(a.bots(), id)
+}
+
+// FindBot returns the bot with an id or organization name, or nil if there isn't one.
+func FindBot(bots []core.Bot, id string) core.Bot {
+	for _, b := range bots {
+		if b.ID() == id || strings.EqualFold(b.OrganizationName(), id) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (a *API) requireToken(action web.Action) web.Action {
+	return func(ctx *web.Ctx) web.Result {
+		if !a.authorized(ctx.Request) {
+			return ctx.API().NotAuthorized()
+		}
+		return action(ctx)
+	}
+}
+
+func (a *API) authorized(r *http.Request) bool {
+	if len(a.token) == 0 {
+		return false
+	}
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+func sortedStrings(set collections.SetOfString) []string {
+	values := set.AsSlice()
+	sort.Strings(values)
+	return values
+}
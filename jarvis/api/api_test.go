@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-chronometer"
+	"github.com/blendlabs/go-web"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/modules"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+type testJob struct{}
+
+func (tj testJob) Name() string                                    { return "test" }
+func (tj testJob) Schedule() chronometer.Schedule                  { return chronometer.OnDemand() }
+func (tj testJob) Execute(ct *chronometer.CancellationToken) error { return nil }
+
+type botResponse struct {
+	Meta     web.APIResponseMeta
+	Response BotStatus
+}
+
+func testAPI(assert *assert.Assertions) (*web.App, *core.MockBot) {
+	b := core.NewMockBot(slack.UUIDv4().ToShortString())
+	b.RegisterModule(new(modules.Util))
+	b.SetConfig("jira_credentials", "user:hunter2")
+	b.SetConfig(modules.ConfigOptionPassive, "true")
+	assert.Nil(b.LoadJob(testJob{}))
+
+	app := web.New()
+	app.Register(New(func() []core.Bot { return []core.Bot{b} }, "sekrit"))
+	return app, b
+}
+
+func TestGetBots(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testAPI(assert)
+
+	var bots struct {
+		Response []BotSummary
+	}
+	meta, err := app.Mock().Get("/api/v1/bots").JSONWithMeta(&bots)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Len(bots.Response, 1)
+	assert.Equal(b.ID(), bots.Response[0].ID)
+	assert.True(bots.Response[0].Connected)
+
+	var bot botResponse
+	meta, err = app.Mock().Get("/api/v1/bots/%s", b.ID()).JSONWithMeta(&bot)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal([]string{modules.ModuleUtil}, bot.Response.RegisteredModules)
+	assert.Empty(bot.Response.LoadedModules)
+	assert.Equal(modules.RedactedConfigValue, bot.Response.Configuration["jira_credentials"])
+	assert.Equal("true", bot.Response.Configuration[modules.ConfigOptionPassive])
+	assert.Len(bot.Response.Jobs, 1)
+	assert.Equal("test", bot.Response.Jobs[0].Name)
+
+	meta, err = app.Mock().Get("/api/v1/bots/not-a-bot").JSONWithMeta(&bot)
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, meta.StatusCode)
+}
+
+func TestPostRequiresToken(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testAPI(assert)
+
+	var res botResponse
+	meta, err := app.Mock().Post("/api/v1/bots/%s/modules/util/load", b.ID()).JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusForbidden, meta.StatusCode)
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/modules/util/load", b.ID()).WithHeader("Authorization", "Bearer nope").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusForbidden, meta.StatusCode)
+	assert.False(b.LoadedModules().Contains(modules.ModuleUtil))
+
+	disabled := web.New()
+	disabled.Register(New(func() []core.Bot { return []core.Bot{b} }, ""))
+	meta, err = disabled.Mock().Post("/api/v1/bots/%s/modules/util/load", b.ID()).WithHeader("Authorization", "Bearer ").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusForbidden, meta.StatusCode, "posts are disabled without a token")
+}
+
+func TestPostModules(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testAPI(assert)
+
+	var res botResponse
+	meta, err := app.Mock().Post("/api/v1/bots/%s/modules/util/load", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal([]string{modules.ModuleUtil}, res.Response.LoadedModules)
+	assert.True(b.LoadedModules().Contains(modules.ModuleUtil))
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/modules/util/load", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, meta.StatusCode)
+	assert.Equal("Module `util` is already loaded.", res.Meta.Message)
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/modules/util/unload", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.False(b.LoadedModules().Contains(modules.ModuleUtil))
+
+	audit := b.AuditLog().ByUser(AuditUser, 0)
+	assert.Len(audit, 3)
+	assert.Equal(modules.ActionModuleUnload, audit[0].Action)
+	assert.Equal(core.AuditResultError, audit[1].Result)
+	assert.Equal(modules.ModuleUtil, audit[2].Arguments)
+}
+
+func TestPostConfigAndJobs(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testAPI(assert)
+
+	var config struct {
+		Response ConfigResponse
+	}
+	meta, err := app.Mock().Post("/api/v1/bots/%s/config", b.ID()).WithHeader("Authorization", "Bearer sekrit").
+		WithPostBodyAsJSON(ConfigRequest{Key: modules.ConfigOptionPassive, Value: "off"}).JSONWithMeta(&config)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal("false", config.Response.Value)
+	assert.Equal("false", b.Configuration()[modules.ConfigOptionPassive])
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/config", b.ID()).WithHeader("Authorization", "Bearer sekrit").
+		WithPostBodyAsJSON(ConfigRequest{Key: "jira_credentials", Value: "user:hunter3"}).JSONWithMeta(&config)
+	assert.Nil(err)
+	assert.Equal(modules.RedactedConfigValue, config.Response.Value)
+	assert.Equal("user:hunter3", b.Configuration()["jira_credentials"])
+
+	audit := b.AuditLog().ByUser(AuditUser, 2)
+	assert.Equal(modules.ActionConfigSet, audit[0].Action)
+	assert.Equal("jira_credentials "+modules.RedactedConfigValue, audit[0].Arguments)
+	assert.Equal(modules.ConfigOptionPassive+" off", audit[1].Arguments)
+
+	var res botResponse
+	meta, err = app.Mock().Post("/api/v1/bots/%s/jobs/test/disable", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.True(b.JobManager().IsDisabled("test"))
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/jobs/test/run", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, meta.StatusCode)
+	assert.Equal("job `test` is disabled", res.Meta.Message)
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/jobs/test/enable", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	meta, err = app.Mock().Post("/api/v1/bots/%s/jobs/test/run", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+
+	meta, err = app.Mock().Post("/api/v1/bots/%s/jobs/nope/run", b.ID()).WithHeader("Authorization", "Bearer sekrit").JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, meta.StatusCode)
+}
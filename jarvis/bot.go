@@ -2,56 +2,78 @@ package jarvis
 
 import (
 	"fmt"
-	"os"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blendlabs/go-chronometer"
 	"github.com/blendlabs/go-exception"
 	logger "github.com/blendlabs/go-logger"
 	"github.com/blendlabs/go-util"
 	"github.com/blendlabs/go-util/collections"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/jobs"
 	"github.com/wcharczuk/jarvis/jarvis/modules"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
 	// EnvironmentSlackAPIToken is the slack api token environment variable.
 	EnvironmentSlackAPIToken = "SLACK_API_TOKEN"
+
+	// processedFile is the data file the newest processed message in each channel is saved to, so a restarted
+	// bot can catch up on what it missed.
+	processedFile = "processed.json"
 )
 
 // NewBotFromEnvironment creates a new bot from environment variables.
 func NewBotFromEnvironment() (*Bot, error) {
-	envToken := os.Getenv(EnvironmentSlackAPIToken)
-	if len(envToken) == 0 {
-		return nil, exception.Newf("`%s` is empty, cannot start bot.", EnvironmentSlackAPIToken)
-	}
-	b := NewBot(envToken)
-	envModules := os.Getenv(modules.EnvironmentModules)
-	if len(envModules) != 0 {
-		b.Configuration()[modules.ConfigModules] = envModules
-	} else {
-		b.Configuration()[modules.ConfigModules] = "all"
+	w, err := WorkspaceFromEnvironment()
+	if err != nil {
+		return nil, err
 	}
+	b := NewWorkspaceBot(w)
 	b.agent = logger.NewFromEnvironment()
 	return b, nil
 }
 
 // NewBot returns a new Bot instance.
 func NewBot(token string) *Bot {
-	return &Bot{
-		token:          token,
-		jobManager:     chronometer.NewJobManager(),
-		state:          map[string]interface{}{},
-		configuration:  map[string]string{},
-		actionLookup:   map[string]core.Action{},
-		modules:        map[string]core.BotModule{},
-		loadedModules:  collections.SetOfString{},
-		mentionActions: []core.Action{},
-		passiveActions: []core.Action{},
-		agent:          logger.New(logger.NewEventFlagSetNone()),
+	b := &Bot{
+		token:           token,
+		jobManager:      chronometer.NewJobManager(),
+		jobHistory:      core.NewJobHistory(core.DefaultJobHistoryCapacity),
+		sessions:        core.NewSessions(),
+		directory:       core.NewDirectory(),
+		auditLog:        core.NewAuditLog(core.DefaultAuditLogCapacity),
+		rateLimiter:     core.NewRateLimiter(time.Now),
+		metrics:         core.NewBotMetrics(),
+		errors:          core.NewErrorLog(core.DefaultErrorLogCapacity),
+		health:          core.NewHealth(),
+		correlations:    core.NewMessageCorrelations(core.DefaultMessageCorrelationCapacity),
+		processed:       core.NewProcessedMessages(core.DefaultProcessedMessagesCapacity),
+		state:           map[string]interface{}{},
+		configuration:   map[string]string{},
+		actionLookup:    map[string]core.Action{},
+		modules:         map[string]core.BotModule{},
+		loadedModules:   collections.SetOfString{},
+		mentionActions:  []core.Action{},
+		passiveActions:  []core.Action{},
+		reactionActions: []core.Action{},
+		intents:         core.NewIntentClassifier(nil),
+		subscriptions:   map[slack.Event][]core.EventSubscription{},
+		listening:       map[slack.Event]bool{},
+		agent:           logger.New(logger.NewEventFlagSetNone()),
+		newClient:       slack.NewClient,
 	}
+	b.outbound = core.NewOutboundQueue(func(channel string, err error) {
+		b.Logf("dropped an outgoing message to `%s`: %v", channel, err)
+		b.errors.Add(fmt.Sprintf("outbound %s", channel), err)
+	})
+	return b
 }
 
 // Bot is the main primitive.
@@ -63,18 +85,38 @@ type Bot struct {
 	configuration    map[string]string
 	state            map[string]interface{}
 	jobManager       *chronometer.JobManager
+	jobHistory       *core.JobHistory
+	sessions         *core.Sessions
+	directory        *core.Directory
+	auditLog         *core.AuditLog
+	rateLimiter      *core.RateLimiter
+	outbound         *core.OutboundQueue
+	metrics          *core.BotMetrics
+	errors           *core.ErrorLog
+	health           *core.Health
+	correlations     *core.MessageCorrelations
+	processed        *core.ProcessedMessages
 	client           *slack.Client
+	newClient        func(token string) *slack.Client
 
 	agent *logger.Agent
 
 	modules       map[string]core.BotModule
 	loadedModules collections.SetOfString
 
-	mentionActions []core.Action
-	passiveActions []core.Action
-	actionLookup   map[string]core.Action
-	UsersLookup    map[string]slack.User
-	ChannelsLookup map[string]slack.Channel
+	mentionActions  []core.Action
+	passiveActions  []core.Action
+	reactionActions []core.Action
+	actionLookup    map[string]core.Action
+	intents         *core.IntentClassifier
+
+	// lock guards the configuration, modules and actions, which the api and dashboard change while messages are
+	// being handled.
+	lock sync.RWMutex
+
+	subscriptionsLock sync.Mutex
+	subscriptions     map[slack.Event][]core.EventSubscription
+	listening         map[slack.Event]bool
 }
 
 // ID returns the id.
@@ -100,9 +142,125 @@ func (b *Bot) JobManager() *chronometer.JobManager {
 	return b.jobManager
 }
 
-//Configuration returns the current bot configuration.
+// LoadJob loads a job into the job manager, recording its runs in the job history.
+func (b *Bot) LoadJob(job chronometer.Job) error {
+	return b.jobManager.LoadJob(newTrackedJob(b, job))
+}
+
+// JobHistory returns the recent runs of jobs loaded with `LoadJob`.
+func (b *Bot) JobHistory() *core.JobHistory {
+	return b.jobHistory
+}
+
+// Sessions returns the active user sessions.
+func (b *Bot) Sessions() *core.Sessions {
+	return b.sessions
+}
+
+// Correlations returns the replies the bot sent to recent messages.
+func (b *Bot) Correlations() *core.MessageCorrelations {
+	return b.correlations
+}
+
+// JobOutputChannels returns the channels a job should post to; these are the channels (ids or names)
+// listed in the job's `job.<name>.channels` config entry, or the active channels if there is no entry.
+func (b *Bot) JobOutputChannels(jobName string) []string {
+	value, hasValue := b.config(fmt.Sprintf(jobs.ConfigJobChannels, jobName))
+	if !hasValue || core.IsEmpty(value) {
+		return b.ActiveChannels()
+	}
+
+	channelIDs := []string{}
+	for _, channel := range strings.Split(value, ",") {
+		channelID := b.ResolveChannelID(channel)
+		if len(channelID) != 0 {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	return channelIDs
+}
+
+// jobOwnerChannel returns the channel a job's failures are reported to, or empty if there is no owner.
+func (b *Bot) jobOwnerChannel(jobName string) string {
+	if owner, hasOwner := b.config(fmt.Sprintf(jobs.ConfigJobOwner, jobName)); hasOwner && !core.IsEmpty(owner) {
+		return b.ResolveChannelID(owner)
+	}
+	if owner, hasOwner := b.config(jobs.ConfigJobsOwner); hasOwner && !core.IsEmpty(owner) {
+		return b.ResolveChannelID(owner)
+	}
+	return ""
+}
+
+func (b *Bot) notifyJobFailure(run core.JobRun) {
+	b.Logf("job `%s` failed after %v: %s", run.Job, run.Elapsed, run.Error)
+	channelID := b.jobOwnerChannel(run.Job)
+	if len(channelID) == 0 || b.client == nil {
+		return
+	}
+	err := b.Sayf(channelID, "job `%s` failed after %v:\n> %s", run.Job, run.Elapsed, run.Error)
+	if err != nil {
+		b.Log(err)
+	}
+}
+
+// ResolveChannelID returns the id for a channel given as an id, a name, a `#name` or a `<#id|name>` mention.
+func (b *Bot) ResolveChannelID(channel string) string {
+	channel = strings.TrimSpace(channel)
+	if strings.HasPrefix(channel, "<#") {
+		return core.MentionID(channel)
+	}
+	channel = strings.TrimPrefix(channel, "#")
+	if len(channel) == 0 {
+		return ""
+	}
+	if b.directory.Channel(channel) != nil {
+		return channel
+	}
+	if c := b.FindChannelByName(channel); c != nil {
+		return c.ID
+	}
+	return channel
+}
+
+// Configuration returns a copy of the current bot configuration; use `SetConfig` to change it.
 func (b *Bot) Configuration() map[string]string {
-	return b.configuration
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	configuration := map[string]string{}
+	for key, value := range b.configuration {
+		configuration[key] = value
+	}
+	return configuration
+}
+
+// SetConfig sets a config entry; it's safe while messages are being handled.
+// Setting a `job.<name>.schedule` entry reschedules the job's next run if it's loaded and enabled.
+func (b *Bot) SetConfig(key, value string) {
+	b.lock.Lock()
+	b.configuration[key] = value
+	b.lock.Unlock()
+
+	if parts := core.ExtractSubMatches(key, jobs.ConfigJobScheduleExpr); len(parts) > 1 {
+		b.rescheduleJob(parts[1])
+	}
+}
+
+// rescheduleJob recomputes a loaded job's next run from its current schedule, unless the job is disabled.
+func (b *Bot) rescheduleJob(jobName string) {
+	if !b.jobManager.HasJob(jobName) || b.jobManager.IsDisabled(jobName) {
+		return
+	}
+	if err := b.jobManager.EnableJob(jobName); err != nil {
+		b.Logf("error rescheduling job `%s`: %v", jobName, err)
+	}
+}
+
+// config returns a config entry and if it's set.
+func (b *Bot) config(key string) (string, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	value, hasValue := b.configuration[key]
+	return value, hasValue
 }
 
 //State returns the current bot state.
@@ -117,9 +275,12 @@ func (b *Bot) Client() *slack.Client {
 
 // Actions returns the actions loaded for a bot
 func (b *Bot) Actions() []core.Action {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
 	allActions := []core.Action{}
 	allActions = append(allActions, b.mentionActions...)
 	allActions = append(allActions, b.passiveActions...)
+	allActions = append(allActions, b.reactionActions...)
 	sort.Sort(core.ActionsByPriority(allActions))
 	return allActions
 }
@@ -129,33 +290,49 @@ func (b *Bot) AddAction(action core.Action) {
 	if action.Priority == 0 {
 		action.Priority = core.PriorityNormal
 	}
-	if action.Passive {
-		b.passiveActions = append(b.passiveActions, action)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	// the action lists are copied rather than appended to in place, so dispatches already ranging over them aren't
+	// affected.
+	if action.IsReaction() {
+		b.reactionActions = append(append([]core.Action{}, b.reactionActions...), action)
+
+		sortable := core.ActionsByPriority(b.reactionActions)
+		sort.Sort(sortable)
+		b.reactionActions = sortable
+	} else if action.Passive {
+		b.passiveActions = append(append([]core.Action{}, b.passiveActions...), action)
 
 		sortable := core.ActionsByPriority(b.passiveActions)
 		sort.Sort(sortable)
 		b.passiveActions = sortable
 	} else {
-		b.mentionActions = append(b.mentionActions, action)
+		b.mentionActions = append(append([]core.Action{}, b.mentionActions...), action)
 
 		sortable := core.ActionsByPriority(b.mentionActions)
 		sort.Sort(sortable)
 		b.mentionActions = sortable
+		b.intents = core.NewIntentClassifier(b.mentionActions)
 	}
 	b.actionLookup[action.ID] = action
 }
 
 // RemoveAction removes an action from the bot.
 func (b *Bot) RemoveAction(id string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
 	action, hasAction := b.actionLookup[id]
 	if !hasAction {
 		return
 	}
 
-	if action.Passive {
+	if action.IsReaction() {
+		b.reactionActions = filterActions(b.reactionActions, id)
+	} else if action.Passive {
 		b.passiveActions = filterActions(b.passiveActions, id)
 	} else {
 		b.mentionActions = filterActions(b.mentionActions, id)
+		b.intents = core.NewIntentClassifier(b.mentionActions)
 	}
 	delete(b.actionLookup, id)
 }
@@ -170,29 +347,121 @@ func filterActions(actions []core.Action, id string) []core.Action {
 	return newActions
 }
 
+// AddSubscription subscribes a handler to a slack event.
+func (b *Bot) AddSubscription(subscription core.EventSubscription) {
+	b.subscriptionsLock.Lock()
+	b.subscriptions[subscription.Event] = append(b.subscriptions[subscription.Event], subscription)
+	b.subscriptionsLock.Unlock()
+	b.listenFor(subscription.Event)
+}
+
+// RemoveSubscription removes an event subscription by id.
+func (b *Bot) RemoveSubscription(id string) {
+	b.subscriptionsLock.Lock()
+	defer b.subscriptionsLock.Unlock()
+	for event, subscriptions := range b.subscriptions {
+		remaining := []core.EventSubscription{}
+		for _, subscription := range subscriptions {
+			if subscription.ID != id {
+				remaining = append(remaining, subscription)
+			}
+		}
+		b.subscriptions[event] = remaining
+	}
+}
+
+// Subscriptions returns the event subscriptions.
+func (b *Bot) Subscriptions() []core.EventSubscription {
+	b.subscriptionsLock.Lock()
+	defer b.subscriptionsLock.Unlock()
+	all := []core.EventSubscription{}
+	for _, subscriptions := range b.subscriptions {
+		all = append(all, subscriptions...)
+	}
+	return all
+}
+
+// listenFor adds a client listener for an event the first time something subscribes to it, which is safe while the
+// client is running (e.g. for `module:load`); message subtypes (e.g. `channel_leave`) are dispatched by the message
+// listener.
+func (b *Bot) listenFor(event slack.Event) {
+	b.subscriptionsLock.Lock()
+	defer b.subscriptionsLock.Unlock()
+	if b.client == nil || b.listening[event] {
+		return
+	}
+	b.listening[event] = true
+	b.client.AddEventListener(event, func(c *slack.Client, m *slack.Message) {
+		b.dispatchEvent(event, m)
+	})
+}
+
+// dispatchEvent runs the subscriptions to an event.
+func (b *Bot) dispatchEvent(event slack.Event, m *slack.Message) {
+	b.subscriptionsLock.Lock()
+	subscriptions := append([]core.EventSubscription{}, b.subscriptions[event]...)
+	b.subscriptionsLock.Unlock()
+
+	for _, subscription := range subscriptions {
+		b.agent.Debugf("dispatchEvent :: %s handler found: %s", event, subscription.ID)
+		if err := subscription.Handler(b, m); err != nil {
+			b.Log(err)
+		}
+	}
+}
+
 // TriggerAction triggers and action with a given message.
 func (b *Bot) TriggerAction(id string, m *slack.Message) error {
-	if action, hasAction := b.actionLookup[id]; hasAction {
+	b.lock.RLock()
+	action, hasAction := b.actionLookup[id]
+	b.lock.RUnlock()
+	if hasAction {
 		return action.Handler(b, m)
 	}
 	return exception.Newf("action %s is not loaded.", id)
 }
 
+// IsConnected returns if the bot has an open connection to slack.
+func (b *Bot) IsConnected() bool {
+	return b.client != nil && b.client.IsConnected()
+}
+
+// Readiness returns whether the bot is connected, has heard from slack within `pongTimeout` and is running jobs.
+func (b *Bot) Readiness(pongTimeout time.Duration) core.Readiness {
+	readiness := b.health.Readiness(b.IsConnected(), time.Now().UTC(), pongTimeout)
+	readiness.ID = b.ID()
+	readiness.OrganizationName = b.OrganizationName()
+	return readiness
+}
+
 // ActiveChannels returns a list of active channel ids.
 func (b *Bot) ActiveChannels() []string {
+	if b.client == nil {
+		return nil
+	}
 	return b.client.ActiveChannels
 }
 
 // RegisterModule loads a given bot module
 func (b *Bot) RegisterModule(m core.BotModule) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
 	b.modules[m.Name()] = m
 }
 
+// module returns a registered module.
+func (b *Bot) module(moduleName string) (core.BotModule, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	m, hasModule := b.modules[moduleName]
+	return m, hasModule
+}
+
 // LoadModule loads a registered module.
 func (b *Bot) LoadModule(moduleName string) error {
 	var err error
 	var actions []core.Action
-	if m, hasModule := b.modules[moduleName]; hasModule {
+	if m, hasModule := b.module(moduleName); hasModule {
 		err = m.Init(b)
 		if err != nil {
 			return err
@@ -202,29 +471,51 @@ func (b *Bot) LoadModule(moduleName string) error {
 		for _, action := range actions {
 			b.AddAction(action)
 		}
+		if subscriber, isSubscriber := m.(core.EventSubscriber); isSubscriber {
+			for _, subscription := range subscriber.Subscriptions() {
+				b.AddSubscription(subscription)
+			}
+		}
+		b.lock.Lock()
 		b.loadedModules.Add(moduleName)
+		b.lock.Unlock()
 	}
 	return nil
 }
 
 // UnloadModule unloads a module and its actions.
 func (b *Bot) UnloadModule(moduleName string) {
-	if m, hasModule := b.modules[moduleName]; hasModule {
+	if m, hasModule := b.module(moduleName); hasModule {
 		actions := m.Actions()
 		for _, action := range actions {
 			b.RemoveAction(action.ID)
 		}
+		if subscriber, isSubscriber := m.(core.EventSubscriber); isSubscriber {
+			for _, subscription := range subscriber.Subscriptions() {
+				b.RemoveSubscription(subscription.ID)
+			}
+		}
+		b.lock.Lock()
 		b.loadedModules.Remove(moduleName)
+		b.lock.Unlock()
 	}
 }
 
-// LoadedModules returns the currently loaded modules.
+// LoadedModules returns a copy of the currently loaded modules.
 func (b *Bot) LoadedModules() collections.SetOfString {
-	return b.loadedModules
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	loaded := collections.SetOfString{}
+	for name := range b.loadedModules {
+		loaded.Add(name)
+	}
+	return loaded
 }
 
 // RegisteredModules returns the registered modules.
 func (b *Bot) RegisteredModules() collections.SetOfString {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
 	registered := collections.SetOfString{}
 	for key := range b.modules {
 		registered.Add(key)
@@ -234,7 +525,7 @@ func (b *Bot) RegisteredModules() collections.SetOfString {
 }
 
 func (b *Bot) loadAllRegisteredModules() {
-	for name := range b.modules {
+	for name := range b.RegisteredModules() {
 		loadErr := b.LoadModule(name)
 		if loadErr != nil {
 			b.Logf("Error loading module `%s`: %v", name, loadErr)
@@ -243,7 +534,7 @@ func (b *Bot) loadAllRegisteredModules() {
 }
 
 func (b *Bot) loadConfiguredModules() {
-	configEntry, hasEntry := b.configuration[modules.ConfigModules]
+	configEntry, hasEntry := b.config(modules.ConfigModules)
 	if !hasEntry || strings.ToLower(configEntry) == "all" {
 		b.loadAllRegisteredModules()
 		return
@@ -263,36 +554,81 @@ func (b *Bot) loadConfiguredModules() {
 func (b *Bot) Init() error {
 
 	b.RegisterModule(new(modules.ConsoleRunner))
-	//b.RegisterModule(new(modules.Jira))
+	b.RegisterModule(new(modules.Jira))
 	b.RegisterModule(new(modules.Stocks))
 	b.RegisterModule(new(modules.Jobs))
+	b.RegisterModule(modules.NewReminders())
+	b.RegisterModule(modules.NewStandup())
+	b.RegisterModule(modules.NewPins())
+	b.RegisterModule(modules.NewWelcome())
+	b.RegisterModule(modules.NewPolicies())
+	b.RegisterModule(new(modules.Audit))
 	b.RegisterModule(new(modules.Config))
 	b.RegisterModule(new(modules.Util))
 	b.RegisterModule(new(modules.Core))
 	b.RegisterModule(modules.NewSlack())
 	b.loadConfiguredModules()
 
-	client := slack.NewClient(b.token)
+	err := b.LoadJob(jobs.NewSessionReaper(b))
+	if err != nil {
+		return err
+	}
+	err = b.LoadJob(jobs.NewDirectorySync(b))
+	if err != nil {
+		return err
+	}
+
+	client := b.newClient(b.token)
 	client.SetDebug(true)
 	b.client = client
+	b.client.OnPong(func(rtt time.Duration) {
+		b.metrics.PingRTT.Observe(rtt.Seconds())
+	})
+	b.client.OnReconnect(func() {
+		b.metrics.WebsocketReconnects.Inc()
+		b.catchUp(b.channelHistory)
+	})
 	b.client.AddEventListener(slack.EventHello, func(c *slack.Client, m *slack.Message) {
+		b.health.Connected(time.Now().UTC())
 		b.Log("slack is connected")
 	})
-	if b.agent.IsEnabled(logger.EventDebug) {
-		b.client.AddEventListener(slack.EventPing, func(c *slack.Client, m *slack.Message) {
-			b.agent.Debugf("ping!")
-		})
-		b.client.AddEventListener(slack.EventPong, func(c *slack.Client, m *slack.Message) {
-			b.agent.Debugf("pong!")
-		})
-	}
+	b.client.AddEventListener(slack.EventPing, func(c *slack.Client, m *slack.Message) {
+		b.health.Ping(time.Now().UTC())
+		b.agent.Debugf("ping!")
+	})
+	b.client.AddEventListener(slack.EventPong, func(c *slack.Client, m *slack.Message) {
+		b.health.Pong(time.Now().UTC())
+		b.agent.Debugf("pong!")
+	})
 	b.client.AddEventListener(slack.EventMessage, func(c *slack.Client, m *slack.Message) {
+		if len(m.SubType) != 0 {
+			b.dispatchEvent(slack.Event(m.SubType), m)
+		} else if m.Timestamp != nil && !b.processed.Mark(m.Channel, *m.Timestamp) {
+			return
+		}
 		resErr := b.dispatchResponse(m)
 		if resErr != nil {
-			c.Sayf(m.Channel, "there was an error handling the message:\n> %s", resErr.Error())
+			b.Replyf(m, "there was an error handling the message:\n> %s", resErr.Error())
 			b.Log(resErr)
+			b.errors.Add("message", resErr)
 		}
 	})
+	reactionListener := func(c *slack.Client, m *slack.Message) {
+		if err := b.dispatchReaction(m); err != nil {
+			b.Log(err)
+			b.errors.Add("reaction", err)
+		}
+	}
+	b.client.AddEventListener(slack.EventReactionAdded, reactionListener)
+	b.client.AddEventListener(slack.EventReactionRemoved, reactionListener)
+	for _, event := range core.DirectoryEvents {
+		b.AddSubscription(core.EventSubscription{ID: "directory", Event: event, Handler: func(b core.Bot, m *slack.Message) error {
+			return b.Directory().Apply(m)
+		}})
+	}
+	for _, subscription := range b.Subscriptions() {
+		b.listenFor(subscription.Event)
+	}
 
 	return nil
 }
@@ -306,15 +642,145 @@ func (b *Bot) Start() error {
 
 	b.id = session.Self.ID
 	b.organizationName = session.Team.Name
-	b.ChannelsLookup = b.createChannelLookup(session)
-	b.UsersLookup = b.createUsersLookup(session)
+	b.directory.SetChannels(session.Channels)
+	b.directory.SetUsers(session.Users)
 	b.jobManager.SetLogger(b.agent)
 	b.jobManager.Start()
+	b.health.SetJobManagerRunning(true)
+	b.outbound.Start()
+	b.restoreProcessed()
+	go b.catchUp(b.channelHistory)
 	return nil
 }
 
+// Stop disconnects the bot from slack, stops its jobs and outgoing messages, and closes its audit log file.
+func (b *Bot) Stop() error {
+	b.jobManager.Stop()
+	b.health.SetJobManagerRunning(false)
+	b.outbound.Stop()
+	b.saveProcessed()
+	auditErr := b.auditLog.Close()
+	if b.client == nil {
+		return auditErr
+	}
+	if err := b.client.Stop(); err != nil {
+		return err
+	}
+	return auditErr
+}
+
+// restoreProcessed loads the newest message processed in each channel by a previous bot for the workspace.
+func (b *Bot) restoreProcessed() {
+	latest := map[string]slack.Timestamp{}
+	if err := core.NewJSONFileStore(modules.DataFilePath(b, processedFile)).Load(&latest); err != nil {
+		b.Log(err)
+		b.errors.Add("processed messages", err)
+		return
+	}
+	b.processed.Restore(latest)
+}
+
+// saveProcessed saves the newest message processed in each channel, if there are any.
+func (b *Bot) saveProcessed() {
+	latest := b.processed.Latest()
+	if len(latest) == 0 {
+		return
+	}
+	if err := core.NewJSONFileStore(modules.DataFilePath(b, processedFile)).Save(latest); err != nil {
+		b.Log(err)
+		b.errors.Add("processed messages", err)
+	}
+}
+
+// channelHistoryFunc returns the messages in a channel since `oldest`, newest first.
+type channelHistoryFunc func(channelID string, oldest time.Time) ([]slack.Message, error)
+
+func (b *Bot) channelHistory(channelID string, oldest time.Time) ([]slack.Message, error) {
+	res, err := b.client.ChannelsHistory(channelID, nil, &oldest, 100, false)
+	if err != nil {
+		return nil, err
+	}
+	return res.Messages, nil
+}
+
+// catchUp answers the mentions the bot missed in each channel it's seen messages in while it was disconnected (or,
+// after a restart, the channels the previous bot saw messages in), up to the configured max age, with a notice that
+// it's answering late.
+func (b *Bot) catchUp(history channelHistoryFunc) {
+	maxAge := b.catchUpMaxAge()
+	if maxAge <= 0 {
+		return
+	}
+	now := time.Now().UTC()
+	cutoff := now.Add(-maxAge)
+	for channelID, latest := range b.processed.Latest() {
+		// channels.history only covers public channels.
+		if !core.IsChannel(channelID) {
+			continue
+		}
+		oldest := latest.Time()
+		if oldest.Before(cutoff) {
+			oldest = cutoff
+		}
+		messages, err := history(channelID, oldest)
+		if err != nil {
+			b.Log(err)
+			b.errors.Add(fmt.Sprintf("catch up %s", channelID), err)
+			continue
+		}
+
+		missed := b.missedMentions(channelID, messages, cutoff)
+		if len(missed) == 0 {
+			continue
+		}
+		b.Sayf(channelID, "_sorry, I was disconnected for a bit; answering %d message(s) from the last %v late._", len(missed), now.Sub(missed[0].Timestamp.Time()).Truncate(time.Second))
+		for _, m := range missed {
+			if err := b.dispatchResponse(m); err != nil {
+				b.Log(err)
+				b.errors.Add("message", err)
+			}
+		}
+	}
+	b.saveProcessed()
+}
+
+// missedMentions returns the messages from a channel's history that mention the bot, were sent after `cutoff` and
+// haven't been processed, oldest first, marking them processed.
+func (b *Bot) missedMentions(channelID string, messages []slack.Message, cutoff time.Time) []*slack.Message {
+	missed := []*slack.Message{}
+	for index := len(messages) - 1; index >= 0; index-- {
+		m := messages[index]
+		if len(m.SubType) != 0 || m.Timestamp == nil || m.User == b.id || m.Timestamp.Time().Before(cutoff) {
+			continue
+		}
+		if !core.IsUserMention(m.Text, b.id) || !b.processed.Mark(channelID, *m.Timestamp) {
+			continue
+		}
+		m.Channel = channelID
+		m.Type = slack.EventMessage
+		missed = append(missed, &m)
+	}
+	return missed
+}
+
+func (b *Bot) catchUpMaxAge() time.Duration {
+	value, hasValue := b.config(modules.ConfigCatchUpMaxAge)
+	if !hasValue {
+		value = modules.DefaultCatchUpMaxAge
+	}
+	if strings.EqualFold(value, "off") {
+		return 0
+	}
+	maxAge, err := core.ParseDuration(value)
+	if err != nil {
+		b.Logf("invalid `%s`: %v", modules.ConfigCatchUpMaxAge, err)
+		maxAge, _ = core.ParseDuration(modules.DefaultCatchUpMaxAge)
+	}
+	return maxAge
+}
+
 func (b *Bot) passivesEnabled() bool {
-	if value, hasKey := b.configuration[modules.ConfigOptionPassive]; hasKey {
+	if value, hasKey := b.config(modules.ConfigOptionPassive); hasKey {
 		return strings.ToLower(value) == "true"
 	}
 	return false
@@ -323,35 +789,61 @@ func (b *Bot) passivesEnabled() bool {
 func (b *Bot) dispatchResponse(m *slack.Message) error {
 	defer func() {
 		if r := recover(); r != nil {
-			b.Sayf(m.Channel, "there was a panic handling the message:\n> %v", r)
+			b.Replyf(m, "there was a panic handling the message:\n> %v", r)
+			b.errors.Add("message", exception.Newf("panic: %v", r))
 		}
 	}()
 
 	b.agent.Debugf("dispatchResponse :: incoming message:\n%#v", m)
+	if slack.Event(m.SubType) != slack.EventSubtypeMessageChanged {
+		b.metrics.MessagesReceived.Inc()
+	}
+
+	switch slack.Event(m.SubType) {
+	case slack.EventSubtypeMessageChanged:
+		return b.dispatchEdit(m)
+	case slack.EventSubtypeMessageDeleted:
+		return b.dispatchDelete(m)
+	}
 
 	//b.LogIncomingMessage(m)
 	user := b.FindUser(m.User)
 	if user != nil {
 		if m.User != "slackbot" && m.User != b.id && !user.IsBot {
+			if handled, err := b.sessions.Handle(b, m); handled {
+				b.agent.Debugf("dispatchResponse :: message handled by session.")
+				return err
+			}
 			messageText := util.String.TrimWhitespace(core.LessMentions(m.Text))
 			if core.IsUserMention(m.Text, b.id) || core.IsDM(m.Channel) {
-				for _, action := range b.mentionActions {
-					if core.Like(messageText, action.MessagePattern) && !core.IsEmpty(action.MessagePattern) {
-						b.agent.Debugf("dispatchResponse :: handler found: %s", action.ID)
-						return action.Handler(b, m)
-					}
+				if handled, err := b.dispatchMention(m, messageText, false); handled {
+					return err
+				}
+				if handled, err := b.dispatchIntent(m, messageText); handled {
+					return err
+				}
+				if handled, err := b.dispatchMention(m, messageText, true); handled {
+					return err
 				}
 			} else {
 				b.agent.Debugf("dispatchResponse :: message was not a bot user mention.")
 			}
 			if b.passivesEnabled() {
 				var err error
-				for _, action := range b.passiveActions {
+				b.lock.RLock()
+				passiveActions := b.passiveActions
+				b.lock.RUnlock()
+				for _, action := range passiveActions {
 					if core.Like(messageText, action.MessagePattern) && !core.IsEmpty(action.MessagePattern) {
 						b.agent.Debugf("dispatchResponse :: passive handler found: %s", action.ID)
-						err = action.Handler(b, m)
+						if allowed, _ := b.rateLimiter.Allow(b.rateLimitKeys(action, m)...); !allowed {
+							b.agent.Debugf("dispatchResponse :: passive handler rate limited: %s", action.ID)
+							continue
+						}
+						err = b.runAction(action, m)
 						if err != nil {
 							b.agent.Error(err)
+							b.errors.Add(fmt.Sprintf("action %s", action.ID), err)
 						}
 					}
 				}
@@ -367,51 +859,445 @@ func (b *Bot) dispatchResponse(m *slack.Message) error {
 	return nil
 }
 
-// FindUser returns the user object for a given userID.
-func (b *Bot) FindUser(userID string) *slack.User {
-	if user, hasUser := b.UsersLookup[userID]; hasUser {
-		return &user
+// dispatchMention runs the first mention action whose message pattern matches, from either the catch all actions
+// or the rest.
+func (b *Bot) dispatchMention(m *slack.Message, messageText string, catchAll bool) (bool, error) {
+	b.lock.RLock()
+	mentionActions := b.mentionActions
+	b.lock.RUnlock()
+	for _, action := range mentionActions {
+		if (action.Priority <= core.PriorityCatchAll) != catchAll {
+			continue
+		}
+		if core.Like(messageText, action.MessagePattern) && !core.IsEmpty(action.MessagePattern) {
+			b.agent.Debugf("dispatchResponse :: handler found: %s", action.ID)
+			return true, b.runThrottledAction(action, m)
+		}
+	}
+	return false, nil
+}
+
+// dispatchIntent runs the mention action whose examples a message is closest to, with its command as the message
+// text, if it's close enough; if it's only somewhat close it asks if that's what the user meant.
+func (b *Bot) dispatchIntent(m *slack.Message, messageText string) (bool, error) {
+	threshold := b.intentThreshold(modules.ConfigIntentThreshold, core.DefaultIntentThreshold)
+	suggestThreshold := b.intentThreshold(modules.ConfigIntentSuggestThreshold, core.DefaultIntentSuggestThreshold)
+	b.lock.RLock()
+	intents := b.intents
+	b.lock.RUnlock()
+	matches := intents.Classify(messageText)
+	if len(matches) == 0 {
+		return false, nil
+	}
+
+	best := matches[0]
+	if best.Complete && best.Score >= threshold {
+		b.agent.Debugf("dispatchResponse :: intent found: %s (%.2f)", best.Action.ID, best.Score)
+		intended := *m
+		intended.Text = best.Command
+		return true, b.runThrottledAction(best.Action, &intended)
+	}
+	if best.Score >= suggestThreshold {
+		b.agent.Debugf("dispatchResponse :: intent suggested: %s (%.2f)", best.Action.ID, best.Score)
+		return true, b.Replyf(m, "did you mean `%s`?", best.Suggestion())
+	}
+	return false, nil
+}
+
+// intentThreshold returns an intent threshold from the configuration; `off` is a threshold nothing reaches.
+func (b *Bot) intentThreshold(key string, defaultValue float64) float64 {
+	value, hasValue := b.config(key)
+	if !hasValue {
+		return defaultValue
+	}
+	if strings.EqualFold(value, "off") {
+		return math.Inf(1)
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		b.Logf("invalid `%s`: %v", key, err)
+		return defaultValue
+	}
+	return threshold
+}
+
+// runThrottledAction runs a mention action unless it's over a rate limit.
+func (b *Bot) runThrottledAction(action core.Action, m *slack.Message) error {
+	if throttled, notify := b.throttle(action, m); throttled {
+		if notify {
+			return b.Replyf(m, "<@%s> slow down a little please, I'll be ready for more in a moment.", m.User)
+		}
+		return nil
+	}
+	return b.runAuditedAction(action, m)
+}
+
+// dispatchEdit re-handles an edited message the bot replied to, updating the previous replies
+// in place and deleting any that the new response doesn't need.
+func (b *Bot) dispatchEdit(m *slack.Message) error {
+	if m.Message == nil || m.Message.Timestamp == nil {
+		return nil
+	}
+	if m.PreviousMessage != nil && m.PreviousMessage.Text == m.Message.Text {
+		b.agent.Debugf("dispatchEdit :: message text is unchanged.")
+		return nil
+	}
+
+	edited := *m.Message
+	edited.Channel = m.Channel
+	messageTimestamp := edited.Timestamp.String()
+	if !b.correlations.BeginEdit(edited.Channel, messageTimestamp) {
+		b.agent.Debugf("dispatchEdit :: no replies to update.")
+		return nil
+	}
+
+	err := b.dispatchResponse(&edited)
+	if err != nil {
+		b.Replyf(&edited, "there was an error handling the message:\n> %s", err.Error())
+		b.Log(err)
+		b.errors.Add("message", err)
+	}
+
+	for _, reply := range b.correlations.EndEdit(edited.Channel, messageTimestamp) {
+		b.deleteMessage(edited.Channel, reply)
 	}
 	return nil
 }
 
-// FindChannel returns the channel object for a given channelID.
-func (b *Bot) FindChannel(channelID string) *slack.Channel {
-	if channel, hasChannel := b.ChannelsLookup[channelID]; hasChannel {
-		return &channel
+// dispatchDelete deletes the bot's replies to a deleted message.
+func (b *Bot) dispatchDelete(m *slack.Message) error {
+	if m.DeletedTimestamp == nil {
+		return nil
+	}
+	for _, reply := range b.correlations.Remove(m.Channel, m.DeletedTimestamp.String()) {
+		b.deleteMessage(m.Channel, reply)
 	}
 	return nil
 }
 
-func (b *Bot) createUsersLookup(session *slack.Session) map[string]slack.User {
-	lookup := map[string]slack.User{}
-	for x := 0; x < len(session.Users); x++ {
-		user := session.Users[x]
-		lookup[user.ID] = user
+// deleteMessage deletes a message, through the outbound queue so it happens after any queued updates to it.
+func (b *Bot) deleteMessage(channelID string, ts slack.Timestamp) {
+	b.outbound.Enqueue(channelID, func() error {
+		return b.client.ChatDelete(channelID, ts)
+	})
+}
+
+// dispatchReaction runs the reaction actions for a reaction to a message, in priority order.
+func (b *Bot) dispatchReaction(m *slack.Message) error {
+	defer func() {
+		if r := recover(); r != nil {
+			b.Logf("there was a panic handling the reaction: %v", r)
+		}
+	}()
+
+	actions := b.reactionActionsFor(m)
+	if len(actions) == 0 {
+		return nil
+	}
+
+	res, err := b.client.ReactionsGet(nil, nil, &m.Item.Channel, m.Item.Timestamp)
+	if err != nil {
+		return err
+	}
+	if res.Message == nil {
+		return nil
+	}
+	m.Channel = m.Item.Channel
+	m.Message = res.Message
+	m.Message.Channel = m.Item.Channel
+
+	for _, action := range actions {
+		b.agent.Debugf("dispatchReaction :: reaction handler found: %s", action.ID)
+		if err = b.runHandler(action, m); err != nil {
+			b.agent.Error(err)
+			b.errors.Add(fmt.Sprintf("action %s", action.ID), err)
+		}
+	}
+	return nil
+}
+
+// reactionActionsFor returns the reaction actions a reaction event triggers.
+func (b *Bot) reactionActionsFor(m *slack.Message) []core.Action {
+	if m.Item == nil || m.Item.Type != "message" || m.Item.Timestamp == nil {
+		return nil
+	}
+	if m.User == b.id || m.User == "slackbot" {
+		return nil
+	}
+	if user := b.FindUser(m.User); user == nil || user.IsBot {
+		return nil
 	}
-	return lookup
+
+	removed := slack.Event(m.Type) == slack.EventReactionRemoved
+	actions := []core.Action{}
+	b.lock.RLock()
+	reactionActions := b.reactionActions
+	b.lock.RUnlock()
+	for _, action := range reactionActions {
+		if strings.EqualFold(action.Reaction, strings.Trim(m.Reaction, ":")) && action.ReactionRemoved == removed {
+			actions = append(actions, action)
+		}
+	}
+	return actions
 }
 
-func (b *Bot) createChannelLookup(session *slack.Session) map[string]slack.Channel {
-	lookup := map[string]slack.Channel{}
-	for x := 0; x < len(session.Channels); x++ {
-		channel := session.Channels[x]
-		lookup[channel.ID] = channel
+func (b *Bot) runAction(action core.Action, m *slack.Message) error {
+	if action.ReplyInThread {
+		return b.runHandler(action, core.InThread(m))
 	}
-	return lookup
+	return b.runHandler(action, m)
+}
+
+// runHandler runs an action's handler, recording it in the dispatch, latency, error and panic metrics.
+func (b *Bot) runHandler(action core.Action, m *slack.Message) (err error) {
+	b.metrics.ActionsDispatched.Inc(action.ID)
+	started := time.Now()
+	defer func() {
+		b.metrics.ActionDuration.Observe(time.Since(started).Seconds(), action.ID)
+		if r := recover(); r != nil {
+			b.metrics.ActionPanics.Inc(action.ID)
+			panic(r)
+		}
+		if err != nil && !core.IsDenied(err) {
+			b.metrics.ActionErrors.Inc(action.ID)
+		}
+	}()
+	return action.Handler(b, m)
 }
 
-// Say calls the internal slack.Client.Say method.
+// runAuditedAction runs a mention action and records who ran it, where, and the result in the audit log.
+func (b *Bot) runAuditedAction(action core.Action, m *slack.Message) (err error) {
+	entry := core.AuditEntry{
+		User:      m.User,
+		Channel:   m.Channel,
+		Action:    action.ID,
+		Arguments: b.auditArguments(m),
+		Result:    core.AuditResultSuccess,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			entry.Result = core.AuditResultError
+			entry.Error = fmt.Sprintf("panic: %v", r)
+			b.audit(entry)
+			panic(r)
+		}
+	}()
+
+	err = b.runAction(action, m)
+	if core.IsDenied(err) {
+		entry.Result = core.AuditResultDenied
+		entry.Error = err.Error()
+		b.audit(entry)
+		return b.Replyf(m, "you can't do that: %s", err.Error())
+	}
+	if err != nil {
+		entry.Result = core.AuditResultError
+		entry.Error = err.Error()
+	}
+	b.audit(entry)
+	return err
+}
+
+// throttle returns if a mention action is over the user, channel or action rate limit, in which case the attempt is
+// audited as denied; `notify` is true the first time a limit is hit so the user gets a single notice.
+func (b *Bot) throttle(action core.Action, m *slack.Message) (throttled bool, notify bool) {
+	allowed, notify := b.rateLimiter.Allow(b.rateLimitKeys(action, m)...)
+	if allowed {
+		return false, false
+	}
+	b.agent.Debugf("dispatchResponse :: rate limited: %s", action.ID)
+	b.audit(core.AuditEntry{
+		User:      m.User,
+		Channel:   m.Channel,
+		Action:    action.ID,
+		Arguments: b.auditArguments(m),
+		Result:    core.AuditResultDenied,
+		Error:     "rate limited",
+	})
+	return true, notify
+}
+
+// rateLimitKeys returns the rate limit buckets running an action counts against. Passive actions get their own
+// user and channel buckets, so chatter in a channel doesn't use up what people need to talk to the bot.
+func (b *Bot) rateLimitKeys(action core.Action, m *slack.Message) []core.RateLimitKey {
+	var prefix string
+	if action.Passive {
+		prefix = "passive:"
+	}
+	actionLimit := b.rateLimit(modules.ConfigRateLimitAction, modules.DefaultRateLimitAction)
+	if _, hasLimit := b.config(fmt.Sprintf(modules.ConfigRateLimitActionFormat, action.ID)); hasLimit {
+		actionLimit = b.rateLimit(fmt.Sprintf(modules.ConfigRateLimitActionFormat, action.ID), actionLimit.String())
+	}
+	return []core.RateLimitKey{
+		{Key: prefix + "user:" + m.User, Limit: b.rateLimit(modules.ConfigRateLimitUser, modules.DefaultRateLimitUser)},
+		{Key: prefix + "channel:" + m.Channel, Limit: b.rateLimit(modules.ConfigRateLimitChannel, modules.DefaultRateLimitChannel)},
+		{Key: "action:" + action.ID, Limit: actionLimit},
+	}
+}
+
+// rateLimit returns a configured rate limit, falling back to the default if it's unset or invalid.
+func (b *Bot) rateLimit(configKey, defaultValue string) core.RateLimit {
+	value, hasValue := b.config(configKey)
+	if !hasValue {
+		value = defaultValue
+	}
+	limit, err := core.ParseRateLimit(value)
+	if err != nil {
+		b.Log(err)
+		limit, _ = core.ParseRateLimit(defaultValue)
+	}
+	return limit
+}
+
+func (b *Bot) audit(entry core.AuditEntry) {
+	if err := b.auditLog.Add(entry); err != nil {
+		b.Logf("error writing to the audit log: %v", err)
+	}
+}
+
+// auditArguments returns the text of a message after the bot mention, with secret config values redacted.
+func (b *Bot) auditArguments(m *slack.Message) string {
+	return modules.RedactCommand(util.String.TrimWhitespace(core.LessSpecificMention(m.Text, b.id)))
+}
+
+// OutboundQueue returns the queue outgoing messages are sent through.
+func (b *Bot) OutboundQueue() *core.OutboundQueue {
+	return b.outbound
+}
+
+// Metrics returns the bot's counters and histograms.
+func (b *Bot) Metrics() *core.BotMetrics {
+	return b.metrics
+}
+
+// CollectMetrics implements core.MetricsCollector, labeling the bot's metrics with its organization name.
+func (b *Bot) CollectMetrics() []core.MetricFamily {
+	families := append(b.metrics.CollectMetrics(), core.OutboundQueueMetrics(b.outbound.Stats())...)
+	return core.WithMetricLabel(families, "bot", b.OrganizationName())
+}
+
+// Errors returns the log of recent errors.
+func (b *Bot) Errors() *core.ErrorLog {
+	return b.errors
+}
+
+// AuditLog returns the audit log of dispatched actions.
+func (b *Bot) AuditLog() *core.AuditLog {
+	return b.auditLog
+}
+
+// Directory returns the user and channel directory.
+func (b *Bot) Directory() *core.Directory {
+	return b.directory
+}
+
+// FindUser returns the user object for a given userID.
+func (b *Bot) FindUser(userID string) *slack.User {
+	return b.directory.User(userID)
+}
+
+// FindChannel returns the channel object for a given channelID.
+func (b *Bot) FindChannel(channelID string) *slack.Channel {
+	return b.directory.Channel(channelID)
+}
+
+// FindChannelByName returns the channel object for a given channel name (with or without the leading `#`).
+func (b *Bot) FindChannelByName(name string) *slack.Channel {
+	return b.directory.ChannelByName(name)
+}
+
+// Say sends a message to a channel (or user) over the rtm websocket, through the outbound queue.
 func (b *Bot) Say(destinationID string, components ...interface{}) error {
 	b.LogOutgoingMessage(destinationID, components...)
-	return b.client.Say(destinationID, components...)
+	b.outbound.Enqueue(destinationID, func() error {
+		return b.client.Say(destinationID, components...)
+	})
+	return nil
 }
 
-// Sayf calls the internal slack.Client.Sayf method.
+// Sayf sends a message to a channel (or user) in a given format.
 func (b *Bot) Sayf(destinationID string, format string, components ...interface{}) error {
-	message := fmt.Sprintf(format, components...)
-	b.LogOutgoingMessage(destinationID, message)
-	return b.client.Sayf(destinationID, format, components...)
+	return b.Say(destinationID, fmt.Sprintf(format, components...))
+}
+
+// Reply replies to a message in the channel it was sent in, or in its thread if it's in one.
+// Replies are remembered so that they can be updated if the message is edited, or deleted if it's deleted.
+func (b *Bot) Reply(m *slack.Message, components ...interface{}) error {
+	messageText := fmt.Sprint(components...)
+	b.LogOutgoingMessage(m.Channel, messageText)
+	message := slack.NewChatMessage(m.Channel, messageText)
+	message.AsUser = slack.OptionalBool(true)
+	return b.ReplyMessage(m, message)
+}
+
+// Replyf replies to a message in a given format.
+func (b *Bot) Replyf(m *slack.Message, format string, components ...interface{}) error {
+	return b.Reply(m, fmt.Sprintf(format, components...))
+}
+
+// ReplyMessage posts a chat message, attachments and all, in reply to a message; like `Reply`, it's updated or
+// deleted when the message it replies to is.
+func (b *Bot) ReplyMessage(m *slack.Message, message *slack.ChatMessage) error {
+	if message.ThreadTimestamp == nil {
+		message.ThreadTimestamp = core.ThreadTimestamp(m)
+	}
+	if m.Timestamp == nil {
+		return b.PostMessage(message)
+	}
+
+	channel, messageTimestamp := m.Channel, m.Timestamp.String()
+	if previous := b.correlations.NextEdit(channel, messageTimestamp); previous != nil {
+		b.outbound.Enqueue(channel, func() error {
+			if _, err := b.client.ChatUpdate(*previous, message); err != nil {
+				return err
+			}
+			b.correlations.Add(channel, messageTimestamp, m.User, *previous)
+			return nil
+		})
+		return nil
+	}
+
+	b.outbound.Enqueue(channel, func() error {
+		res, err := b.client.ChatPostMessage(message)
+		if err != nil {
+			return err
+		}
+		b.correlations.Add(channel, messageTimestamp, m.User, res.Timestamp)
+		return nil
+	})
+	return nil
+}
+
+// PostMessage posts a message with the chat api, through the outbound queue.
+func (b *Bot) PostMessage(message *slack.ChatMessage) error {
+	b.outbound.Enqueue(message.Channel, func() error {
+		_, err := b.client.ChatPostMessage(message)
+		return err
+	})
+	return nil
+}
+
+// InviteUser invites a user to a channel, through the outbound queue.
+func (b *Bot) InviteUser(channelID, userID string) error {
+	b.outbound.Enqueue(channelID, func() error {
+		_, err := b.client.InviteUser(channelID, userID)
+		return err
+	})
+	return nil
+}
+
+// DirectMessage sends a direct message to a user.
+func (b *Bot) DirectMessage(userID string, components ...interface{}) error {
+	messageText := fmt.Sprint(components...)
+	b.LogOutgoingMessage(userID, messageText)
+	message := slack.NewChatMessage(userID, messageText)
+	message.AsUser = slack.OptionalBool(true)
+	return b.PostMessage(message)
+}
+
+// DirectMessagef sends a direct message to a user in a given format.
+func (b *Bot) DirectMessagef(userID string, format string, components ...interface{}) error {
+	return b.DirectMessage(userID, fmt.Sprintf(format, components...))
 }
 
 // LogIncomingMessage writes an incoming message to the log.
@@ -433,9 +1319,10 @@ func (b *Bot) LogIncomingMessage(m *slack.Message) {
 
 // LogOutgoingMessage logs an outgoing message.
 func (b *Bot) LogOutgoingMessage(destinationID string, components ...interface{}) {
-	if core.Like(destinationID, "^C") {
-		channel := b.FindChannel(destinationID)
+	if channel := b.FindChannel(destinationID); channel != nil {
 		b.agent.Debugf("<= #%s (%s) - jarvis: %s", channel.Name, channel.ID, fmt.Sprint(components...))
+	} else if core.Like(destinationID, "^C") {
+		b.agent.Debugf("<= %s - jarvis: %s", destinationID, fmt.Sprint(components...))
 	} else {
 		b.agent.Debugf("<= PM - jarvis: %s", fmt.Sprint(components...))
 	}
@@ -1,12 +1,23 @@
 package jarvis
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/blendlabs/go-assert"
-	"github.com/wcharczuk/go-slack"
+	"github.com/blendlabs/go-chronometer"
+	"github.com/blendlabs/go-exception"
+	"github.com/blendlabs/go-web"
+	"github.com/wcharczuk/jarvis/jarvis/api"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/jobs"
 	"github.com/wcharczuk/jarvis/jarvis/modules"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 func TestAddAction(t *testing.T) {
@@ -51,3 +62,623 @@ func TestLoadModule(t *testing.T) {
 	assert.NotEmpty(b.mentionActions)
 	assert.NotEmpty(b.passiveActions)
 }
+
+type failingJob struct{}
+
+func (fj failingJob) Name() string {
+	return "failing"
+}
+
+func (fj failingJob) Schedule() chronometer.Schedule {
+	return chronometer.OnDemand()
+}
+
+func (fj failingJob) Execute(ct *chronometer.CancellationToken) error {
+	return exception.New("this is only a test")
+}
+
+func TestLoadJobRecordsHistory(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	assert.Nil(b.LoadJob(failingJob{}))
+	assert.Nil(b.JobManager().RunJob("failing"))
+
+	deadline := time.Now().Add(time.Second)
+	for b.JobHistory().Last("failing") == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	last := b.JobHistory().Last("failing")
+	assert.NotNil(last)
+	assert.True(last.Failed())
+	assert.Equal("this is only a test", last.Error)
+	assert.Equal(float64(1), b.Metrics().JobsRun.Value("failing"))
+	assert.Equal(float64(1), b.Metrics().JobsFailed.Value("failing"))
+	assert.Equal("job failing", b.Errors().Recent(1)[0].Source)
+}
+
+type panickingJob struct{}
+
+func (pj panickingJob) Name() string {
+	return "panicking"
+}
+
+func (pj panickingJob) Schedule() chronometer.Schedule {
+	return chronometer.OnDemand()
+}
+
+func (pj panickingJob) Execute(ct *chronometer.CancellationToken) error {
+	var counts map[string]int
+	counts["runs"]++
+	return nil
+}
+
+func TestLoadJobRecordsPanics(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	assert.Nil(b.LoadJob(panickingJob{}))
+	assert.Nil(b.JobManager().RunJob("panicking"))
+
+	deadline := time.Now().Add(time.Second)
+	for b.JobHistory().Last("panicking") == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	last := b.JobHistory().Last("panicking")
+	assert.NotNil(last)
+	assert.True(last.Failed())
+	assert.Contains("assignment to entry in nil map", last.Error, "runtime errors aren't cancellations")
+}
+
+func TestTrackedJobCancellation(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	job := newTrackedJob(b, panickingJob{})
+
+	ct := chronometer.NewCancellationToken()
+	assert.NotNil(job.Execute(ct))
+
+	ct.Cancel()
+	defer func() {
+		r := recover()
+		_, isCancellation := r.(chronometer.CancellationPanic)
+		assert.True(isCancellation, "cancellations are passed on to the job manager")
+	}()
+	newTrackedJob(b, cancelingJob{}).Execute(ct)
+}
+
+type cancelingJob struct {
+	panickingJob
+}
+
+func (cj cancelingJob) Execute(ct *chronometer.CancellationToken) error {
+	ct.CheckCancellation()
+	return nil
+}
+
+type scheduledJob struct {
+	bot core.Bot
+}
+
+func (sj scheduledJob) Name() string {
+	return "scheduled"
+}
+
+func (sj scheduledJob) Schedule() chronometer.Schedule {
+	return jobs.ScheduleFor(sj.bot, sj.Name(), chronometer.Every(time.Hour))
+}
+
+func (sj scheduledJob) Execute(ct *chronometer.CancellationToken) error {
+	return nil
+}
+
+func nextRunTime(b *Bot, jobName string) string {
+	for _, status := range b.JobManager().Status() {
+		if status.Name == jobName {
+			return status.NextRunTime
+		}
+	}
+	return ""
+}
+
+func TestSetConfigReschedulesJobs(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	assert.Nil(b.LoadJob(scheduledJob{bot: b}))
+
+	yearly := chronometer.FormatTime(*jobs.MustParseCron("@yearly").GetNextRunTime(nil))
+	assert.NotEqual(yearly, nextRunTime(b, "scheduled"))
+
+	b.SetConfig("job.scheduled.schedule", "@yearly")
+	assert.Equal(yearly, nextRunTime(b, "scheduled"))
+
+	// later runs use the new schedule too.
+	after := time.Now().UTC()
+	assert.Equal(*jobs.MustParseCron("@yearly").GetNextRunTime(&after), *newTrackedJob(b, scheduledJob{bot: b}).Schedule().GetNextRunTime(&after))
+
+	// disabled jobs stay disabled.
+	assert.Nil(b.JobManager().DisableJob("scheduled"))
+	b.SetConfig("job.scheduled.schedule", "@daily")
+	assert.True(b.JobManager().IsDisabled("scheduled"))
+}
+
+func TestJobOutputChannels(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.Directory().SetChannels([]slack.Channel{
+		{ID: "C01", Name: "general"},
+		{ID: "C02", Name: "random"},
+	})
+	b.SetConfig("job.clock.channels", "#general, C02")
+	assert.Equal([]string{"C01", "C02"}, b.JobOutputChannels("clock"))
+
+	b.SetConfig("jobs.owner", "random")
+	assert.Equal("C02", b.jobOwnerChannel("clock"))
+	b.SetConfig("job.clock.owner", "general")
+	assert.Equal("C01", b.jobOwnerChannel("clock"))
+}
+
+func TestResolveChannelID(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.Directory().SetChannels([]slack.Channel{
+		{ID: "C01", Name: "general"},
+	})
+	assert.Equal("C01", b.ResolveChannelID("C01"))
+	assert.Equal("C01", b.ResolveChannelID("general"))
+	assert.Equal("C01", b.ResolveChannelID(" #general "))
+	assert.Equal("C02", b.ResolveChannelID("<#C02|team>"))
+	assert.Equal("C03", b.ResolveChannelID("C03"))
+	assert.Empty(b.ResolveChannelID("#"))
+}
+
+func TestRunActionReplyInThread(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+
+	var message slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"channel":"C01","text":"help","ts":"1476900000.000003"}`), &message))
+
+	var threadID string
+	handler := func(b core.Bot, m *slack.Message) error {
+		threadID = core.ThreadID(m)
+		return nil
+	}
+
+	assert.Nil(b.runAction(core.Action{ID: "test", Handler: handler}, &message))
+	assert.Empty(threadID)
+
+	assert.Nil(b.runAction(core.Action{ID: "test", Handler: handler, ReplyInThread: true}, &message))
+	assert.Equal("1476900000.000003", threadID)
+}
+
+func TestDispatchEditIgnoresUnansweredMessages(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+
+	handled := false
+	b.AddAction(core.Action{ID: "test", MessagePattern: "(.*)", Passive: true, Handler: func(b core.Bot, m *slack.Message) error {
+		handled = true
+		return nil
+	}})
+
+	var edit slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"type":"message","subtype":"message_changed","channel":"C01","ts":"1476900005.000001","message":{"user":"U01","text":"after","ts":"1476900000.000001"},"previous_message":{"user":"U01","text":"before","ts":"1476900000.000001"}}`), &edit))
+	assert.Equal("after", edit.Message.Text)
+	assert.Nil(b.dispatchResponse(&edit))
+	assert.False(handled)
+
+	var deleted slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"type":"message","subtype":"message_deleted","channel":"C01","ts":"1476900006.000001","deleted_ts":"1476900000.000001"}`), &deleted))
+	assert.Equal("1476900000.000001", deleted.DeletedTimestamp.String())
+	assert.Nil(b.dispatchResponse(&deleted))
+}
+
+func TestReactionActions(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.Directory().SetUsers([]slack.User{{ID: "U01"}, {ID: "UBOT", IsBot: true}})
+
+	noop := func(b core.Bot, m *slack.Message) error { return nil }
+	b.AddAction(core.Action{ID: "pin", Reaction: "pushpin", Handler: noop})
+	b.AddAction(core.Action{ID: "pin_first", Reaction: "pushpin", Priority: core.PriorityHigh, Handler: noop})
+	b.AddAction(core.Action{ID: "unpin", Reaction: "pushpin", ReactionRemoved: true, Handler: noop})
+	assert.Len(b.reactionActions, 3)
+	assert.Empty(b.passiveActions)
+	assert.Empty(b.mentionActions)
+
+	reaction := func(eventType, user string) *slack.Message {
+		var m slack.Message
+		assert.Nil(json.Unmarshal([]byte(`{"type":"`+eventType+`","user":"`+user+`","reaction":"pushpin","item":{"type":"message","channel":"C01","ts":"1476900000.000001"}}`), &m))
+		return &m
+	}
+
+	actions := b.reactionActionsFor(reaction("reaction_added", "U01"))
+	assert.Len(actions, 2)
+	assert.Equal("pin_first", actions[0].ID)
+	assert.Equal("pin", actions[1].ID)
+
+	actions = b.reactionActionsFor(reaction("reaction_removed", "U01"))
+	assert.Len(actions, 1)
+	assert.Equal("unpin", actions[0].ID)
+
+	assert.Empty(b.reactionActionsFor(reaction("reaction_added", "UBOT")))
+
+	b.RemoveAction("unpin")
+	assert.Len(b.reactionActions, 2)
+}
+
+type subscriberModule struct {
+	events []string
+}
+
+func (sm *subscriberModule) Init(b core.Bot) error  { return nil }
+func (sm *subscriberModule) Name() string           { return "subscriber" }
+func (sm *subscriberModule) Actions() []core.Action { return nil }
+func (sm *subscriberModule) Subscriptions() []core.EventSubscription {
+	return []core.EventSubscription{
+		{ID: "subscriber.team_join", Event: slack.EventTeamJoin, Handler: func(b core.Bot, m *slack.Message) error {
+			sm.events = append(sm.events, string(m.Type))
+			return nil
+		}},
+	}
+}
+
+func TestModuleSubscriptions(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	module := &subscriberModule{}
+	b.RegisterModule(module)
+	assert.Nil(b.LoadModule(module.Name()))
+	assert.Len(b.Subscriptions(), 1)
+
+	b.dispatchEvent(slack.EventTeamJoin, &slack.Message{Type: slack.EventTeamJoin})
+	b.dispatchEvent(slack.EventPresenceChange, &slack.Message{Type: slack.EventPresenceChange})
+	assert.Equal([]string{"team_join"}, module.events)
+
+	b.UnloadModule(module.Name())
+	assert.Empty(b.Subscriptions())
+	b.dispatchEvent(slack.EventTeamJoin, &slack.Message{Type: slack.EventTeamJoin})
+	assert.Len(module.events, 1)
+}
+
+func TestRunAuditedAction(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+
+	message := &slack.Message{User: "U01", Channel: "C01", Text: "<@" + b.ID() + "> config:foo bar"}
+	succeed := func(b core.Bot, m *slack.Message) error { return nil }
+	assert.Nil(b.runAuditedAction(core.Action{ID: "config.set", MessagePattern: "^config:([^ ]+) (.+)", Handler: succeed}, message))
+
+	fail := func(b core.Bot, m *slack.Message) error { return exception.New("this is only a test") }
+	assert.NotNil(b.runAuditedAction(core.Action{ID: "tell", MessagePattern: "^tell", Handler: fail}, &slack.Message{User: "U01", Channel: "C01", Text: "tell <@U02> hi"}))
+
+	secret := &slack.Message{User: "U01", Channel: "C01", Text: "<@" + b.ID() + "> config:jira.password hunter2"}
+	assert.Nil(b.runAuditedAction(core.Action{ID: "config.set", MessagePattern: "^config:([^ ]+) (.+)", Handler: succeed}, secret))
+	assert.Equal("config:jira.password "+modules.RedactedConfigValue, b.AuditLog().Recent(1)[0].Arguments, "secret values are redacted")
+
+	entries := b.AuditLog().Recent(0)[1:]
+	assert.Len(entries, 2)
+	assert.Equal("tell", entries[0].Action)
+	assert.Equal("tell <@U02> hi", entries[0].Arguments, "other mentions are kept")
+	assert.Equal(core.AuditResultError, entries[0].Result)
+	assert.Equal("this is only a test", entries[0].Error)
+	assert.Equal("config.set", entries[1].Action)
+	assert.Equal("config:foo bar", entries[1].Arguments)
+	assert.Equal(core.AuditResultSuccess, entries[1].Result)
+	assert.Equal("U01", entries[1].User)
+	assert.Equal("C01", entries[1].Channel)
+}
+
+func TestSetConfigWhileDispatching(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.id = "UJARVIS"
+	b.Directory().SetUsers([]slack.User{{ID: "U01"}})
+	b.RegisterModule(new(modules.Util))
+	b.SetConfig(modules.ConfigRateLimitUser, "off")
+	b.SetConfig(modules.ConfigRateLimitChannel, "off")
+	b.SetConfig(modules.ConfigRateLimitAction, "off")
+
+	app := web.New()
+	app.Register(api.New(func() []core.Bot { return []core.Bot{b} }, "sekrit"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := 0; x < 20; x++ {
+			app.Mock().Post("/api/v1/bots/%s/config", b.ID()).WithHeader("Authorization", "Bearer sekrit").
+				WithPostBodyAsJSON(api.ConfigRequest{Key: modules.ConfigOptionPassive, Value: "on"}).Response()
+			app.Mock().Post("/api/v1/bots/%s/modules/util/load", b.ID()).WithHeader("Authorization", "Bearer sekrit").Response()
+			app.Mock().Post("/api/v1/bots/%s/modules/util/unload", b.ID()).WithHeader("Authorization", "Bearer sekrit").Response()
+		}
+	}()
+
+	for x := 0; x < 20; x++ {
+		assert.Nil(b.dispatchResponse(&slack.Message{Type: "message", Channel: "C01", User: "U01", Text: "<@UJARVIS> user <@U01>"}))
+		assert.Nil(b.dispatchResponse(&slack.Message{Type: "message", Channel: "C01", User: "U01", Text: "just chatting"}))
+	}
+	<-done
+	assert.Equal("true", b.Configuration()[modules.ConfigOptionPassive])
+}
+
+func TestStopClosesAuditLog(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	assert.Nil(b.AuditLog().SetOutput(path))
+	assert.Nil(b.AuditLog().Add(core.AuditEntry{User: "U01", Action: "help"}))
+
+	assert.Nil(b.Stop())
+	assert.Nil(b.AuditLog().Add(core.AuditEntry{User: "U01", Action: "help"}))
+	assert.Len(b.AuditLog().Recent(0), 2)
+
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	assert.Len(strings.Split(strings.TrimSpace(string(contents)), "\n"), 1, "entries after stopping aren't written")
+}
+
+func TestThrottle(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC)
+	b.rateLimiter = core.NewRateLimiter(func() time.Time { return now })
+	b.SetConfig(modules.ConfigRateLimitUser, "2/1m")
+	b.SetConfig(modules.ConfigRateLimitChannel, "off")
+	b.SetConfig(fmt.Sprintf(modules.ConfigRateLimitActionFormat, "stock.price"), "1/10s")
+
+	alice := &slack.Message{User: "U01", Channel: "C01"}
+	bob := &slack.Message{User: "U02", Channel: "C01"}
+	help := core.Action{ID: "help", MessagePattern: "^help"}
+	stocks := core.Action{ID: "stock.price", MessagePattern: "^stock:price"}
+
+	throttled, _ := b.throttle(help, alice)
+	assert.False(throttled)
+	throttled, _ = b.throttle(stocks, alice)
+	assert.False(throttled)
+
+	throttled, notify := b.throttle(help, alice)
+	assert.True(throttled)
+	assert.True(notify)
+	throttled, notify = b.throttle(help, alice)
+	assert.True(throttled)
+	assert.False(notify)
+
+	throttled, notify = b.throttle(stocks, bob)
+	assert.True(throttled, "the action limit is shared between users")
+	assert.True(notify)
+	throttled, _ = b.throttle(help, bob)
+	assert.False(throttled)
+
+	now = now.Add(30 * time.Second)
+	throttled, _ = b.throttle(help, alice)
+	assert.False(throttled)
+	throttled, _ = b.throttle(stocks, bob)
+	assert.False(throttled)
+
+	denied := b.AuditLog().ByUser("U01", 0)
+	assert.Len(denied, 2)
+	assert.Equal(core.AuditResultDenied, denied[0].Result)
+	assert.Equal("rate limited", denied[0].Error)
+}
+
+func TestPassivesDontThrottleMentions(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.id = "UJARVIS"
+	b.Directory().SetUsers([]slack.User{{ID: "U01"}})
+	b.SetConfig(modules.ConfigOptionPassive, "true")
+	b.SetConfig(modules.ConfigRateLimitUser, "2/1m")
+	b.SetConfig(modules.ConfigRateLimitChannel, "2/1m")
+
+	ran := []string{}
+	record := func(id string) core.MessageHandler {
+		return func(b core.Bot, m *slack.Message) error {
+			ran = append(ran, id)
+			return nil
+		}
+	}
+	b.AddAction(core.Action{ID: "jira", MessagePattern: "BUGS-[0-9]+", Passive: true, Handler: record("jira")})
+	b.AddAction(core.Action{ID: "help", MessagePattern: "^help", Handler: record("help")})
+
+	message := func(text string) *slack.Message {
+		return &slack.Message{Type: "message", Channel: "C01", User: "U01", Text: text}
+	}
+
+	for x := 0; x < 3; x++ {
+		assert.Nil(b.dispatchResponse(message("looking at BUGS-1234")))
+	}
+	assert.Equal([]string{"jira", "jira"}, ran, "passives are still limited")
+
+	assert.Nil(b.dispatchResponse(message("<@UJARVIS> help")))
+	assert.Nil(b.dispatchResponse(message("<@UJARVIS> help")))
+	assert.Equal([]string{"jira", "jira", "help", "help"}, ran)
+}
+
+func TestOutgoingMessagesAreQueued(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+
+	var message slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"channel":"C01","user":"U01","text":"help","ts":"1476900000.000001"}`), &message))
+	assert.Nil(b.Reply(&message, "hello"))
+	assert.Nil(b.Say("C01", "hello again"))
+	assert.Nil(b.DirectMessage("U01", "hello there"))
+	assert.Nil(b.InviteUser("C02", "U01"))
+
+	stats := b.OutboundQueue().Stats()
+	assert.Equal(4, stats.Depth, "nothing is sent until the bot is started")
+	assert.Zero(stats.Sent)
+}
+
+func TestRunHandlerMetrics(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+
+	m := &slack.Message{User: "U01", Channel: "C01"}
+	ok := core.Action{ID: "ok", Handler: func(b core.Bot, m *slack.Message) error { return nil }}
+	failing := core.Action{ID: "failing", Handler: func(b core.Bot, m *slack.Message) error { return exception.New("this is only a test") }}
+	denied := core.Action{ID: "denied", Handler: func(b core.Bot, m *slack.Message) error { return core.Deny("nope") }}
+	panicking := core.Action{ID: "panicking", Handler: func(b core.Bot, m *slack.Message) error { panic("this is only a test") }}
+
+	assert.Nil(b.runAction(ok, m))
+	assert.Nil(b.runAction(ok, m))
+	assert.NotNil(b.runAction(failing, m))
+	assert.NotNil(b.runAction(denied, m))
+	func() {
+		defer func() {
+			assert.NotNil(recover(), "panics are passed along")
+		}()
+		b.runAction(panicking, m)
+	}()
+
+	metrics := b.Metrics()
+	assert.Equal(float64(2), metrics.ActionsDispatched.Value("ok"))
+	assert.Equal(uint64(2), metrics.ActionDuration.Count("ok"))
+	assert.Zero(metrics.ActionErrors.Value("ok"))
+	assert.Equal(float64(1), metrics.ActionErrors.Value("failing"))
+	assert.Zero(metrics.ActionErrors.Value("denied"), "denials aren't errors")
+	assert.Equal(float64(1), metrics.ActionPanics.Value("panicking"))
+	assert.Equal(uint64(1), metrics.ActionDuration.Count("panicking"))
+
+	families := b.CollectMetrics()
+	assert.NotEmpty(families)
+	for _, family := range families {
+		for _, sample := range family.Samples {
+			assert.Equal("bot", sample.Labels[0].Name, family.Name)
+		}
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.SetConfig(modules.ConfigDataPath, t.TempDir())
+	b.id = "UJARVIS"
+	b.Directory().SetUsers([]slack.User{{ID: "U01"}})
+
+	answered := []string{}
+	b.AddAction(core.Action{ID: "test", MessagePattern: "(.*)", Handler: func(b core.Bot, m *slack.Message) error {
+		answered = append(answered, m.Text)
+		return nil
+	}})
+
+	message := func(seconds int64, text string) slack.Message {
+		var m slack.Message
+		ts := fmt.Sprintf("%d.000001", time.Now().UTC().Unix()-seconds)
+		assert.Nil(json.Unmarshal([]byte(fmt.Sprintf(`{"type":"message","user":"U01","text":%q,"ts":%q}`, text, ts)), &m))
+		return m
+	}
+	seen := message(120, "<@UJARVIS> seen")
+	assert.True(b.processed.Mark("C01", *seen.Timestamp))
+	assert.True(b.processed.Mark("D01", *seen.Timestamp))
+
+	requested := []string{}
+	history := func(channelID string, oldest time.Time) ([]slack.Message, error) {
+		requested = append(requested, channelID)
+		// newest first, like slack.
+		return []slack.Message{
+			message(10, "<@UJARVIS> second"),
+			message(20, "not for the bot"),
+			message(30, "<@UJARVIS> first"),
+			seen,
+			message(3600, "<@UJARVIS> too old"),
+		}, nil
+	}
+
+	b.catchUp(history)
+	assert.Equal([]string{"C01"}, requested, "only public channels have history")
+	assert.Equal([]string{"<@UJARVIS> first", "<@UJARVIS> second"}, answered)
+	assert.Equal(1, b.OutboundQueue().Stats().Depth, "a late notice is posted")
+
+	b.catchUp(history)
+	assert.Len(answered, 2, "messages are only answered once")
+
+	b.SetConfig(modules.ConfigCatchUpMaxAge, "off")
+	requested = nil
+	b.catchUp(history)
+	assert.Empty(requested)
+}
+
+func TestCatchUpAfterRestart(t *testing.T) {
+	assert := assert.New(t)
+	dataPath := t.TempDir()
+
+	message := func(seconds int64, text string) slack.Message {
+		var m slack.Message
+		ts := fmt.Sprintf("%d.000001", time.Now().UTC().Unix()-seconds)
+		assert.Nil(json.Unmarshal([]byte(fmt.Sprintf(`{"type":"message","user":"U01","text":%q,"ts":%q}`, text, ts)), &m))
+		return m
+	}
+	seen := message(120, "<@UJARVIS> seen")
+
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.SetConfig(modules.ConfigDataPath, dataPath)
+	assert.True(b.processed.Mark("C01", *seen.Timestamp))
+	assert.Nil(b.Stop())
+
+	restarted := NewBot(slack.UUIDv4().ToShortString())
+	restarted.id = "UJARVIS"
+	restarted.SetConfig(modules.ConfigDataPath, dataPath)
+	restarted.Directory().SetUsers([]slack.User{{ID: "U01"}})
+	answered := []string{}
+	restarted.AddAction(core.Action{ID: "test", MessagePattern: "(.*)", Handler: func(b core.Bot, m *slack.Message) error {
+		answered = append(answered, m.Text)
+		return nil
+	}})
+	restarted.restoreProcessed()
+
+	var oldest time.Time
+	restarted.catchUp(func(channelID string, since time.Time) ([]slack.Message, error) {
+		oldest = since
+		return []slack.Message{message(10, "<@UJARVIS> missed"), seen}, nil
+	})
+	assert.Equal(seen.Timestamp.Time(), oldest, "history is read from the last message the previous bot processed")
+	assert.Equal([]string{"<@UJARVIS> missed"}, answered)
+}
+
+func TestDispatchIntent(t *testing.T) {
+	assert := assert.New(t)
+	b := NewBot(slack.UUIDv4().ToShortString())
+	b.id = "UJARVIS"
+	b.Directory().SetUsers([]slack.User{{ID: "U01"}})
+
+	ran := []string{}
+	record := func(id string) core.MessageHandler {
+		return func(b core.Bot, m *slack.Message) error {
+			ran = append(ran, id+": "+m.Text)
+			return nil
+		}
+	}
+	b.AddAction(core.Action{ID: "stock.price", MessagePattern: "^stock:price", Handler: record("stock.price"),
+		Command: "stock:price {ticker}", Slots: map[string]core.SlotType{"ticker": core.SlotTicker},
+		Examples: []string{"what's {ticker} trading at", "what is the stock price of {ticker}"}})
+	b.AddAction(core.Action{ID: "catch_all", MessagePattern: "(.*)", Priority: core.PriorityCatchAll, Handler: record("catch_all")})
+
+	mention := func(text string) *slack.Message {
+		var m slack.Message
+		assert.Nil(json.Unmarshal([]byte(fmt.Sprintf(`{"type":"message","channel":"C01","user":"U01","text":%q}`, "<@UJARVIS> "+text)), &m))
+		return &m
+	}
+
+	assert.Nil(b.dispatchResponse(mention("stock:price goog")))
+	assert.Nil(b.dispatchResponse(mention("what's GOOG trading at?")))
+	assert.Nil(b.dispatchResponse(mention("tell me a joke")))
+	assert.Equal([]string{"stock.price: <@UJARVIS> stock:price goog", "stock.price: stock:price GOOG", "catch_all: <@UJARVIS> tell me a joke"}, ran)
+	assert.Equal("stock:price GOOG", b.AuditLog().Recent(2)[1].Arguments, "intents are audited with their command")
+
+	assert.Nil(b.dispatchResponse(mention("what's the price")))
+	assert.Len(ran, 3, "incomplete intents aren't run")
+	assert.Equal(1, b.OutboundQueue().Stats().Depth, "they're suggested instead")
+
+	assert.Nil(b.dispatchResponse(mention("what's lunch trading at?")))
+	assert.Len(ran, 3, "lower case words aren't tickers")
+
+	b.SetConfig(modules.ConfigIntentThreshold, "off")
+	b.SetConfig(modules.ConfigIntentSuggestThreshold, "off")
+	assert.Nil(b.dispatchResponse(mention("what's GOOG trading at?")))
+	assert.Equal("catch_all: <@UJARVIS> what's GOOG trading at?", ran[3])
+
+	b.RemoveAction("stock.price")
+	assert.Equal(0, b.intents.Len())
+}
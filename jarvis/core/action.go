@@ -19,6 +19,35 @@ type Action struct {
 	Passive        bool
 	Handler        MessageHandler
 	Priority       int
+
+	// ReplyInThread has the action reply in a thread on the triggering message even if it
+	// wasn't sent in one, which keeps verbose output out of the channel.
+	ReplyInThread bool
+
+	// Reaction makes the action a reaction action, triggered when someone reacts to a message with the
+	// named emoji (e.g. `pushpin`) instead of by a message pattern. The handler is passed the reaction
+	// event, with the message that was reacted to as `m.Message`.
+	Reaction string
+
+	// ReactionRemoved triggers a reaction action when the reaction is removed instead of when it's added.
+	ReactionRemoved bool
+
+	// Examples are example utterances for the action, with slots written as `{name}`, e.g. `what's {ticker} trading
+	// at`. Mentions that don't match any message pattern are classified against them, and the closest action runs
+	// with its command if it's close enough.
+	Examples []string
+
+	// Slots are the types of the slots in the examples, by name; slots without a type are single words.
+	Slots map[string]SlotType
+
+	// Command is the message the handler sees when the action is matched by its examples, with the slots filled
+	// in, e.g. `stock:price {ticker}`.
+	Command string
+}
+
+// IsReaction returns if the action is triggered by a reaction.
+func (a Action) IsReaction() bool {
+	return len(a.Reaction) != 0
 }
 
 // ActionsByPriority sorts an action slice by the priority desc.
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+)
+
+const (
+	// DefaultAuditLogCapacity is the default number of entries kept in memory for queries.
+	DefaultAuditLogCapacity = 1000
+
+	// AuditResultSuccess is the result of an action that ran without error.
+	AuditResultSuccess = "success"
+
+	// AuditResultError is the result of an action that returned an error (or panicked).
+	AuditResultError = "error"
+
+	// AuditResultDenied is the result of an action the user wasn't allowed to run.
+	AuditResultDenied = "denied"
+)
+
+// Deny returns an error that action handlers return when a user isn't allowed to run the action;
+// it's recorded in the audit log as `denied` and the reason is replied to the user.
+func Deny(format string, args ...interface{}) error {
+	return &DeniedError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// DeniedError is the error returned by `Deny`.
+type DeniedError struct {
+	Reason string
+}
+
+// Error implements error.
+func (de *DeniedError) Error() string {
+	return de.Reason
+}
+
+// IsDenied returns if an error is a `DeniedError`.
+func IsDenied(err error) bool {
+	_, isDenied := err.(*DeniedError)
+	return isDenied
+}
+
+// AuditEntry is the record of a dispatched action.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Channel   string    `json:"channel"`
+	Action    string    `json:"action"`
+	Arguments string    `json:"arguments,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// NewAuditLog returns a new audit log that keeps the last `capacity` entries in memory.
+func NewAuditLog(capacity int) *AuditLog {
+	if capacity < 1 {
+		capacity = DefaultAuditLogCapacity
+	}
+	return &AuditLog{
+		capacity: capacity,
+		entries:  []AuditEntry{},
+	}
+}
+
+// AuditLog is an append-only log of the actions the bot dispatches.
+// Recent entries are kept in memory for queries; with an output set every entry is also appended to a JSON-lines file.
+type AuditLog struct {
+	lock     sync.Mutex
+	capacity int
+	entries  []AuditEntry
+	output   *os.File
+}
+
+// SetOutput sets the JSON-lines file entries are appended to, loading the most recent entries already in it.
+func (al *AuditLog) SetOutput(path string) error {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return exception.Wrap(err)
+	}
+	output, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return exception.Wrap(err)
+	}
+
+	entries := []AuditEntry{}
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		output.Close()
+		return exception.Wrap(err)
+	}
+
+	if al.output != nil {
+		al.output.Close()
+	}
+	al.output = output
+	al.entries = append(entries, al.entries...)
+	al.trim()
+	return nil
+}
+
+// Close closes the output file, if there is one.
+func (al *AuditLog) Close() error {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+	if al.output == nil {
+		return nil
+	}
+	err := al.output.Close()
+	al.output = nil
+	return exception.Wrap(err)
+}
+
+// Add appends an entry to the log.
+func (al *AuditLog) Add(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	al.lock.Lock()
+	defer al.lock.Unlock()
+	al.entries = append(al.entries, entry)
+	al.trim()
+
+	if al.output == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	_, err = al.output.Write(append(line, '\n'))
+	return exception.Wrap(err)
+}
+
+// Record runs a change made outside of chat (e.g. through the api) and adds an entry for it as `user`, returning the
+// change's error. The entry is kept in memory even if it can't be appended to the output file.
+func (al *AuditLog) Record(user, action, arguments string, change func() error) error {
+	entry := AuditEntry{User: user, Action: action, Arguments: arguments, Result: AuditResultSuccess}
+	err := change()
+	if err != nil {
+		entry.Result = AuditResultError
+		entry.Error = err.Error()
+	}
+	al.Add(entry)
+	return err
+}
+
+// Recent returns up to `limit` of the most recent entries, most recent first.
+func (al *AuditLog) Recent(limit int) []AuditEntry {
+	return al.filter(limit, func(entry AuditEntry) bool { return true })
+}
+
+// ByUser returns up to `limit` of the most recent entries for a user, most recent first.
+func (al *AuditLog) ByUser(userID string, limit int) []AuditEntry {
+	return al.filter(limit, func(entry AuditEntry) bool { return entry.User == userID })
+}
+
+func (al *AuditLog) filter(limit int, predicate func(AuditEntry) bool) []AuditEntry {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	results := []AuditEntry{}
+	for index := len(al.entries) - 1; index >= 0 && (limit < 1 || len(results) < limit); index-- {
+		if predicate(al.entries[index]) {
+			results = append(results, al.entries[index])
+		}
+	}
+	return results
+}
+
+func (al *AuditLog) trim() {
+	if len(al.entries) > al.capacity {
+		al.entries = al.entries[len(al.entries)-al.capacity:]
+	}
+}
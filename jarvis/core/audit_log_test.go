@@ -0,0 +1,87 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-exception"
+)
+
+func TestAuditLog(t *testing.T) {
+	assert := assert.New(t)
+
+	al := NewAuditLog(3)
+	assert.Nil(al.Add(AuditEntry{User: "U01", Action: "config.set", Result: AuditResultSuccess}))
+	assert.Nil(al.Add(AuditEntry{User: "U02", Action: "run", Result: AuditResultError, Error: "nope"}))
+	assert.Nil(al.Add(AuditEntry{User: "U01", Action: "module.unload", Result: AuditResultDenied}))
+	assert.Nil(al.Add(AuditEntry{User: "U01", Action: "help", Result: AuditResultSuccess}))
+
+	recent := al.Recent(0)
+	assert.Len(recent, 3)
+	assert.Equal("help", recent[0].Action)
+	assert.Equal("run", recent[2].Action)
+	assert.False(recent[0].Time.IsZero())
+
+	assert.Len(al.Recent(1), 1)
+
+	byUser := al.ByUser("U01", 10)
+	assert.Len(byUser, 2)
+	assert.Equal("module.unload", byUser[1].Action)
+}
+
+func TestAuditLogRecord(t *testing.T) {
+	assert := assert.New(t)
+
+	al := NewAuditLog(10)
+	assert.Nil(al.Record("api", "module.load", "util", func() error { return nil }))
+	assert.NotNil(al.Record("api", "job.run", "clock", func() error { return exception.New("job `clock` is disabled") }))
+
+	entries := al.ByUser("api", 0)
+	assert.Len(entries, 2)
+	assert.Equal(AuditResultError, entries[0].Result)
+	assert.Equal("job `clock` is disabled", entries[0].Error)
+	assert.Equal(AuditResultSuccess, entries[1].Result)
+	assert.Equal("util", entries[1].Arguments)
+}
+
+func TestAuditLogOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit", "audit.jsonl")
+
+	al := NewAuditLog(DefaultAuditLogCapacity)
+	assert.Nil(al.SetOutput(path))
+	assert.Nil(al.Add(AuditEntry{User: "U01", Action: "config.set", Arguments: "foo bar", Result: AuditResultSuccess}))
+	assert.Nil(al.Add(AuditEntry{User: "U02", Action: "run", Result: AuditResultError, Error: "nope"}))
+	assert.Nil(al.Close())
+
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	assert.Len(lines, 2)
+	assert.True(strings.Contains(lines[0], `"arguments":"foo bar"`))
+
+	reopened := NewAuditLog(DefaultAuditLogCapacity)
+	assert.Nil(reopened.SetOutput(path))
+	defer reopened.Close()
+	assert.Len(reopened.Recent(0), 2)
+	assert.Nil(reopened.Add(AuditEntry{User: "U01", Action: "help", Result: AuditResultSuccess}))
+	assert.Equal("help", reopened.ByUser("U01", 0)[0].Action)
+}
+
+func TestDeny(t *testing.T) {
+	assert := assert.New(t)
+
+	err := Deny("only admins can `%s`", "run")
+	assert.True(IsDenied(err))
+	assert.Equal("only admins can `run`", err.Error())
+	assert.False(IsDenied(exception.New("this is only a test")))
+	assert.False(IsDenied(nil))
+}
@@ -4,7 +4,7 @@ import (
 	"github.com/blendlabs/go-chronometer"
 	logger "github.com/blendlabs/go-logger"
 	"github.com/blendlabs/go-util/collections"
-	"github.com/wcharczuk/go-slack"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 // MessageHandler is a function that takes a slack message and acts on it.
@@ -29,8 +29,18 @@ type Bot interface {
 	OrganizationName() string
 
 	Configuration() map[string]string
+	SetConfig(key, value string)
 	State() map[string]interface{}
 	JobManager() *chronometer.JobManager
+	LoadJob(job chronometer.Job) error
+	JobHistory() *JobHistory
+	JobOutputChannels(jobName string) []string
+	Sessions() *Sessions
+	Correlations() *MessageCorrelations
+	Directory() *Directory
+	AuditLog() *AuditLog
+	Errors() *ErrorLog
+	OutboundQueue() *OutboundQueue
 
 	LoadModule(moduleName string) error
 	UnloadModule(moduleName string)
@@ -43,13 +53,23 @@ type Bot interface {
 	TriggerAction(id string, m *slack.Message) error
 
 	Client() *slack.Client
+	IsConnected() bool
 	ActiveChannels() []string
 
 	FindUser(userID string) *slack.User
 	FindChannel(channelID string) *slack.Channel
+	FindChannelByName(name string) *slack.Channel
+	ResolveChannelID(channel string) string
 
 	Say(destinationID string, components ...interface{}) error
 	Sayf(destinationID string, format string, components ...interface{}) error
+	Reply(m *slack.Message, components ...interface{}) error
+	Replyf(m *slack.Message, format string, components ...interface{}) error
+	ReplyMessage(m *slack.Message, message *slack.ChatMessage) error
+	PostMessage(message *slack.ChatMessage) error
+	InviteUser(channelID, userID string) error
+	DirectMessage(userID string, components ...interface{}) error
+	DirectMessagef(userID string, format string, components ...interface{}) error
 
 	Logger() *logger.Agent
 	Log(components ...interface{})
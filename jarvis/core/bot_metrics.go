@@ -0,0 +1,58 @@
+package core
+
+// NewBotMetrics returns a new, empty set of bot metrics.
+func NewBotMetrics() *BotMetrics {
+	return &BotMetrics{
+		MessagesReceived:    NewCounterVec("jarvis_messages_received_total", "Messages received from slack."),
+		ActionsDispatched:   NewCounterVec("jarvis_actions_dispatched_total", "Actions run, by action id.", "action"),
+		ActionDuration:      NewHistogramVec("jarvis_action_duration_seconds", "Action handler latency, by action id.", DefaultLatencyBuckets, "action"),
+		ActionErrors:        NewCounterVec("jarvis_action_errors_total", "Action handlers that returned an error, by action id.", "action"),
+		ActionPanics:        NewCounterVec("jarvis_action_panics_total", "Action handlers that panicked, by action id.", "action"),
+		JobsRun:             NewCounterVec("jarvis_jobs_run_total", "Job runs, by job name.", "job"),
+		JobsFailed:          NewCounterVec("jarvis_jobs_failed_total", "Job runs that failed, by job name.", "job"),
+		WebsocketReconnects: NewCounterVec("jarvis_websocket_reconnects_total", "Times the slack websocket was reconnected."),
+		PingRTT:             NewHistogramVec("jarvis_ping_rtt_seconds", "Round trip time of slack websocket pings.", DefaultLatencyBuckets),
+	}
+}
+
+// BotMetrics are the counters and histograms a bot records.
+type BotMetrics struct {
+	MessagesReceived    *CounterVec
+	ActionsDispatched   *CounterVec
+	ActionDuration      *HistogramVec
+	ActionErrors        *CounterVec
+	ActionPanics        *CounterVec
+	JobsRun             *CounterVec
+	JobsFailed          *CounterVec
+	WebsocketReconnects *CounterVec
+	PingRTT             *HistogramVec
+}
+
+// CollectMetrics implements MetricsCollector.
+func (bm *BotMetrics) CollectMetrics() []MetricFamily {
+	families := []MetricFamily{}
+	for _, collector := range []MetricsCollector{
+		bm.MessagesReceived,
+		bm.ActionsDispatched,
+		bm.ActionDuration,
+		bm.ActionErrors,
+		bm.ActionPanics,
+		bm.JobsRun,
+		bm.JobsFailed,
+		bm.WebsocketReconnects,
+		bm.PingRTT,
+	} {
+		families = append(families, collector.CollectMetrics()...)
+	}
+	return families
+}
+
+// OutboundQueueMetrics returns the metrics for an outbound queue's stats.
+func OutboundQueueMetrics(stats OutboundQueueStats) []MetricFamily {
+	return []MetricFamily{
+		{Name: "jarvis_outbound_queue_depth", Help: "Outgoing messages waiting to be sent.", Type: MetricTypeGauge, Samples: []MetricSample{{Value: float64(stats.Depth)}}},
+		{Name: "jarvis_outbound_sent_total", Help: "Outgoing messages sent.", Type: MetricTypeCounter, Samples: []MetricSample{{Value: float64(stats.Sent)}}},
+		{Name: "jarvis_outbound_retries_total", Help: "Outgoing message sends that were retried.", Type: MetricTypeCounter, Samples: []MetricSample{{Value: float64(stats.Retries)}}},
+		{Name: "jarvis_outbound_dropped_total", Help: "Outgoing messages dropped after failing.", Type: MetricTypeCounter, Samples: []MetricSample{{Value: float64(stats.Dropped)}}},
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blendlabs/go-util"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+var (
+	cancellationPhrases = []string{"nevermind", "never mind", "nvm", "cancel", "forget it", "stop"}
+	affirmativePhrases  = []string{"yes", "y", "yep", "yeah", "sure", "ok", "okay", "do it", "confirm"}
+)
+
+// ReplyHandler handles a user's reply in a conversation.
+type ReplyHandler func(b Bot, reply *slack.Message, c *Conversation) error
+
+// IsCancellation returns if a message is the user calling off a conversation (e.g. "nevermind").
+func IsCancellation(message string) bool {
+	return EqualsAny(normalizeReply(message), cancellationPhrases...)
+}
+
+// IsAffirmative returns if a message is the user saying yes.
+func IsAffirmative(message string) bool {
+	return EqualsAny(normalizeReply(message), affirmativePhrases...)
+}
+
+func normalizeReply(message string) string {
+	return strings.Trim(strings.ToLower(util.String.TrimWhitespace(LessMentions(message))), ".!")
+}
+
+// NewConversation starts a conversation with the sender of a message, in the channel (and thread) it was sent in.
+func NewConversation(m *slack.Message) *Conversation {
+	return &Conversation{
+		UserID:    m.User,
+		ChannelID: m.Channel,
+		ThreadID:  ThreadID(m),
+		Timeout:   DefaultSessionTimeout,
+		State:     map[string]interface{}{},
+		origin:    m,
+	}
+}
+
+// Conversation lets an action ask a follow-up question; the next message from the same user in the
+// same channel (or thread) goes to the reply handler instead of the bot's actions.
+// Replying "nevermind" (or "cancel") ends the conversation, as does not replying before the timeout.
+type Conversation struct {
+	UserID    string
+	ChannelID string
+	ThreadID  string
+	Timeout   time.Duration
+	State     map[string]interface{}
+
+	origin *slack.Message
+}
+
+// Ask asks the user a question and routes their reply to the handler.
+// The handler can call `Ask` again to keep the conversation going.
+func (c *Conversation) Ask(b Bot, question string, handler ReplyHandler) error {
+	session := NewSession(c.UserID, c.ChannelID, func(b Bot, m *slack.Message, s *Session) error {
+		s.End()
+		if IsCancellation(m.Text) {
+			return c.say(b, "ok, nevermind.")
+		}
+		return handler(b, m, c)
+	})
+	session.ChannelID = c.ChannelID
+	session.ThreadID = c.ThreadID
+	session.Timeout = c.Timeout
+	session.OnExpire = func(s *Session) {
+		c.say(b, fmt.Sprintf("<@%s> I stopped waiting for an answer.", c.UserID))
+	}
+	b.Sessions().Start(session)
+	return c.say(b, question)
+}
+
+// Confirm asks the user a yes or no question and calls `onConfirm` with their reply if they say yes.
+func (c *Conversation) Confirm(b Bot, question string, onConfirm MessageHandler) error {
+	return c.Ask(b, fmt.Sprintf("%s (yes/no)", question), func(b Bot, reply *slack.Message, c *Conversation) error {
+		if IsAffirmative(reply.Text) {
+			return onConfirm(b, reply)
+		}
+		return c.say(b, "ok, I won't.")
+	})
+}
+
+// End ends the conversation without waiting for a reply.
+func (c *Conversation) End(b Bot) {
+	b.Sessions().EndInThread(c.UserID, c.ChannelID, c.ThreadID)
+}
+
+// say replies in the thread the conversation started in, if it started in one.
+func (c *Conversation) say(b Bot, text string) error {
+	if c.origin == nil {
+		return b.Say(c.ChannelID, text)
+	}
+	return b.Reply(c.origin, text)
+}
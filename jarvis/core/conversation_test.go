@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestConversationAsk(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	said := []string{}
+	mb.MockMessageHandler(func(b Bot, m *slack.Message) error {
+		said = append(said, m.Text)
+		return nil
+	})
+
+	start := &slack.Message{User: "U01", Channel: "C01", Text: "create issue"}
+	answers := []string{}
+	c := NewConversation(start)
+	assert.Nil(c.Ask(mb, "what's the title?", func(b Bot, reply *slack.Message, c *Conversation) error {
+		answers = append(answers, reply.Text)
+		return c.Ask(b, "what's the description?", func(b Bot, reply *slack.Message, c *Conversation) error {
+			answers = append(answers, reply.Text)
+			return nil
+		})
+	}))
+	assert.Equal([]string{"what's the title?"}, said)
+
+	handled, err := mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "the build is broken"})
+	assert.True(handled)
+	assert.Nil(err)
+	handled, err = mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "it doesn't build"})
+	assert.True(handled)
+	assert.Nil(err)
+	assert.Equal([]string{"the build is broken", "it doesn't build"}, answers)
+
+	handled, _ = mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "anything else"})
+	assert.False(handled)
+}
+
+func TestConversationThread(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	var start slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"user":"U01","channel":"C01","text":"start","thread_ts":"1476900000.000002"}`), &start))
+	assert.Equal("1476900000.000002", ThreadID(&start))
+
+	replied := false
+	assert.Nil(NewConversation(&start).Ask(mb, "question?", func(b Bot, reply *slack.Message, c *Conversation) error {
+		replied = true
+		return nil
+	}))
+
+	// replies outside of the thread aren't part of the conversation.
+	handled, _ := mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "answer"})
+	assert.False(handled)
+	handled, _ = mb.Sessions().Handle(mb, &start)
+	assert.True(handled)
+	assert.True(replied)
+}
+
+func TestConversationConfirmAndCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	said := []string{}
+	mb.MockMessageHandler(func(b Bot, m *slack.Message) error {
+		said = append(said, m.Text)
+		return nil
+	})
+
+	start := &slack.Message{User: "U01", Channel: "C01", Text: "do the thing"}
+	confirmed := 0
+	onConfirm := func(b Bot, m *slack.Message) error {
+		confirmed++
+		return nil
+	}
+
+	assert.Nil(NewConversation(start).Confirm(mb, "are you sure?", onConfirm))
+	mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "<@UBOT> Yes!"})
+	assert.Equal(1, confirmed)
+
+	assert.Nil(NewConversation(start).Confirm(mb, "are you sure?", onConfirm))
+	mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "no"})
+	assert.Equal(1, confirmed)
+	assert.Equal("ok, I won't.", said[len(said)-1])
+
+	assert.Nil(NewConversation(start).Confirm(mb, "are you sure?", onConfirm))
+	mb.Sessions().Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "nevermind"})
+	assert.Equal(1, confirmed)
+	assert.Equal("ok, nevermind.", said[len(said)-1])
+
+	assert.True(IsCancellation("Never mind."))
+	assert.False(IsCancellation("cancel the order"))
+	assert.True(IsAffirmative("yep"))
+	assert.False(IsAffirmative("nope"))
+}
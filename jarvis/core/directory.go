@@ -0,0 +1,228 @@
+package core
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+var (
+	// DirectoryEvents are the slack events that change the directory.
+	DirectoryEvents = []slack.Event{
+		slack.EventTeamJoin,
+		slack.EventUserChange,
+		slack.EventChannelCreated,
+		slack.EventChannelRename,
+		slack.EventChannelDeleted,
+		slack.EventChannelArchive,
+		slack.EventChannelUnArchive,
+		slack.EventChannelJoined,
+		slack.EventChannelLeft,
+	}
+)
+
+// FirstName returns a user's first name, falling back to their user name if their profile doesn't have one.
+func FirstName(user *slack.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.Profile != nil && len(user.Profile.FirstName) != 0 {
+		return user.Profile.FirstName
+	}
+	return user.Name
+}
+
+// NewDirectory returns a new, empty directory.
+func NewDirectory() *Directory {
+	return &Directory{
+		users:    map[string]slack.User{},
+		channels: map[string]slack.Channel{},
+	}
+}
+
+// Directory is the bot's view of the users and channels in the slack team.
+// It's loaded from the rtm session, kept up to date by applying slack events and reconciled periodically.
+type Directory struct {
+	lock     sync.RWMutex
+	users    map[string]slack.User
+	channels map[string]slack.Channel
+}
+
+// SetUsers replaces the users in the directory.
+func (d *Directory) SetUsers(users []slack.User) {
+	lookup := map[string]slack.User{}
+	for _, user := range users {
+		lookup[user.ID] = user
+	}
+	d.lock.Lock()
+	d.users = lookup
+	d.lock.Unlock()
+}
+
+// SetChannels replaces the channels in the directory.
+func (d *Directory) SetChannels(channels []slack.Channel) {
+	lookup := map[string]slack.Channel{}
+	for _, channel := range channels {
+		lookup[channel.ID] = channel
+	}
+	d.lock.Lock()
+	d.channels = lookup
+	d.lock.Unlock()
+}
+
+// PutUser adds or updates a user.
+func (d *Directory) PutUser(user slack.User) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.users[user.ID] = user
+}
+
+// PutChannel adds or updates a channel.
+func (d *Directory) PutChannel(channel slack.Channel) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.channels[channel.ID] = channel
+}
+
+// RemoveChannel removes a channel.
+func (d *Directory) RemoveChannel(channelID string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.channels, channelID)
+}
+
+// User returns a user by id, or nil if they aren't in the directory.
+func (d *Directory) User(userID string) *slack.User {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if user, hasUser := d.users[userID]; hasUser {
+		return &user
+	}
+	return nil
+}
+
+// UserByName returns a user by user name (with or without the leading `@`), or nil if there isn't one.
+func (d *Directory) UserByName(name string) *slack.User {
+	name = strings.TrimPrefix(name, "@")
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	for _, user := range d.users {
+		if strings.EqualFold(user.Name, name) {
+			return &user
+		}
+	}
+	return nil
+}
+
+// UserByEmail returns a user by email address, or nil if there isn't one.
+func (d *Directory) UserByEmail(email string) *slack.User {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	for _, user := range d.users {
+		if user.Profile != nil && strings.EqualFold(user.Profile.Email, email) {
+			return &user
+		}
+	}
+	return nil
+}
+
+// Users returns the users in the directory.
+func (d *Directory) Users() []slack.User {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	users := []slack.User{}
+	for _, user := range d.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// Channel returns a channel by id, or nil if it isn't in the directory.
+func (d *Directory) Channel(channelID string) *slack.Channel {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if channel, hasChannel := d.channels[channelID]; hasChannel {
+		return &channel
+	}
+	return nil
+}
+
+// ChannelByName returns a channel by name (with or without the leading `#`), or nil if there isn't one.
+func (d *Directory) ChannelByName(name string) *slack.Channel {
+	name = strings.TrimPrefix(name, "#")
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	for _, channel := range d.channels {
+		if strings.EqualFold(channel.Name, name) {
+			return &channel
+		}
+	}
+	return nil
+}
+
+// Channels returns the channels in the directory.
+func (d *Directory) Channels() []slack.Channel {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	channels := []slack.Channel{}
+	for _, channel := range d.channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// Apply updates the directory from one of the `DirectoryEvents`; other events are ignored.
+func (d *Directory) Apply(m *slack.Message) error {
+	switch m.Type {
+	case slack.EventTeamJoin, slack.EventUserChange:
+		var event struct {
+			User slack.User `json:"user"`
+		}
+		if err := DecodeEvent(m, &event); err != nil {
+			return err
+		}
+		d.PutUser(event.User)
+	case slack.EventChannelCreated, slack.EventChannelRename, slack.EventChannelJoined:
+		var event struct {
+			Channel slack.Channel `json:"channel"`
+		}
+		if err := DecodeEvent(m, &event); err != nil {
+			return err
+		}
+		d.mergeChannel(event.Channel, m.Type == slack.EventChannelJoined)
+	case slack.EventChannelDeleted:
+		d.RemoveChannel(m.Channel)
+	case slack.EventChannelArchive, slack.EventChannelUnArchive, slack.EventChannelLeft:
+		d.lock.Lock()
+		if channel, hasChannel := d.channels[m.Channel]; hasChannel {
+			switch m.Type {
+			case slack.EventChannelArchive:
+				channel.IsArchived = true
+			case slack.EventChannelUnArchive:
+				channel.IsArchived = false
+			case slack.EventChannelLeft:
+				channel.IsMember = false
+			}
+			d.channels[m.Channel] = channel
+		}
+		d.lock.Unlock()
+	}
+	return nil
+}
+
+// mergeChannel updates a channel from an event, which (other than for `channel_joined`) only has some of the channel's fields.
+func (d *Directory) mergeChannel(update slack.Channel, joined bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	channel, hasChannel := d.channels[update.ID]
+	if joined || !hasChannel {
+		channel = update
+	} else {
+		channel.Name = update.Name
+	}
+	if joined {
+		channel.IsMember = true
+	}
+	d.channels[update.ID] = channel
+}
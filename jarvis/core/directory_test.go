@@ -0,0 +1,76 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func mockEvent(assert *assert.Assertions, raw string) *slack.Message {
+	var m slack.Message
+	json.Unmarshal([]byte(raw), &m)
+	var mt slack.MessageType
+	assert.Nil(json.Unmarshal([]byte(raw), &mt))
+	m.Type = mt.Type
+	m.Raw = []byte(raw)
+	return &m
+}
+
+func TestDirectoryLookups(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDirectory()
+	d.SetUsers([]slack.User{
+		{ID: "U01", Name: "will", Profile: &slack.UserProfile{FirstName: "Will", Email: "will@example.com"}},
+		{ID: "U02", Name: "nobody"},
+	})
+	d.SetChannels([]slack.Channel{{ID: "C01", Name: "general"}})
+
+	assert.Equal("will", d.User("U01").Name)
+	assert.Nil(d.User("U03"))
+	assert.Equal("U01", d.UserByName("@Will").ID)
+	assert.Equal("U01", d.UserByEmail("WILL@example.com").ID)
+	assert.Nil(d.UserByEmail("nobody@example.com"))
+	assert.Equal("C01", d.ChannelByName("#General").ID)
+	assert.Len(d.Users(), 2)
+	assert.Len(d.Channels(), 1)
+
+	assert.Equal("Will", FirstName(d.User("U01")))
+	assert.Equal("nobody", FirstName(d.User("U02")))
+	assert.Empty(FirstName(nil))
+}
+
+func TestDirectoryApply(t *testing.T) {
+	assert := assert.New(t)
+
+	d := NewDirectory()
+	d.SetChannels([]slack.Channel{{ID: "C01", Name: "general", IsMember: true}})
+
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"team_join","user":{"id":"U01","name":"newhire","profile":{"email":"new@example.com"}}}`)))
+	assert.Equal("newhire", d.User("U01").Name)
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"user_change","user":{"id":"U01","name":"renamed"}}`)))
+	assert.Equal("renamed", d.User("U01").Name)
+
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_created","channel":{"id":"C02","name":"new-channel","created":1476900000,"creator":"U01"}}`)))
+	assert.Equal("new-channel", d.Channel("C02").Name)
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_rename","channel":{"id":"C01","name":"everyone","created":1476900000}}`)))
+	assert.Equal("everyone", d.Channel("C01").Name)
+	assert.True(d.Channel("C01").IsMember, "renames should keep the rest of the channel")
+	assert.Nil(d.ChannelByName("general"))
+
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_archive","channel":"C01","user":"U01"}`)))
+	assert.True(d.Channel("C01").IsArchived)
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_unarchive","channel":"C01","user":"U01"}`)))
+	assert.False(d.Channel("C01").IsArchived)
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_left","channel":"C01"}`)))
+	assert.False(d.Channel("C01").IsMember)
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_joined","channel":{"id":"C01","name":"everyone"}}`)))
+	assert.True(d.Channel("C01").IsMember)
+
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"channel_deleted","channel":"C02"}`)))
+	assert.Nil(d.Channel("C02"))
+
+	assert.Nil(d.Apply(mockEvent(assert, `{"type":"presence_change","user":"U01","presence":"away"}`)))
+}
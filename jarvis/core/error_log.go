@@ -0,0 +1,65 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultErrorLogCapacity is the default number of errors an error log keeps.
+	DefaultErrorLogCapacity = 100
+)
+
+// ErrorEntry is a recorded error.
+type ErrorEntry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Error  string    `json:"error"`
+}
+
+// NewErrorLog returns a new error log that keeps the last `capacity` errors.
+func NewErrorLog(capacity int) *ErrorLog {
+	if capacity < 1 {
+		capacity = DefaultErrorLogCapacity
+	}
+	return &ErrorLog{capacity: capacity, entries: []ErrorEntry{}}
+}
+
+// ErrorLog keeps the most recent errors the bot ran into (failed handlers, jobs and sends) so they can be shown
+// somewhere other than the process logs.
+type ErrorLog struct {
+	lock     sync.Mutex
+	capacity int
+	entries  []ErrorEntry
+}
+
+// Add records an error from a source (e.g. `job clock` or `action stock.price`); nil errors are ignored.
+func (el *ErrorLog) Add(source string, err error) {
+	if err == nil {
+		return
+	}
+	el.lock.Lock()
+	defer el.lock.Unlock()
+	el.entries = append(el.entries, ErrorEntry{Time: time.Now().UTC(), Source: source, Error: err.Error()})
+	if len(el.entries) > el.capacity {
+		el.entries = el.entries[len(el.entries)-el.capacity:]
+	}
+}
+
+// Recent returns up to `limit` of the most recent errors, most recent first; a limit below 1 returns them all.
+func (el *ErrorLog) Recent(limit int) []ErrorEntry {
+	el.lock.Lock()
+	defer el.lock.Unlock()
+	results := []ErrorEntry{}
+	for index := len(el.entries) - 1; index >= 0 && (limit < 1 || len(results) < limit); index-- {
+		results = append(results, el.entries[index])
+	}
+	return results
+}
+
+// Len returns the number of errors in the log.
+func (el *ErrorLog) Len() int {
+	el.lock.Lock()
+	defer el.lock.Unlock()
+	return len(el.entries)
+}
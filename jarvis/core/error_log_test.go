@@ -0,0 +1,29 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-exception"
+)
+
+func TestErrorLog(t *testing.T) {
+	assert := assert.New(t)
+
+	el := NewErrorLog(3)
+	el.Add("job clock", nil)
+	assert.Zero(el.Len(), "nil errors aren't recorded")
+
+	for x := 0; x < 5; x++ {
+		el.Add("job clock", exception.New(fmt.Sprintf("error %d", x)))
+	}
+	assert.Equal(3, el.Len())
+
+	recent := el.Recent(2)
+	assert.Len(recent, 2)
+	assert.Equal("error 4", recent[0].Error)
+	assert.Equal("error 3", recent[1].Error)
+	assert.Equal("job clock", recent[0].Source)
+	assert.Len(el.Recent(0), 3)
+}
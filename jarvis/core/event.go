@@ -0,0 +1,32 @@
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+// EventSubscription subscribes a handler to a slack event type (e.g. `team_join` or `presence_change`),
+// or to a message subtype (e.g. `channel_leave`).
+// Subscriptions get every event of their type, whether or not passive actions are enabled.
+type EventSubscription struct {
+	ID      string
+	Event   slack.Event
+	Handler MessageHandler
+}
+
+// EventSubscriber is a module that subscribes to slack events as well as providing actions.
+type EventSubscriber interface {
+	//Subscriptions are the events the module subscribes to.
+	Subscriptions() []EventSubscription
+}
+
+// DecodeEvent decodes the raw json of an event into a given object, for events with fields the
+// `slack.Message` type doesn't have (e.g. the `user` object of a `team_join` event).
+func DecodeEvent(m *slack.Message, v interface{}) error {
+	if len(m.Raw) == 0 {
+		return exception.Newf("event `%s` has no raw payload", m.Type)
+	}
+	return exception.Wrap(json.Unmarshal(m.Raw, v))
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &slack.Message{Type: "team_join", Raw: []byte(`{"type":"team_join","user":{"id":"U01","name":"will"}}`)}
+	var teamJoin struct {
+		User slack.User `json:"user"`
+	}
+	assert.Nil(DecodeEvent(m, &teamJoin))
+	assert.Equal("U01", teamJoin.User.ID)
+	assert.Equal("will", teamJoin.User.Name)
+
+	assert.NotNil(DecodeEvent(&slack.Message{Type: "team_join"}, &teamJoin))
+}
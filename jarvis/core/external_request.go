@@ -1,69 +1,28 @@
 package core
 
 import (
-	"fmt"
-	"net/http"
+	"strconv"
 
-	"github.com/blendlabs/go-exception"
 	"github.com/blendlabs/go-request"
 )
 
-type mockedResponse struct {
-	ResponseBody []byte
-	StatusCode   int
-	Error        error
-}
-
-var isMocked bool
-var mocks map[string]mockedResponse
-
-// MockError simulates a mocked error
-func MockError(verb string, url string) {
-	Mock(
-		verb,
-		url,
-		mockedResponse{
-			StatusCode: http.StatusInternalServerError,
-			Error:      exception.New("Error! This is from service_request#MockError. If you don't want an error don't mock it."),
-		},
-	)
-}
-
-// Mock mocks a response for a given verb to a given url.
-func Mock(verb string, url string, res mockedResponse) {
-	isMocked = true
-	if mocks == nil {
-		mocks = map[string]mockedResponse{}
-	}
-	storedURL := fmt.Sprintf("%s_%s", verb, url)
-	mocks[storedURL] = res
-}
+var externalRequestDuration = NewHistogramVec("jarvis_external_request_duration_seconds", "Latency of requests to external services (jira, stocks, etc.).", DefaultLatencyBuckets, "host", "status")
 
-// MockResponseFromBinary mocks a request from a byte array response.
-func MockResponseFromBinary(verb string, url string, statusCode int, response []byte) {
-	Mock(
-		verb,
-		url,
-		mockedResponse{
-			ResponseBody: response,
-			StatusCode:   statusCode,
-		},
-	)
-}
-
-// MockResponseFromString mocks a request from a string response.
-func MockResponseFromString(verb string, url string, statusCode int, response string) {
-	MockResponseFromBinary(verb, url, statusCode, []byte(response))
-}
-
-// ClearMockedResponses clears and disables response mocking
-func ClearMockedResponses() {
-	isMocked = false
-	mocks = map[string]mockedResponse{}
+// ExternalRequestMetrics returns the latency metrics for requests made with `NewExternalRequest`.
+func ExternalRequestMetrics() MetricsCollector {
+	return externalRequestDuration
 }
 
 // NewExternalRequest Creates a new external request
 func NewExternalRequest() *request.Request {
-	req := request.New().WithMockedResponse(request.MockedResponseInjector)
+	req := request.New().WithMockedResponse(mockedResponseInjector).OnResponse(observeExternalRequest)
 	return req
 }
+
+func observeExternalRequest(req *request.Meta, res *request.ResponseMeta, body []byte) {
+	host := ""
+	if req.URL != nil {
+		host = req.URL.Host
+	}
+	externalRequestDuration.Observe(res.CompleteTime.Sub(req.StartTime).Seconds(), host, strconv.Itoa(res.StatusCode))
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/blendlabs/go-request"
+)
+
+// MockedResponse is the response to a mocked external request.
+type MockedResponse struct {
+	ResponseBody []byte
+	StatusCode   int
+	Error        error
+}
+
+// MockedRequest is an external request made while responses are mocked.
+type MockedRequest struct {
+	Verb    string
+	URL     string
+	Matched bool
+}
+
+// mock is the responses mocked for a verb and url; they're returned in order, and the last one repeats.
+type mock struct {
+	verb      string
+	url       string
+	responses []MockedResponse
+	calls     int
+}
+
+// matches returns if the mock is for a request. An empty verb matches any verb, a url without a query string
+// matches the url with any query string, and a url ending in `*` matches any url it's a prefix of.
+func (m *mock) matches(verb string, requestURL *url.URL) bool {
+	if len(m.verb) != 0 && !strings.EqualFold(m.verb, verb) {
+		return false
+	}
+	full := requestURL.String()
+	if strings.HasSuffix(m.url, "*") {
+		return strings.HasPrefix(full, strings.TrimSuffix(m.url, "*"))
+	}
+	withoutQuery := *requestURL
+	withoutQuery.RawQuery = ""
+	return m.url == full || m.url == withoutQuery.String()
+}
+
+func (m *mock) next() MockedResponse {
+	response := m.responses[len(m.responses)-1]
+	if m.calls < len(m.responses) {
+		response = m.responses[m.calls]
+	}
+	m.calls++
+	return response
+}
+
+var (
+	mocksLock      sync.Mutex
+	isMocked       bool
+	mocks          []*mock
+	mockedRequests []MockedRequest
+)
+
+// Mock mocks the responses to a given verb and url, returned in order with the last one repeating. Once anything
+// is mocked, every request made with `NewExternalRequest` is recorded, and requests that aren't mocked fail instead
+// of going out, until `ClearMockedResponses`. Later mocks for the same verb and url replace earlier ones.
+func Mock(verb string, url string, responses ...MockedResponse) {
+	if len(responses) == 0 {
+		return
+	}
+	mocksLock.Lock()
+	defer mocksLock.Unlock()
+	isMocked = true
+	for index, existing := range mocks {
+		if existing.verb == verb && existing.url == url {
+			mocks = append(mocks[:index], mocks[index+1:]...)
+			break
+		}
+	}
+	mocks = append(mocks, &mock{verb: verb, url: url, responses: responses})
+}
+
+// MockError mocks an error for a given verb and url.
+func MockError(verb string, url string) {
+	Mock(verb, url, MockedResponse{
+		StatusCode: http.StatusInternalServerError,
+		Error:      exception.New("Error! This is from service_request#MockError. If you don't want an error don't mock it."),
+	})
+}
+
+// MockResponseFromBinary mocks a request from a byte array response.
+func MockResponseFromBinary(verb string, url string, statusCode int, response []byte) {
+	Mock(verb, url, MockedResponse{ResponseBody: response, StatusCode: statusCode})
+}
+
+// MockResponseFromString mocks a request from a string response.
+func MockResponseFromString(verb string, url string, statusCode int, response string) {
+	MockResponseFromBinary(verb, url, statusCode, []byte(response))
+}
+
+// MockResponseFromFile mocks a request from a response in a file, like a fixture in `testdata`; if the file can't be
+// read the request fails with the read error.
+func MockResponseFromFile(verb string, url string, statusCode int, path string) {
+	contents, err := ioutil.ReadFile(path)
+	Mock(verb, url, MockedResponse{ResponseBody: contents, StatusCode: statusCode, Error: err})
+}
+
+// MockedRequests returns the requests made since responses were mocked, in order.
+func MockedRequests() []MockedRequest {
+	mocksLock.Lock()
+	defer mocksLock.Unlock()
+	return append([]MockedRequest{}, mockedRequests...)
+}
+
+// ClearMockedResponses clears and disables response mocking, and the recorded requests.
+func ClearMockedResponses() {
+	mocksLock.Lock()
+	defer mocksLock.Unlock()
+	isMocked = false
+	mocks = nil
+	mockedRequests = nil
+}
+
+// mockedResponseInjector answers external requests with mocked responses, once anything is mocked.
+func mockedResponseInjector(verb string, requestURL *url.URL) (bool, *request.ResponseMeta, []byte, error) {
+	mocksLock.Lock()
+	defer mocksLock.Unlock()
+	if !isMocked {
+		return false, nil, nil, nil
+	}
+
+	for index := len(mocks) - 1; index >= 0; index-- {
+		if mocks[index].matches(verb, requestURL) {
+			mockedRequests = append(mockedRequests, MockedRequest{Verb: verb, URL: requestURL.String(), Matched: true})
+			response := mocks[index].next()
+			meta := &request.ResponseMeta{StatusCode: response.StatusCode, ContentLength: int64(len(response.ResponseBody)), Headers: http.Header{}}
+			return true, meta, response.ResponseBody, response.Error
+		}
+	}
+
+	mockedRequests = append(mockedRequests, MockedRequest{Verb: verb, URL: requestURL.String()})
+	meta := &request.ResponseMeta{StatusCode: http.StatusNotImplemented, Headers: http.Header{}}
+	return true, meta, nil, exception.Newf("external request isn't mocked: %s %s", verb, requestURL.String())
+}
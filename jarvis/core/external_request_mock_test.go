@@ -0,0 +1,57 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestMockedExternalRequests(t *testing.T) {
+	assert := assert.New(t)
+	defer ClearMockedResponses()
+
+	Mock("GET", "https://example.com/sequence",
+		MockedResponse{StatusCode: http.StatusOK, ResponseBody: []byte("first")},
+		MockedResponse{StatusCode: http.StatusOK, ResponseBody: []byte("second")},
+	)
+	MockResponseFromString("POST", "https://example.com/prefix/*", http.StatusCreated, "created")
+	MockResponseFromFile("", "https://example.com/fixture", http.StatusOK, "testdata/mocked_response.json")
+
+	for _, expected := range []string{"first", "second", "second"} {
+		body, err := NewExternalRequest().AsGet().WithURL("https://example.com/sequence").WithQueryString("q", "1").String()
+		assert.Nil(err)
+		assert.Equal(expected, body)
+	}
+
+	body, meta, err := NewExternalRequest().AsPost().WithURL("https://example.com/prefix/anything").StringWithMeta()
+	assert.Nil(err)
+	assert.Equal("created", body)
+	assert.Equal(http.StatusCreated, meta.StatusCode)
+
+	var fixture struct {
+		OK bool `json:"ok"`
+	}
+	assert.Nil(NewExternalRequest().AsPut().WithURL("https://example.com/fixture").JSON(&fixture))
+	assert.True(fixture.OK)
+
+	_, err = NewExternalRequest().AsGet().WithURL("https://example.com/prefix/anything").String()
+	assert.NotNil(err, "the verb has to match")
+	_, err = NewExternalRequest().AsGet().WithURL("https://example.com/unmocked").String()
+	assert.NotNil(err)
+	assert.Contains("isn't mocked", err.Error())
+
+	requests := MockedRequests()
+	assert.Len(requests, 7)
+	assert.Equal("https://example.com/sequence?q=1", requests[0].URL)
+	assert.True(requests[4].Matched)
+	assert.Equal("GET", requests[6].Verb)
+	assert.False(requests[6].Matched)
+
+	MockResponseFromFile("GET", "https://example.com/missing", http.StatusOK, "testdata/missing.json")
+	_, err = NewExternalRequest().AsGet().WithURL("https://example.com/missing").String()
+	assert.NotNil(err)
+
+	ClearMockedResponses()
+	assert.Empty(MockedRequests())
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPongTimeout is how long a bot can go without a pong from slack before it's considered not ready;
+	// slack is pinged every 30 seconds, so this allows a couple of missed pongs.
+	DefaultPongTimeout = 90 * time.Second
+)
+
+// Readiness is a point in time report of whether a bot can handle messages, with the reasons it can't.
+type Readiness struct {
+	ID                string     `json:"id"`
+	OrganizationName  string     `json:"organization_name"`
+	Ready             bool       `json:"ready"`
+	Connected         bool       `json:"connected"`
+	JobManagerRunning bool       `json:"job_manager_running"`
+	ConnectedAt       *time.Time `json:"connected_at,omitempty"`
+	LastPing          *time.Time `json:"last_ping,omitempty"`
+	LastPong          *time.Time `json:"last_pong,omitempty"`
+	Problems          []string   `json:"problems,omitempty"`
+}
+
+// NewHealth returns a new health tracker.
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// Health tracks when a bot connected, the websocket pings it sent and pongs it got back, and whether its job
+// manager is running, so a dead connection can be told apart from a quiet one.
+type Health struct {
+	lock              sync.Mutex
+	connectedAt       time.Time
+	lastPing          time.Time
+	lastPong          time.Time
+	jobManagerRunning bool
+}
+
+// Connected records that the bot (re)connected at a given time.
+func (h *Health) Connected(at time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.connectedAt = at
+}
+
+// Ping records that a ping was sent at a given time.
+func (h *Health) Ping(at time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.lastPing = at
+}
+
+// Pong records that a pong was received at a given time.
+func (h *Health) Pong(at time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.lastPong = at
+}
+
+// SetJobManagerRunning records whether the job manager is running.
+func (h *Health) SetJobManagerRunning(running bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.jobManagerRunning = running
+}
+
+// Readiness returns the readiness as of `now`, given whether the websocket is connected. The bot is ready if it's
+// connected, its job manager is running, and it's had a pong within `pongTimeout` (or connected within it, if it
+// hasn't been pinged yet).
+func (h *Health) Readiness(connected bool, now time.Time, pongTimeout time.Duration) Readiness {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	readiness := Readiness{
+		Connected:         connected,
+		JobManagerRunning: h.jobManagerRunning,
+		ConnectedAt:       optionalTime(h.connectedAt),
+		LastPing:          optionalTime(h.lastPing),
+		LastPong:          optionalTime(h.lastPong),
+	}
+	if !connected {
+		readiness.Problems = append(readiness.Problems, "not connected to slack")
+	}
+	if !h.jobManagerRunning {
+		readiness.Problems = append(readiness.Problems, "job manager isn't running")
+	}
+
+	lastHeard := h.lastPong
+	if lastHeard.Before(h.connectedAt) {
+		lastHeard = h.connectedAt
+	}
+	if connected && lastHeard.IsZero() {
+		readiness.Problems = append(readiness.Problems, "no pong from slack yet")
+	} else if connected && now.Sub(lastHeard) > pongTimeout {
+		readiness.Problems = append(readiness.Problems, fmt.Sprintf("no pong from slack in %v", now.Sub(lastHeard).Truncate(time.Second)))
+	}
+	readiness.Ready = len(readiness.Problems) == 0
+	return readiness
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestHealthReadiness(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2017, 01, 02, 12, 00, 00, 00, time.UTC)
+	h := NewHealth()
+
+	readiness := h.Readiness(false, now, time.Minute)
+	assert.False(readiness.Ready)
+	assert.Len(readiness.Problems, 2)
+	assert.Nil(readiness.LastPong)
+
+	h.SetJobManagerRunning(true)
+	h.Connected(now.Add(-30 * time.Second))
+	readiness = h.Readiness(true, now, time.Minute)
+	assert.True(readiness.Ready, "a fresh connection is ready before its first pong")
+	assert.Empty(readiness.Problems)
+
+	readiness = h.Readiness(true, now.Add(time.Minute), time.Minute)
+	assert.False(readiness.Ready)
+	assert.Equal([]string{"no pong from slack in 1m30s"}, readiness.Problems)
+
+	h.Ping(now.Add(50 * time.Second))
+	h.Pong(now.Add(51 * time.Second))
+	readiness = h.Readiness(true, now.Add(time.Minute), time.Minute)
+	assert.True(readiness.Ready)
+	assert.NotNil(readiness.LastPing)
+	assert.Equal(now.Add(51*time.Second), *readiness.LastPong)
+
+	h.SetJobManagerRunning(false)
+	readiness = h.Readiness(true, now.Add(time.Minute), time.Minute)
+	assert.False(readiness.Ready)
+	assert.Equal([]string{"job manager isn't running"}, readiness.Problems)
+}
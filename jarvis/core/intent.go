@@ -0,0 +1,328 @@
+package core
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultIntentThreshold is the score an intent needs for its action to run.
+	DefaultIntentThreshold = 0.7
+
+	// DefaultIntentSuggestThreshold is the score an intent needs to be suggested with "did you mean".
+	DefaultIntentSuggestThreshold = 0.25
+)
+
+// SlotType is the kind of value a slot in an action's examples takes.
+type SlotType string
+
+const (
+	// SlotWord is any single word.
+	SlotWord SlotType = "word"
+
+	// SlotTicker is a stock ticker, e.g. `GOOG` or `$goog`; lower case words aren't taken as tickers unless they
+	// start with `$`, so everyday words in a message aren't looked up.
+	SlotTicker SlotType = "ticker"
+
+	// SlotUser is a user mention, e.g. `<@U024BE7LH>`.
+	SlotUser SlotType = "user"
+
+	// SlotChannel is a channel link, e.g. `<#C024BE7LR|general>`.
+	SlotChannel SlotType = "channel"
+
+	// SlotNumber is a whole number.
+	SlotNumber SlotType = "number"
+
+	// SlotDuration is a duration, e.g. `2h` or `30m`.
+	SlotDuration SlotType = "duration"
+
+	// SlotText is the rest of the message after the word before the slot, e.g. `{text}` in `remind me to {text}`.
+	SlotText SlotType = "text"
+)
+
+var slotTypeExprs = map[SlotType]*regexp.Regexp{
+	SlotWord:     regexp.MustCompile(`^\S+$`),
+	SlotTicker:   regexp.MustCompile(`^\$[A-Za-z]{1,5}$|^[A-Z]{1,5}$`),
+	SlotUser:     regexp.MustCompile(`^<@[A-Z0-9]+(\|[^>]*)?>$`),
+	SlotChannel:  regexp.MustCompile(`^<#[A-Z0-9]+(\|[^>]*)?>$`),
+	SlotNumber:   regexp.MustCompile(`^[0-9]+$`),
+	SlotDuration: regexp.MustCompile(`^[0-9]+(s|m|h|d|w)$`),
+}
+
+var (
+	slotExpr          = regexp.MustCompile(`^\{([a-z_]+)\}$`)
+	intentTokenExpr   = regexp.MustCompile(`[a-z0-9]+`)
+	intentEscapesExpr = regexp.MustCompile(`<[^>]*>`)
+	intentTrimChars   = "?!.,;\"'`()"
+
+	intentStopWords = map[string]bool{
+		"a": true, "an": true, "and": true, "are": true, "at": true, "can": true, "could": true, "do": true, "for": true,
+		"i": true, "in": true, "is": true, "it": true, "me": true, "my": true, "of": true, "on": true, "please": true,
+		"s": true, "the": true, "to": true, "what": true, "whats": true, "you": true,
+	}
+)
+
+// IntentMatch is how well a message matches an action's examples.
+type IntentMatch struct {
+	Action Action
+
+	// Score is the similarity of the message to the action's closest example, from 0 to 1.
+	Score float64
+
+	// Slots are the values found for the closest example's slots, by name.
+	Slots map[string]string
+
+	// Complete is if every slot in the closest example has a value, so `Command` can be run.
+	Complete bool
+
+	// Command is the action's command with the slots filled in.
+	Command string
+}
+
+// Suggestion returns the command to suggest with "did you mean"; the filled in command if it's complete, otherwise
+// the command up to its first slot.
+func (im IntentMatch) Suggestion() string {
+	if im.Complete {
+		return im.Command
+	}
+	if index := strings.Index(im.Action.Command, "{"); index >= 0 {
+		return strings.TrimSpace(im.Action.Command[:index])
+	}
+	return im.Action.Command
+}
+
+// NewIntentClassifier returns a classifier for the actions that have examples and a command.
+func NewIntentClassifier(actions []Action) *IntentClassifier {
+	ic := &IntentClassifier{idf: map[string]float64{}}
+
+	documentFrequency := map[string]int{}
+	for _, action := range actions {
+		if len(action.Examples) == 0 || len(action.Command) == 0 {
+			continue
+		}
+		document := intentDocument{action: action, vocabulary: map[string]bool{}}
+		for _, example := range action.Examples {
+			words := strings.Fields(example)
+			document.examples = append(document.examples, words)
+			for _, token := range intentTokens(strings.Join(words, " ")) {
+				document.vocabulary[token] = true
+			}
+		}
+		for token := range document.vocabulary {
+			documentFrequency[token]++
+		}
+		ic.documents = append(ic.documents, document)
+	}
+
+	total := float64(len(ic.documents))
+	for token, frequency := range documentFrequency {
+		ic.idf[token] = math.Log(1 + total/float64(frequency))
+	}
+	ic.unknownIDF = math.Log(1 + total)
+	return ic
+}
+
+// IntentClassifier ranks actions by how similar a message is to their example utterances, with tf-idf weighted
+// cosine similarity over the words in the message that aren't slot values.
+type IntentClassifier struct {
+	documents  []intentDocument
+	idf        map[string]float64
+	unknownIDF float64
+}
+
+type intentDocument struct {
+	action     Action
+	examples   [][]string
+	vocabulary map[string]bool
+}
+
+// Len returns the number of actions the classifier knows.
+func (ic *IntentClassifier) Len() int {
+	return len(ic.documents)
+}
+
+// Classify returns how well a message matches each action, best first, leaving out actions it doesn't match at all.
+func (ic *IntentClassifier) Classify(messageText string) []IntentMatch {
+	words := strings.Fields(messageText)
+	matches := []IntentMatch{}
+	for _, document := range ic.documents {
+		var best *IntentMatch
+		for _, example := range document.examples {
+			slots, used := extractSlots(document, example, words)
+			score := ic.similarity(example, words, used)
+			if best == nil || score > best.Score {
+				best = &IntentMatch{Action: document.action, Score: score, Slots: slots}
+			}
+		}
+		if best == nil || best.Score == 0 {
+			continue
+		}
+		best.Command, best.Complete = fillCommand(document.action.Command, best.Slots)
+		matches = append(matches, *best)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// similarity returns the cosine similarity of an example and the message words that aren't slot values.
+func (ic *IntentClassifier) similarity(example, words []string, used map[int]bool) float64 {
+	exampleWeights := ic.weights(intentTokens(strings.Join(example, " ")))
+	remaining := []string{}
+	for index, word := range words {
+		if !used[index] {
+			remaining = append(remaining, word)
+		}
+	}
+	messageWeights := ic.weights(intentTokens(strings.Join(remaining, " ")))
+
+	var dot, exampleNorm, messageNorm float64
+	for token, weight := range exampleWeights {
+		exampleNorm += weight * weight
+		dot += weight * messageWeights[token]
+	}
+	for _, weight := range messageWeights {
+		messageNorm += weight * weight
+	}
+	if dot == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(exampleNorm) * math.Sqrt(messageNorm))
+}
+
+func (ic *IntentClassifier) weights(tokens []string) map[string]float64 {
+	weights := map[string]float64{}
+	for _, token := range tokens {
+		idf, known := ic.idf[token]
+		if !known {
+			idf = ic.unknownIDF
+		}
+		weights[token] += idf
+	}
+	return weights
+}
+
+// intentTokens returns the lower cased words of some text without stop words, slots, mentions or links.
+func intentTokens(text string) []string {
+	text = intentEscapesExpr.ReplaceAllString(text, " ")
+	tokens := []string{}
+	for _, word := range strings.Fields(text) {
+		if len(slotName(word)) != 0 {
+			continue
+		}
+		for _, token := range intentTokenExpr.FindAllString(strings.ToLower(strings.Replace(word, "'", "", -1)), -1) {
+			if !intentStopWords[token] {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// extractSlots finds values for an example's slots in the message words, returning them and the indexes of the
+// words they used. Slots take the first unused word of their type that isn't one of the action's own words.
+func extractSlots(document intentDocument, example, words []string) (map[string]string, map[int]bool) {
+	slots := map[string]string{}
+	used := map[int]bool{}
+	next := 0
+	for exampleIndex, exampleWord := range example {
+		name := slotName(exampleWord)
+		if len(name) == 0 {
+			continue
+		}
+		slotType, hasType := document.action.Slots[name]
+		if !hasType {
+			slotType = SlotWord
+		}
+
+		if slotType == SlotText {
+			start := next
+			if exampleIndex > 0 {
+				start = findWord(words, example[exampleIndex-1], next)
+			}
+			if start < 0 || start >= len(words) {
+				continue
+			}
+			slots[name] = strings.Join(words[start:], " ")
+			for index := start; index < len(words); index++ {
+				used[index] = true
+			}
+			next = len(words)
+			continue
+		}
+
+		for index := next; index < len(words); index++ {
+			value := strings.Trim(words[index], intentTrimChars)
+			if used[index] || isIntentWord(document, value) || !isSlotValue(slotType, value) {
+				continue
+			}
+			slots[name] = strings.TrimPrefix(value, "$")
+			used[index] = true
+			next = index + 1
+			break
+		}
+	}
+	return slots, used
+}
+
+// isSlotValue returns if a word can be the value of a slot of a type; tickers also can't be stop words, so `I` or
+// `A` in a message aren't looked up.
+func isSlotValue(slotType SlotType, value string) bool {
+	if !slotTypeExprs[slotType].MatchString(value) {
+		return false
+	}
+	if slotType == SlotTicker {
+		return !intentStopWords[strings.ToLower(strings.TrimPrefix(value, "$"))]
+	}
+	return true
+}
+
+// findWord returns the index after the first occurrence of a word at or after `start`, or -1.
+func findWord(words []string, word string, start int) int {
+	for index := start; index < len(words); index++ {
+		if strings.EqualFold(strings.Trim(words[index], intentTrimChars), word) {
+			return index + 1
+		}
+	}
+	return -1
+}
+
+func isIntentWord(document intentDocument, word string) bool {
+	tokens := intentTokens(word)
+	if len(tokens) == 0 {
+		return !strings.HasPrefix(word, "<")
+	}
+	for _, token := range tokens {
+		if !document.vocabulary[token] {
+			return false
+		}
+	}
+	return true
+}
+
+func slotName(word string) string {
+	pieces := slotExpr.FindStringSubmatch(strings.Trim(word, intentTrimChars))
+	if len(pieces) < 2 {
+		return ""
+	}
+	return pieces[1]
+}
+
+// fillCommand fills a command's slots in, returning if every slot had a value.
+func fillCommand(command string, slots map[string]string) (string, bool) {
+	complete := true
+	words := strings.Fields(command)
+	for index, word := range words {
+		name := slotName(word)
+		if len(name) == 0 {
+			continue
+		}
+		if value, hasValue := slots[name]; hasValue {
+			words[index] = value
+		} else {
+			complete = false
+		}
+	}
+	return strings.Join(words, " "), complete
+}
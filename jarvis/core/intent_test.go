@@ -0,0 +1,95 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func testIntentActions() []Action {
+	return []Action{
+		{ID: "stock.price", MessagePattern: "^stock:price", Command: "stock:price {ticker}", Slots: map[string]SlotType{"ticker": SlotTicker}, Examples: []string{
+			"what's {ticker} trading at",
+			"what is the stock price of {ticker}",
+			"how much is {ticker} stock",
+		}},
+		{ID: "stock.chart", MessagePattern: "^stock:chart", Command: "stock:chart {ticker}", Slots: map[string]SlotType{"ticker": SlotTicker}, Examples: []string{
+			"show me a chart of {ticker}",
+			"graph {ticker} stock",
+			"how has {ticker} been doing",
+		}},
+		{ID: "remind", MessagePattern: "^remind ", Command: "remind me in {duration} to {text}", Slots: map[string]SlotType{"duration": SlotDuration, "text": SlotText}, Examples: []string{
+			"set a reminder in {duration} to {text}",
+			"ping me in {duration} to {text}",
+		}},
+		{ID: "time", MessagePattern: "^time", Command: "time", Examples: []string{"what time is it", "current time"}},
+		{ID: "no.examples", MessagePattern: "^nothing"},
+	}
+}
+
+func TestIntentClassifier(t *testing.T) {
+	assert := assert.New(t)
+	ic := NewIntentClassifier(testIntentActions())
+	assert.Equal(4, ic.Len(), "actions without examples are left out")
+
+	matches := ic.Classify("what's $GOOG trading at?")
+	assert.NotEmpty(matches)
+	assert.Equal("stock.price", matches[0].Action.ID)
+	assert.True(matches[0].Score >= DefaultIntentThreshold, matches[0].Score)
+	assert.True(matches[0].Complete)
+	assert.Equal("GOOG", matches[0].Slots["ticker"])
+	assert.Equal("stock:price GOOG", matches[0].Command)
+
+	matches = ic.Classify("can you show me a chart of AAPL")
+	assert.Equal("stock.chart", matches[0].Action.ID)
+	assert.Equal("stock:chart AAPL", matches[0].Command)
+
+	matches = ic.Classify("set a reminder in 2h to deploy the api")
+	assert.Equal("remind", matches[0].Action.ID)
+	assert.Equal("remind me in 2h to deploy the api", matches[0].Command)
+
+	matches = ic.Classify("time please")
+	assert.Equal("time", matches[0].Action.ID)
+	assert.True(matches[0].Complete)
+
+	assert.Empty(ic.Classify("tell me a joke"))
+}
+
+func TestIntentClassifierIgnoresChatter(t *testing.T) {
+	assert := assert.New(t)
+	ic := NewIntentClassifier(testIntentActions())
+
+	for _, text := range []string{"how is bob doing", "how much is lunch", "show me a chart of sales", "how much is it? I wonder", "what's goog trading at?"} {
+		matches := ic.Classify(text)
+		assert.NotEmpty(matches, text)
+		assert.True(matches[0].Action.ID == "stock.price" || matches[0].Action.ID == "stock.chart", text)
+		assert.False(matches[0].Complete, text)
+		assert.Empty(matches[0].Slots["ticker"], text)
+	}
+
+	matches := ic.Classify("how much is $goog stock")
+	assert.True(matches[0].Complete)
+	assert.Equal("stock:price goog", matches[0].Command, "lower case tickers work with a `$`")
+
+	assert.True(isSlotValue(SlotTicker, "GOOG"))
+	assert.True(isSlotValue(SlotTicker, "$brk"))
+	assert.False(isSlotValue(SlotTicker, "goog"))
+	assert.False(isSlotValue(SlotTicker, "GOOGLE"))
+	assert.False(isSlotValue(SlotTicker, "I"), "stop words aren't tickers")
+	assert.True(isSlotValue(SlotWord, "I"))
+}
+
+func TestIntentClassifierSuggestions(t *testing.T) {
+	assert := assert.New(t)
+	ic := NewIntentClassifier(testIntentActions())
+
+	matches := ic.Classify("what's the price")
+	assert.Equal("stock.price", matches[0].Action.ID)
+	assert.False(matches[0].Complete)
+	assert.Equal("stock:price", matches[0].Suggestion())
+
+	matches = ic.Classify("how much did we spend on the goog ads last quarter")
+	assert.Equal("stock.price", matches[0].Action.ID)
+	assert.True(matches[0].Score < DefaultIntentThreshold, matches[0].Score)
+	assert.True(matches[0].Score >= DefaultIntentSuggestThreshold, matches[0].Score)
+}
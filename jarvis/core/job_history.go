@@ -0,0 +1,99 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultJobHistoryCapacity is the default number of runs kept per job.
+	DefaultJobHistoryCapacity = 25
+)
+
+// JobRun is the record of a single run of a job.
+type JobRun struct {
+	Job     string        `json:"job"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Failed returns if the run returned an error.
+func (jr JobRun) Failed() bool {
+	return len(jr.Error) != 0
+}
+
+// NewJobHistory returns a new job history that keeps the last `capacity` runs per job.
+func NewJobHistory(capacity int) *JobHistory {
+	if capacity < 1 {
+		capacity = DefaultJobHistoryCapacity
+	}
+	return &JobHistory{
+		capacity: capacity,
+		runs:     map[string][]JobRun{},
+	}
+}
+
+// JobHistory is a bounded, per job record of recent job runs.
+type JobHistory struct {
+	lock     sync.Mutex
+	capacity int
+	runs     map[string][]JobRun
+}
+
+// Capacity returns the number of runs kept per job.
+func (jh *JobHistory) Capacity() int {
+	return jh.capacity
+}
+
+// Add records a run, dropping the oldest run for the job if we're at capacity.
+func (jh *JobHistory) Add(run JobRun) {
+	jh.lock.Lock()
+	defer jh.lock.Unlock()
+
+	runs := append(jh.runs[run.Job], run)
+	if len(runs) > jh.capacity {
+		runs = runs[len(runs)-jh.capacity:]
+	}
+	jh.runs[run.Job] = runs
+}
+
+// Runs returns the recorded runs for a job, most recent first.
+func (jh *JobHistory) Runs(jobName string) []JobRun {
+	jh.lock.Lock()
+	defer jh.lock.Unlock()
+
+	runs := jh.runs[jobName]
+	results := make([]JobRun, len(runs))
+	for index, run := range runs {
+		results[len(runs)-1-index] = run
+	}
+	return results
+}
+
+// Last returns the most recent run for a job, or nil if it hasn't run.
+func (jh *JobHistory) Last(jobName string) *JobRun {
+	jh.lock.Lock()
+	defer jh.lock.Unlock()
+
+	runs := jh.runs[jobName]
+	if len(runs) == 0 {
+		return nil
+	}
+	last := runs[len(runs)-1]
+	return &last
+}
+
+// Jobs returns the names of the jobs with recorded runs.
+func (jh *JobHistory) Jobs() []string {
+	jh.lock.Lock()
+	defer jh.lock.Unlock()
+
+	var names []string
+	for name := range jh.runs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
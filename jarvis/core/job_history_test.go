@@ -0,0 +1,39 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestJobHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	jh := NewJobHistory(3)
+	assert.Nil(jh.Last("test"))
+	assert.Empty(jh.Runs("test"))
+
+	started := time.Date(2016, 01, 01, 12, 00, 00, 00, time.UTC)
+	for x := 0; x < 5; x++ {
+		run := JobRun{Job: "test", Started: started.Add(time.Duration(x) * time.Minute), Elapsed: time.Second}
+		if x == 4 {
+			run.Error = "failed"
+		}
+		jh.Add(run)
+	}
+	jh.Add(JobRun{Job: "other", Started: started})
+
+	runs := jh.Runs("test")
+	assert.Len(runs, 3)
+	assert.Equal(started.Add(4*time.Minute), runs[0].Started)
+	assert.Equal(started.Add(2*time.Minute), runs[2].Started)
+	assert.True(runs[0].Failed())
+	assert.False(runs[1].Failed())
+
+	last := jh.Last("test")
+	assert.NotNil(last)
+	assert.True(last.Failed())
+
+	assert.Equal([]string{"other", "test"}, jh.Jobs())
+}
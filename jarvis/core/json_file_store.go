@@ -0,0 +1,78 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/blendlabs/go-exception"
+)
+
+// NewJSONFileStore returns a new store that persists values as json to the given path.
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{path: path}
+}
+
+// JSONFileStore persists a value to disk as json.
+// Writes go to a temp file that is renamed over the original so a crash mid-write doesn't lose data.
+type JSONFileStore struct {
+	lock sync.Mutex
+	path string
+}
+
+// Path returns the file path.
+func (jfs *JSONFileStore) Path() string {
+	return jfs.path
+}
+
+// Load reads the file into a value; if the file doesn't exist the value is left as is.
+func (jfs *JSONFileStore) Load(value interface{}) error {
+	jfs.lock.Lock()
+	defer jfs.lock.Unlock()
+
+	contents, err := ioutil.ReadFile(jfs.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	if len(contents) == 0 {
+		return nil
+	}
+	return exception.Wrap(json.Unmarshal(contents, value))
+}
+
+// Save writes a value to the file.
+func (jfs *JSONFileStore) Save(value interface{}) error {
+	jfs.lock.Lock()
+	defer jfs.lock.Unlock()
+
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return exception.Wrap(err)
+	}
+
+	dir := filepath.Dir(jfs.path)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return exception.Wrap(err)
+	}
+
+	tempFile, err := ioutil.TempFile(dir, filepath.Base(jfs.path)+".tmp")
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(contents)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	return exception.Wrap(os.Rename(tempFile.Name(), jfs.path))
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestJSONFileStore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	store := NewJSONFileStore(filepath.Join(dir, "data", "test.json"))
+
+	values := map[string]string{"foo": "bar"}
+	assert.Nil(store.Load(&values))
+	assert.Equal("bar", values["foo"])
+
+	assert.Nil(store.Save(map[string]string{"bar": "baz"}))
+
+	loaded := map[string]string{}
+	assert.Nil(store.Load(&loaded))
+	assert.Len(loaded, 1)
+	assert.Equal("baz", loaded["bar"])
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "data"))
+	assert.Nil(err)
+	assert.Len(files, 1)
+}
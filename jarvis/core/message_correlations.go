@@ -0,0 +1,155 @@
+package core
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// DefaultMessageCorrelationCapacity is the default number of messages replies are tracked for.
+	DefaultMessageCorrelationCapacity = 256
+)
+
+// NewMessageCorrelations returns a new message correlation cache that tracks the replies to
+// up to `capacity` messages, forgetting the oldest first.
+func NewMessageCorrelations(capacity int) *MessageCorrelations {
+	return &MessageCorrelations{
+		capacity: capacity,
+		replies:  map[string][]slack.Timestamp{},
+		users:    map[string]string{},
+		editing:  map[string][]slack.Timestamp{},
+	}
+}
+
+// MessageCorrelations tracks which replies the bot sent to which messages, so that when a message
+// is edited or deleted its replies can be updated or deleted along with it.
+type MessageCorrelations struct {
+	lock     sync.Mutex
+	capacity int
+	order    []string
+	replies  map[string][]slack.Timestamp
+	users    map[string]string
+	editing  map[string][]slack.Timestamp
+}
+
+func correlationKey(channelID, messageTimestamp string) string {
+	return channelID + "/" + messageTimestamp
+}
+
+// Add records a reply to a message from a given user.
+func (mc *MessageCorrelations) Add(channelID, messageTimestamp, userID string, reply slack.Timestamp) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	key := correlationKey(channelID, messageTimestamp)
+	if _, hasKey := mc.replies[key]; !hasKey {
+		mc.order = append(mc.order, key)
+		if mc.capacity > 0 && len(mc.order) > mc.capacity {
+			delete(mc.replies, mc.order[0])
+			delete(mc.users, mc.order[0])
+			mc.order = mc.order[1:]
+		}
+	}
+	mc.replies[key] = append(mc.replies[key], reply)
+	mc.users[key] = userID
+}
+
+// RepliedTo returns the user whose message a reply was sent to, and if the reply is one the bot is tracking.
+func (mc *MessageCorrelations) RepliedTo(channelID string, reply slack.Timestamp) (string, bool) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	prefix := correlationKey(channelID, "")
+	for key, replies := range mc.replies {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for _, existing := range replies {
+			if existing.String() == reply.String() {
+				return mc.users[key], true
+			}
+		}
+	}
+	return "", false
+}
+
+// Replies returns the replies to a message, oldest first.
+func (mc *MessageCorrelations) Replies(channelID, messageTimestamp string) []slack.Timestamp {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	return append([]slack.Timestamp{}, mc.replies[correlationKey(channelID, messageTimestamp)]...)
+}
+
+// Remove forgets a message, returning its replies.
+func (mc *MessageCorrelations) Remove(channelID, messageTimestamp string) []slack.Timestamp {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	return mc.remove(correlationKey(channelID, messageTimestamp))
+}
+
+// BeginEdit starts re-handling an edited message; until `EndEdit` is called, `NextEdit` returns its
+// previous replies in order so they can be updated instead of replied to again.
+// It returns false if the bot never replied to the message.
+func (mc *MessageCorrelations) BeginEdit(channelID, messageTimestamp string) bool {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	key := correlationKey(channelID, messageTimestamp)
+	replies := mc.remove(key)
+	if len(replies) == 0 {
+		return false
+	}
+	mc.editing[key] = replies
+	return true
+}
+
+// NextEdit returns the next previous reply to update for an edited message, or nil if there isn't one.
+func (mc *MessageCorrelations) NextEdit(channelID, messageTimestamp string) *slack.Timestamp {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	key := correlationKey(channelID, messageTimestamp)
+	replies := mc.editing[key]
+	if len(replies) == 0 {
+		return nil
+	}
+	next := replies[0]
+	mc.editing[key] = replies[1:]
+	return &next
+}
+
+// EndEdit finishes re-handling an edited message, returning the previous replies that weren't updated.
+func (mc *MessageCorrelations) EndEdit(channelID, messageTimestamp string) []slack.Timestamp {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	key := correlationKey(channelID, messageTimestamp)
+	unused := mc.editing[key]
+	delete(mc.editing, key)
+	return unused
+}
+
+// Len returns the number of messages replies are tracked for.
+func (mc *MessageCorrelations) Len() int {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	return len(mc.replies)
+}
+
+func (mc *MessageCorrelations) remove(key string) []slack.Timestamp {
+	replies, hasKey := mc.replies[key]
+	if !hasKey {
+		return nil
+	}
+	delete(mc.replies, key)
+	delete(mc.users, key)
+	for index, existing := range mc.order {
+		if existing == key {
+			mc.order = append(mc.order[:index], mc.order[index+1:]...)
+			break
+		}
+	}
+	return replies
+}
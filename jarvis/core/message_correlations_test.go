@@ -0,0 +1,64 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func testTimestamp(value string) slack.Timestamp {
+	var ts slack.Timestamp
+	json.Unmarshal([]byte(`"`+value+`"`), &ts)
+	return ts
+}
+
+func TestMessageCorrelations(t *testing.T) {
+	assert := assert.New(t)
+
+	mc := NewMessageCorrelations(2)
+	mc.Add("C01", "1476900000.000001", "U01", testTimestamp("1476900001.000001"))
+	mc.Add("C01", "1476900000.000001", "U01", testTimestamp("1476900001.000002"))
+	mc.Add("C01", "1476900000.000002", "U01", testTimestamp("1476900002.000001"))
+
+	replies := mc.Replies("C01", "1476900000.000001")
+	assert.Len(replies, 2)
+	assert.Equal("1476900001.000001", replies[0].String())
+	assert.Empty(mc.Replies("C02", "1476900000.000001"))
+
+	mc.Add("C01", "1476900000.000003", "U01", testTimestamp("1476900003.000001"))
+	assert.Equal(2, mc.Len())
+	assert.Empty(mc.Replies("C01", "1476900000.000001"), "the oldest message should be forgotten")
+
+	requester, found := mc.RepliedTo("C01", testTimestamp("1476900003.000001"))
+	assert.True(found)
+	assert.Equal("U01", requester)
+	_, found = mc.RepliedTo("C02", testTimestamp("1476900003.000001"))
+	assert.False(found)
+
+	removed := mc.Remove("C01", "1476900000.000002")
+	assert.Len(removed, 1)
+	assert.Equal(1, mc.Len())
+	assert.Nil(mc.Remove("C01", "1476900000.000002"))
+}
+
+func TestMessageCorrelationsEdit(t *testing.T) {
+	assert := assert.New(t)
+
+	mc := NewMessageCorrelations(DefaultMessageCorrelationCapacity)
+	assert.False(mc.BeginEdit("C01", "1476900000.000001"))
+
+	mc.Add("C01", "1476900000.000001", "U01", testTimestamp("1476900001.000001"))
+	mc.Add("C01", "1476900000.000001", "U01", testTimestamp("1476900001.000002"))
+	assert.True(mc.BeginEdit("C01", "1476900000.000001"))
+
+	next := mc.NextEdit("C01", "1476900000.000001")
+	assert.NotNil(next)
+	assert.Equal("1476900001.000001", next.String())
+
+	unused := mc.EndEdit("C01", "1476900000.000001")
+	assert.Len(unused, 1)
+	assert.Equal("1476900001.000002", unused[0].String())
+	assert.Nil(mc.NextEdit("C01", "1476900000.000001"))
+}
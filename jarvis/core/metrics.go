@@ -0,0 +1,273 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// MetricTypeCounter is the prometheus counter type.
+	MetricTypeCounter = "counter"
+
+	// MetricTypeGauge is the prometheus gauge type.
+	MetricTypeGauge = "gauge"
+
+	// MetricTypeHistogram is the prometheus histogram type.
+	MetricTypeHistogram = "histogram"
+)
+
+var (
+	// DefaultLatencyBuckets are the histogram buckets (in seconds) used for latencies.
+	DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// MetricLabel is a label name and value.
+type MetricLabel struct {
+	Name  string
+	Value string
+}
+
+// MetricSample is one value of a metric; `Suffix` is appended to the family name (e.g. `_bucket` for histograms).
+type MetricSample struct {
+	Suffix string
+	Labels []MetricLabel
+	Value  float64
+}
+
+// MetricFamily is a named metric and its samples.
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []MetricSample
+}
+
+// MetricsCollector is anything that exports metrics.
+type MetricsCollector interface {
+	CollectMetrics() []MetricFamily
+}
+
+// WithMetricLabel returns metric families with a label added to every sample, e.g. to tell bots apart.
+func WithMetricLabel(families []MetricFamily, name, value string) []MetricFamily {
+	labeled := make([]MetricFamily, len(families))
+	for familyIndex, family := range families {
+		labeled[familyIndex] = family
+		labeled[familyIndex].Samples = make([]MetricSample, len(family.Samples))
+		for sampleIndex, sample := range family.Samples {
+			sample.Labels = append([]MetricLabel{{Name: name, Value: value}}, sample.Labels...)
+			labeled[familyIndex].Samples[sampleIndex] = sample
+		}
+	}
+	return labeled
+}
+
+// WriteMetrics writes metrics in the prometheus text format, merging families with the same name from different collectors.
+func WriteMetrics(w io.Writer, collectors ...MetricsCollector) error {
+	families := map[string]*MetricFamily{}
+	names := []string{}
+	for _, collector := range collectors {
+		for _, family := range collector.CollectMetrics() {
+			if existing, hasFamily := families[family.Name]; hasFamily {
+				existing.Samples = append(existing.Samples, family.Samples...)
+				continue
+			}
+			copied := family
+			families[family.Name] = &copied
+			names = append(names, family.Name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := families[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", family.Name, family.Help, family.Name, family.Type); err != nil {
+			return err
+		}
+		for _, sample := range family.Samples {
+			if _, err := fmt.Fprintf(w, "%s%s%s %s\n", family.Name, sample.Suffix, formatMetricLabels(sample.Labels), formatMetricValue(sample.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatMetricLabels(labels []MetricLabel) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pieces := []string{}
+	for _, label := range labels {
+		value := strings.Replace(label.Value, `\`, `\\`, -1)
+		value = strings.Replace(value, `"`, `\"`, -1)
+		value = strings.Replace(value, "\n", `\n`, -1)
+		pieces = append(pieces, fmt.Sprintf(`%s="%s"`, label.Name, value))
+	}
+	return "{" + strings.Join(pieces, ",") + "}"
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func metricKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func metricLabels(labelNames, labelValues []string) []MetricLabel {
+	labels := []MetricLabel{}
+	for index, name := range labelNames {
+		value := ""
+		if index < len(labelValues) {
+			value = labelValues[index]
+		}
+		labels = append(labels, MetricLabel{Name: name, Value: value})
+	}
+	return labels
+}
+
+// NewCounterVec returns a new counter with the given label names.
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     map[string]float64{},
+		labels:     map[string][]string{},
+	}
+}
+
+// CounterVec is a counter per set of label values.
+type CounterVec struct {
+	lock       sync.Mutex
+	name       string
+	help       string
+	labelNames []string
+	values     map[string]float64
+	labels     map[string][]string
+	order      []string
+}
+
+// Inc adds one to the counter for the label values.
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+// Add adds to the counter for the label values.
+func (cv *CounterVec) Add(delta float64, labelValues ...string) {
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	key := metricKey(labelValues)
+	if _, hasValue := cv.values[key]; !hasValue {
+		cv.labels[key] = labelValues
+		cv.order = append(cv.order, key)
+	}
+	cv.values[key] = cv.values[key] + delta
+}
+
+// Value returns the counter for the label values.
+func (cv *CounterVec) Value(labelValues ...string) float64 {
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	return cv.values[metricKey(labelValues)]
+}
+
+// CollectMetrics implements MetricsCollector.
+func (cv *CounterVec) CollectMetrics() []MetricFamily {
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	family := MetricFamily{Name: cv.name, Help: cv.help, Type: MetricTypeCounter}
+	for _, key := range cv.order {
+		family.Samples = append(family.Samples, MetricSample{Labels: metricLabels(cv.labelNames, cv.labels[key]), Value: cv.values[key]})
+	}
+	return []MetricFamily{family}
+}
+
+// NewHistogramVec returns a new histogram with the given buckets (upper bounds, ascending) and label names.
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		buckets:    buckets,
+		labelNames: labelNames,
+		values:     map[string]*histogramValue{},
+	}
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// HistogramVec is a histogram per set of label values.
+type HistogramVec struct {
+	lock       sync.Mutex
+	name       string
+	help       string
+	buckets    []float64
+	labelNames []string
+	values     map[string]*histogramValue
+	order      []string
+}
+
+// Observe records a value for the label values.
+func (hv *HistogramVec) Observe(value float64, labelValues ...string) {
+	hv.lock.Lock()
+	defer hv.lock.Unlock()
+	key := metricKey(labelValues)
+	histogram, hasValue := hv.values[key]
+	if !hasValue {
+		histogram = &histogramValue{labelValues: labelValues, counts: make([]uint64, len(hv.buckets))}
+		hv.values[key] = histogram
+		hv.order = append(hv.order, key)
+	}
+	for index, upperBound := range hv.buckets {
+		if value <= upperBound {
+			histogram.counts[index]++
+		}
+	}
+	histogram.count++
+	histogram.sum = histogram.sum + value
+}
+
+// Count returns the number of observations for the label values.
+func (hv *HistogramVec) Count(labelValues ...string) uint64 {
+	hv.lock.Lock()
+	defer hv.lock.Unlock()
+	if histogram, hasValue := hv.values[metricKey(labelValues)]; hasValue {
+		return histogram.count
+	}
+	return 0
+}
+
+// CollectMetrics implements MetricsCollector.
+func (hv *HistogramVec) CollectMetrics() []MetricFamily {
+	hv.lock.Lock()
+	defer hv.lock.Unlock()
+	family := MetricFamily{Name: hv.name, Help: hv.help, Type: MetricTypeHistogram}
+	for _, key := range hv.order {
+		histogram := hv.values[key]
+		labels := metricLabels(hv.labelNames, histogram.labelValues)
+		for index, upperBound := range hv.buckets {
+			bucketLabels := append(append([]MetricLabel{}, labels...), MetricLabel{Name: "le", Value: formatMetricValue(upperBound)})
+			family.Samples = append(family.Samples, MetricSample{Suffix: "_bucket", Labels: bucketLabels, Value: float64(histogram.counts[index])})
+		}
+		infLabels := append(append([]MetricLabel{}, labels...), MetricLabel{Name: "le", Value: "+Inf"})
+		family.Samples = append(family.Samples,
+			MetricSample{Suffix: "_bucket", Labels: infLabels, Value: float64(histogram.count)},
+			MetricSample{Suffix: "_sum", Labels: labels, Value: histogram.sum},
+			MetricSample{Suffix: "_count", Labels: labels, Value: float64(histogram.count)},
+		)
+	}
+	return []MetricFamily{family}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestCounterVec(t *testing.T) {
+	assert := assert.New(t)
+
+	cv := NewCounterVec("test_total", "A test counter.", "action")
+	cv.Inc("help")
+	cv.Inc("help")
+	cv.Add(2.5, "stock.price")
+	assert.Equal(float64(2), cv.Value("help"))
+	assert.Equal(2.5, cv.Value("stock.price"))
+	assert.Zero(cv.Value("unknown"))
+
+	families := cv.CollectMetrics()
+	assert.Len(families, 1)
+	assert.Equal(MetricTypeCounter, families[0].Type)
+	assert.Len(families[0].Samples, 2)
+	assert.Equal("help", families[0].Samples[0].Labels[0].Value, "samples keep the order they were first seen")
+}
+
+func TestHistogramVec(t *testing.T) {
+	assert := assert.New(t)
+
+	hv := NewHistogramVec("test_seconds", "A test histogram.", []float64{0.1, 1})
+	hv.Observe(0.05)
+	hv.Observe(0.5)
+	hv.Observe(5)
+	assert.Equal(uint64(3), hv.Count())
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(WriteMetrics(buffer, hv))
+	expected := strings.Join([]string{
+		"# HELP test_seconds A test histogram.",
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{le="0.1"} 1`,
+		`test_seconds_bucket{le="1"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		"test_seconds_sum 5.55",
+		"test_seconds_count 3",
+		"",
+	}, "\n")
+	assert.Equal(expected, buffer.String())
+}
+
+func TestWriteMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	first := NewCounterVec("test_total", "A test counter.")
+	first.Inc()
+	second := NewCounterVec("test_total", "A test counter.")
+	second.Add(3)
+	other := NewCounterVec("another_total", "Another counter.", "name")
+	other.Inc("quote\" and \\ slash\nnewline")
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(WriteMetrics(buffer,
+		collectorFunc(func() []MetricFamily { return WithMetricLabel(first.CollectMetrics(), "bot", "first") }),
+		collectorFunc(func() []MetricFamily { return WithMetricLabel(second.CollectMetrics(), "bot", "second") }),
+		other,
+	))
+	expected := strings.Join([]string{
+		"# HELP another_total Another counter.",
+		"# TYPE another_total counter",
+		`another_total{name="quote\" and \\ slash\nnewline"} 1`,
+		"# HELP test_total A test counter.",
+		"# TYPE test_total counter",
+		`test_total{bot="first"} 1`,
+		`test_total{bot="second"} 3`,
+		"",
+	}, "\n")
+	assert.Equal(expected, buffer.String())
+}
+
+type collectorFunc func() []MetricFamily
+
+func (cf collectorFunc) CollectMetrics() []MetricFamily {
+	return cf()
+}
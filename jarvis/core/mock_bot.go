@@ -3,25 +3,43 @@ package core
 // NewMockBot returns a new Bot instance.
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/blendlabs/go-chronometer"
 	"github.com/blendlabs/go-exception"
 	logger "github.com/blendlabs/go-logger"
 	"github.com/blendlabs/go-util/collections"
-	"github.com/wcharczuk/go-slack"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+	"github.com/wcharczuk/jarvis/jarvis/slacktest"
 )
 
 // NewMockBot creates a new mock bot.
 func NewMockBot(token string) *MockBot {
-	return &MockBot{
+	mb := &MockBot{
 		id:               slack.UUIDv4().ToShortString(),
 		organizationName: "Test Organization",
 		token:            token,
 		jobManager:       chronometer.NewJobManager(),
+		jobHistory:       NewJobHistory(DefaultJobHistoryCapacity),
+		sessions:         NewSessions(),
+		correlations:     NewMessageCorrelations(DefaultMessageCorrelationCapacity),
+		directory:        NewDirectory(),
+		auditLog:         NewAuditLog(DefaultAuditLogCapacity),
+		errors:           NewErrorLog(DefaultErrorLogCapacity),
 		state:            map[string]interface{}{},
 		configuration:    map[string]string{"option.passive": "false"},
 		actions:          map[string]Action{},
+		modules:          map[string]BotModule{},
+		loadedModules:    collections.SetOfString{},
 		agent:            logger.New(logger.NewEventFlagSetNone())}
+	mb.outbound = NewOutboundQueue(func(channel string, err error) {
+		mb.errors.Add(fmt.Sprintf("outbound %s", channel), err)
+	})
+	mb.outbound.channelInterval = 0
+	mb.outbound.Start()
+	return mb
 }
 
 // MockMessage returns a mock message.
@@ -34,9 +52,17 @@ type MockBot struct {
 	id               string
 	token            string
 	organizationName string
+	lock             sync.RWMutex
 	configuration    map[string]string
 	state            map[string]interface{}
 	jobManager       *chronometer.JobManager
+	jobHistory       *JobHistory
+	sessions         *Sessions
+	correlations     *MessageCorrelations
+	directory        *Directory
+	auditLog         *AuditLog
+	errors           *ErrorLog
+	outbound         *OutboundQueue
 	actions          map[string]Action
 
 	agent         *logger.Agent
@@ -44,6 +70,13 @@ type MockBot struct {
 	loadedModules collections.SetOfString
 
 	mockMessageHandler MessageHandler
+
+	outboxLock sync.Mutex
+	outbox     MockOutbox
+
+	slackLock sync.Mutex
+	slack     *slacktest.Server
+	client    *slack.Client
 }
 
 // MockMessageHandler sets a handler for any call to Say or Sayf
@@ -66,9 +99,22 @@ func (mb *MockBot) OrganizationName() string {
 	return mb.organizationName
 }
 
-// Configuration returns configuration.
+// Configuration returns a copy of the configuration.
 func (mb *MockBot) Configuration() map[string]string {
-	return mb.configuration
+	mb.lock.RLock()
+	defer mb.lock.RUnlock()
+	configuration := map[string]string{}
+	for key, value := range mb.configuration {
+		configuration[key] = value
+	}
+	return configuration
+}
+
+// SetConfig sets a config entry.
+func (mb *MockBot) SetConfig(key, value string) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	mb.configuration[key] = value
 }
 
 // State returns state.
@@ -81,9 +127,137 @@ func (mb *MockBot) JobManager() *chronometer.JobManager {
 	return mb.jobManager
 }
 
-// Client returns a bare slack client.
+// LoadJob loads a job into the job manager.
+func (mb *MockBot) LoadJob(job chronometer.Job) error {
+	return mb.jobManager.LoadJob(job)
+}
+
+// JobHistory returns the job history.
+func (mb *MockBot) JobHistory() *JobHistory {
+	return mb.jobHistory
+}
+
+// Sessions returns the active user sessions.
+func (mb *MockBot) Sessions() *Sessions {
+	return mb.sessions
+}
+
+// Correlations returns the replies the bot sent to recent messages.
+func (mb *MockBot) Correlations() *MessageCorrelations {
+	return mb.correlations
+}
+
+// Directory returns the user and channel directory; users in it are returned by `FindUser` instead of the test user.
+func (mb *MockBot) Directory() *Directory {
+	return mb.directory
+}
+
+// AuditLog returns the audit log.
+func (mb *MockBot) AuditLog() *AuditLog {
+	return mb.auditLog
+}
+
+// Errors returns the error log.
+func (mb *MockBot) Errors() *ErrorLog {
+	return mb.errors
+}
+
+// OutboundQueue returns the queue writes are sent through; unlike the bot's, it doesn't wait between sends.
+func (mb *MockBot) OutboundQueue() *OutboundQueue {
+	return mb.outbound
+}
+
+// WaitForOutbound waits (for up to 5 seconds) for everything queued on the outbound queue to be sent,
+// and returns if it was.
+func (mb *MockBot) WaitForOutbound() bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for mb.outbound.Stats().Depth > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// JobOutputChannels returns the active channels.
+func (mb *MockBot) JobOutputChannels(jobName string) []string {
+	return mb.ActiveChannels()
+}
+
+// Client returns a slack client connected to a fake slack with the mock bot's users and channels, started on the
+// first call and stopped by `Close`. Its web api calls are recorded in the outbox.
 func (mb *MockBot) Client() *slack.Client {
-	return &slack.Client{ActiveChannels: []string{"CTESTCHANNEL"}}
+	mb.slackLock.Lock()
+	defer mb.slackLock.Unlock()
+	if mb.client != nil {
+		return mb.client
+	}
+
+	mb.slack = slacktest.NewServer()
+	mb.slack.PutChannel(*mb.FindChannel("CTESTCHANNEL"))
+	for _, user := range mb.directory.Users() {
+		mb.slack.PutUser(user)
+	}
+	for _, channel := range mb.directory.Channels() {
+		mb.slack.PutChannel(channel)
+	}
+	mb.slack.OnCall(func(call slacktest.Call) {
+		mb.record(MockOutbound{Kind: MockOutboundAPI, Channel: call.Args.Get("channel"), Text: call.Args.Get("text"), Method: call.Method, Args: call.Args})
+	})
+	mb.client = mb.slack.Client(mb.token)
+	mb.client.ActiveChannels = []string{"CTESTCHANNEL"}
+	return mb.client
+}
+
+// Close stops the fake slack behind `Client`, if it was started.
+func (mb *MockBot) Close() {
+	mb.slackLock.Lock()
+	defer mb.slackLock.Unlock()
+	if mb.slack != nil {
+		mb.slack.Close()
+		mb.slack, mb.client = nil, nil
+	}
+}
+
+// AddUser adds a user to the directory, and to the fake slack behind `Client`.
+func (mb *MockBot) AddUser(user slack.User) {
+	mb.directory.PutUser(user)
+	mb.slackLock.Lock()
+	defer mb.slackLock.Unlock()
+	if mb.slack != nil {
+		mb.slack.PutUser(user)
+	}
+}
+
+// AddChannel adds a channel to the directory, and to the fake slack behind `Client`.
+func (mb *MockBot) AddChannel(channel slack.Channel) {
+	mb.directory.PutChannel(channel)
+	mb.slackLock.Lock()
+	defer mb.slackLock.Unlock()
+	if mb.slack != nil {
+		mb.slack.PutChannel(channel)
+	}
+}
+
+// Outbox returns everything the mock bot has sent, in order.
+func (mb *MockBot) Outbox() MockOutbox {
+	mb.outboxLock.Lock()
+	defer mb.outboxLock.Unlock()
+	return append(MockOutbox{}, mb.outbox...)
+}
+
+// ResetOutbox clears the outbox.
+func (mb *MockBot) ResetOutbox() {
+	mb.outboxLock.Lock()
+	defer mb.outboxLock.Unlock()
+	mb.outbox = nil
+}
+
+func (mb *MockBot) record(o MockOutbound) {
+	mb.outboxLock.Lock()
+	defer mb.outboxLock.Unlock()
+	mb.outbox = append(mb.outbox, o)
 }
 
 // Actions returns the actions loaded for a bot
@@ -114,6 +288,11 @@ func (mb *MockBot) TriggerAction(id string, m *slack.Message) error {
 	return exception.Newf("action %s is not loaded.", id)
 }
 
+// IsConnected returns true; the mock bot is always connected.
+func (mb *MockBot) IsConnected() bool {
+	return true
+}
+
 // ActiveChannels returns a list of active channel ids.
 func (mb *MockBot) ActiveChannels() []string {
 	return []string{"CTESTCHANNEL"}
@@ -121,6 +300,9 @@ func (mb *MockBot) ActiveChannels() []string {
 
 // FindUser returns the user object for a given userID.
 func (mb *MockBot) FindUser(userID string) *slack.User {
+	if user := mb.directory.User(userID); user != nil {
+		return user
+	}
 	return &slack.User{
 		ID:   slack.UUIDv4().ToShortString(),
 		Name: "test_user",
@@ -133,28 +315,116 @@ func (mb *MockBot) FindUser(userID string) *slack.User {
 	}
 }
 
-// FindChannel returns the channel object for a given channelID.
+// FindChannel returns the channel object for a given channelID; channels not in the directory are the test channel.
 func (mb *MockBot) FindChannel(channelID string) *slack.Channel {
+	if channel := mb.directory.Channel(channelID); channel != nil {
+		return channel
+	}
 	return &slack.Channel{
 		ID:   "CTESTCHANNEL",
 		Name: "test-channel",
 	}
 }
 
-// Say routes messages to a mock handler if there is one.
+// FindChannelByName returns the channel in the directory with a given name, or the test channel if the name matches it.
+func (mb *MockBot) FindChannelByName(name string) *slack.Channel {
+	if channel := mb.directory.ChannelByName(name); channel != nil {
+		return channel
+	}
+	if strings.TrimPrefix(name, "#") == "test-channel" {
+		return mb.FindChannel("CTESTCHANNEL")
+	}
+	return nil
+}
+
+// ResolveChannelID returns the id for a channel given as an id, a name, a `#name` or a `<#id|name>` mention.
+func (mb *MockBot) ResolveChannelID(channel string) string {
+	channel = strings.TrimSpace(channel)
+	if strings.HasPrefix(channel, "<#") {
+		return MentionID(channel)
+	}
+	if found := mb.FindChannelByName(channel); found != nil {
+		return found.ID
+	}
+	return strings.TrimPrefix(channel, "#")
+}
+
+// Say records messages in the outbox and routes them to a mock handler if there is one.
 func (mb *MockBot) Say(destinationID string, components ...interface{}) error {
 	messageText := fmt.Sprint(components...)
+	mb.record(MockOutbound{Kind: MockOutboundSay, Channel: destinationID, Text: messageText})
 	mb.dispatchToMockHandler(MockMessage(messageText))
 	return nil
 }
 
-// Sayf routes messages to a mock handler if there is one.
+// Sayf records messages in the outbox and routes them to a mock handler if there is one.
 func (mb *MockBot) Sayf(destinationID, format string, components ...interface{}) error {
-	messageText := fmt.Sprintf(format, components...)
+	return mb.Say(destinationID, fmt.Sprintf(format, components...))
+}
+
+// Reply records messages in the outbox and routes them to a mock handler if there is one, keeping the channel and
+// thread of the message being replied to.
+func (mb *MockBot) Reply(m *slack.Message, components ...interface{}) error {
+	reply := MockMessage(fmt.Sprint(components...))
+	reply.Channel = m.Channel
+	reply.ThreadTimestamp = m.ThreadTimestamp
+	mb.record(MockOutbound{Kind: MockOutboundReply, Channel: reply.Channel, Text: reply.Text, ThreadTimestamp: ThreadID(m)})
+	mb.dispatchToMockHandler(reply)
+	return nil
+}
+
+// Replyf records messages in the outbox and routes them to a mock handler if there is one, keeping the channel and thread of the message being replied to.
+func (mb *MockBot) Replyf(m *slack.Message, format string, components ...interface{}) error {
+	return mb.Reply(m, fmt.Sprintf(format, components...))
+}
+
+// ReplyMessage records messages in the outbox, attachments and all, and routes them to a mock handler if there is
+// one, keeping the channel and thread of the message being replied to.
+func (mb *MockBot) ReplyMessage(m *slack.Message, message *slack.ChatMessage) error {
+	if message.ThreadTimestamp == nil {
+		message.ThreadTimestamp = ThreadTimestamp(m)
+	}
+	reply := MockMessage(message.Text)
+	reply.Channel = message.Channel
+	reply.ThreadTimestamp = m.ThreadTimestamp
+	mb.record(MockOutbound{Kind: MockOutboundReply, Channel: message.Channel, Text: message.Text, ThreadTimestamp: ThreadID(m), Message: message})
+	mb.dispatchToMockHandler(reply)
+	return nil
+}
+
+// PostMessage records messages in the outbox, attachments and all, and routes them to a mock handler if there is
+// one, keeping the channel.
+func (mb *MockBot) PostMessage(message *slack.ChatMessage) error {
+	posted := MockMessage(message.Text)
+	posted.Channel = message.Channel
+	threadTimestamp := ""
+	if message.ThreadTimestamp != nil {
+		threadTimestamp = *message.ThreadTimestamp
+	}
+	mb.record(MockOutbound{Kind: MockOutboundPost, Channel: message.Channel, Text: message.Text, ThreadTimestamp: threadTimestamp, Message: message})
+	mb.dispatchToMockHandler(posted)
+	return nil
+}
+
+// InviteUser records the invite in the outbox.
+func (mb *MockBot) InviteUser(channelID, userID string) error {
+	mb.record(MockOutbound{Kind: MockOutboundInvite, Channel: channelID, UserID: userID})
+	return nil
+}
+
+// DirectMessage records messages in the outbox and routes them to a mock handler if there is one.
+func (mb *MockBot) DirectMessage(userID string, components ...interface{}) error {
+	messageText := fmt.Sprint(components...)
+	mb.record(MockOutbound{Kind: MockOutboundDirectMessage, Channel: userID, Text: messageText, UserID: userID})
 	mb.dispatchToMockHandler(MockMessage(messageText))
 	return nil
 }
 
+// DirectMessagef records messages in the outbox and routes them to a mock handler if there is one.
+func (mb *MockBot) DirectMessagef(userID, format string, components ...interface{}) error {
+	return mb.DirectMessage(userID, fmt.Sprintf(format, components...))
+}
+
 // Log writes to the log.
 func (mb *MockBot) Log(components ...interface{}) {}
 
@@ -169,11 +439,15 @@ func (mb *MockBot) dispatchToMockHandler(m *slack.Message) {
 
 // RegisterModule loads a given bot module
 func (mb *MockBot) RegisterModule(m BotModule) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
 	mb.modules[m.Name()] = m
 }
 
 // LoadModule loads a registered module.
 func (mb *MockBot) LoadModule(moduleName string) error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
 	if _, hasModule := mb.modules[moduleName]; hasModule {
 		mb.loadedModules.Add(moduleName)
 	}
@@ -182,18 +456,28 @@ func (mb *MockBot) LoadModule(moduleName string) error {
 
 // UnloadModule unloads a module and its actions.
 func (mb *MockBot) UnloadModule(moduleName string) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
 	if _, hasModule := mb.modules[moduleName]; hasModule {
 		mb.loadedModules.Remove(moduleName)
 	}
 }
 
-// LoadedModules returns the currently loaded modules.
+// LoadedModules returns a copy of the currently loaded modules.
 func (mb *MockBot) LoadedModules() collections.SetOfString {
-	return mb.loadedModules
+	mb.lock.RLock()
+	defer mb.lock.RUnlock()
+	loaded := collections.SetOfString{}
+	for name := range mb.loadedModules {
+		loaded.Add(name)
+	}
+	return loaded
 }
 
 // RegisteredModules returns the registered modules.
 func (mb *MockBot) RegisteredModules() collections.SetOfString {
+	mb.lock.RLock()
+	defer mb.lock.RUnlock()
 	registered := collections.SetOfString{}
 	for key := range mb.modules {
 		registered.Add(key)
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestMockBotOutbox(t *testing.T) {
+	assert := assert.New(t)
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+
+	var handled []string
+	mb.MockMessageHandler(func(b Bot, m *slack.Message) error {
+		handled = append(handled, m.Text)
+		return nil
+	})
+
+	threadTimestamp := testTimestamp("1476900000.000001")
+	assert.Nil(mb.Sayf("C01", "hello %s", "everyone"))
+	assert.Nil(mb.Reply(&slack.Message{Channel: "C02", ThreadTimestamp: &threadTimestamp}, "in a thread"))
+	assert.Nil(mb.DirectMessage("U01", "psst"))
+	assert.Nil(mb.InviteUser("C02", "U01"))
+
+	message := slack.NewChatMessage("C01", "")
+	message.Attachments = []slack.ChatMessageAttachment{{Text: slack.OptionalString("attached")}}
+	assert.Nil(mb.PostMessage(message))
+
+	outbox := mb.Outbox()
+	assert.Len(outbox, 5)
+	assert.Equal([]string{"hello everyone", "in a thread", "psst", ""}, handled, "the mock handler still sees messages")
+	assert.Equal([]string{"hello everyone", ""}, outbox.Channel("C01").Texts())
+	assert.Equal("1476900000.000001", outbox.Kind(MockOutboundReply).Last().ThreadTimestamp)
+	assert.Equal("U01", outbox.Kind(MockOutboundDirectMessage).Last().UserID)
+	assert.Equal("U01", outbox.Kind(MockOutboundInvite).Last().UserID)
+	assert.Equal("attached", *outbox.Last().Message.Attachments[0].Text)
+	assert.Len(outbox.Matching("^hello"), 1)
+	assert.Nil(outbox.Calls("chat.postMessage").Last())
+
+	mb.ResetOutbox()
+	assert.Empty(mb.Outbox())
+}
+
+func TestMockBotClient(t *testing.T) {
+	assert := assert.New(t)
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	defer mb.Close()
+
+	mb.AddUser(slack.User{ID: "U01", Name: "alice"})
+	mb.AddChannel(slack.Channel{ID: "C01", Name: "general", Members: []string{"U01"}})
+	assert.Equal("general", mb.FindChannel("C01").Name)
+	assert.Equal("C01", mb.FindChannelByName("#general").ID)
+	assert.Equal("CTESTCHANNEL", mb.FindChannel("C02").ID)
+
+	client := mb.Client()
+	assert.True(client == mb.Client())
+	assert.Nil(client.ChannelsSetTopic("C01", "deploys only"))
+	channel, err := client.ChannelsInfo("C01")
+	assert.Nil(err)
+	assert.Equal([]string{"U01"}, channel.Members)
+
+	mb.AddUser(slack.User{ID: "U02", Name: "bob"})
+	users, err := client.UsersList()
+	assert.Nil(err)
+	assert.Len(users, 3, "the bot, and the users added before and after the client started")
+
+	calls := mb.Outbox().Calls("channels.setTopic")
+	assert.Len(calls, 1)
+	assert.Equal("C01", calls[0].Channel)
+	assert.Equal("deploys only", calls[0].Args.Get("topic"))
+	assert.Len(mb.Outbox().Kind(MockOutboundAPI), 3)
+}
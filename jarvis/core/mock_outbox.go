@@ -0,0 +1,98 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// MockOutboundSay is the kind of messages sent with `Say` or `Sayf`.
+	MockOutboundSay = "say"
+
+	// MockOutboundReply is the kind of messages sent with `Reply`, `Replyf` or `ReplyMessage`.
+	MockOutboundReply = "reply"
+
+	// MockOutboundPost is the kind of messages sent with `PostMessage`, which keep their attachments.
+	MockOutboundPost = "post"
+
+	// MockOutboundDirectMessage is the kind of messages sent with `DirectMessage` or `DirectMessagef`.
+	MockOutboundDirectMessage = "direct_message"
+
+	// MockOutboundInvite is the kind of `InviteUser` calls.
+	MockOutboundInvite = "invite"
+
+	// MockOutboundAPI is the kind of web api calls made with the mock bot's `Client`, like adding reactions,
+	// setting topics or uploading files.
+	MockOutboundAPI = "api"
+)
+
+// MockOutbound is something a mock bot sent to slack.
+type MockOutbound struct {
+	Kind            string
+	Channel         string
+	Text            string
+	ThreadTimestamp string
+
+	// Message is the whole message for posts and `ReplyMessage` replies, with its attachments.
+	Message *slack.ChatMessage
+
+	// UserID is the user invited or direct messaged.
+	UserID string
+
+	// Method and Args are the web api method, like `reactions.add`, and its arguments for api calls.
+	Method string
+	Args   url.Values
+}
+
+// MockOutbox is the log of everything a mock bot sent, in order.
+type MockOutbox []MockOutbound
+
+// Kind returns the entries of a given kind.
+func (mo MockOutbox) Kind(kind string) MockOutbox {
+	return mo.filter(func(o MockOutbound) bool { return o.Kind == kind })
+}
+
+// Channel returns the entries sent to a given channel.
+func (mo MockOutbox) Channel(channelID string) MockOutbox {
+	return mo.filter(func(o MockOutbound) bool { return o.Channel == channelID })
+}
+
+// Calls returns the web api calls to a given method, like `channels.setTopic`.
+func (mo MockOutbox) Calls(method string) MockOutbox {
+	return mo.filter(func(o MockOutbound) bool { return o.Kind == MockOutboundAPI && o.Method == method })
+}
+
+// Matching returns the entries whose text matches a regular expression.
+func (mo MockOutbox) Matching(pattern string) MockOutbox {
+	expr := regexp.MustCompile(pattern)
+	return mo.filter(func(o MockOutbound) bool { return expr.MatchString(o.Text) })
+}
+
+// Texts returns the text of each entry.
+func (mo MockOutbox) Texts() []string {
+	texts := []string{}
+	for _, o := range mo {
+		texts = append(texts, o.Text)
+	}
+	return texts
+}
+
+// Last returns the last entry, or nil if there aren't any.
+func (mo MockOutbox) Last() *MockOutbound {
+	if len(mo) == 0 {
+		return nil
+	}
+	return &mo[len(mo)-1]
+}
+
+func (mo MockOutbox) filter(predicate func(MockOutbound) bool) MockOutbox {
+	filtered := MockOutbox{}
+	for _, o := range mo {
+		if predicate(o) {
+			filtered = append(filtered, o)
+		}
+	}
+	return filtered
+}
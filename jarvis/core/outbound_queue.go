@@ -0,0 +1,257 @@
+package core
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// DefaultOutboundChannelInterval is the minimum time between sends to a channel; slack allows about one message per second per channel.
+	DefaultOutboundChannelInterval = time.Second
+
+	// DefaultOutboundInitialBackoff is the wait before the first retry of a failed send; it doubles with each retry.
+	DefaultOutboundInitialBackoff = time.Second
+
+	// DefaultOutboundMaxBackoff is the longest wait between retries.
+	DefaultOutboundMaxBackoff = 30 * time.Second
+
+	// DefaultOutboundMaxAttempts is the number of times a send is tried before it's dropped.
+	DefaultOutboundMaxAttempts = 5
+)
+
+var (
+	slackErrorCodeExpr = regexp.MustCompile(`^[a-z_]+$`)
+
+	transientSlackErrors = []string{"ratelimited", "internal_error", "fatal_error", "service_unavailable", "request_timeout"}
+)
+
+// IsTransient returns if a failed slack api call or websocket send is worth retrying.
+// Slack api errors (e.g. `channel_not_found`) are permanent unless they're one of the few that signal slack being
+// unavailable; anything else (network errors, a closed websocket) is assumed to be transient.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, isRateLimited := err.(*slack.RateLimitedError); isRateLimited {
+		return true
+	}
+	message := err.Error()
+	if slackErrorCodeExpr.MatchString(message) {
+		return EqualsAny(message, transientSlackErrors...)
+	}
+	return true
+}
+
+// OutboundQueueStats are the counters for an outbound queue.
+type OutboundQueueStats struct {
+	Depth   int   `json:"depth"`
+	Sent    int64 `json:"sent"`
+	Retries int64 `json:"retries"`
+	Dropped int64 `json:"dropped"`
+}
+
+type outboundMessage struct {
+	channel   string
+	send      func() error
+	attempts  int
+	notBefore time.Time
+}
+
+// NewOutboundQueue returns a new, stopped outbound queue; `onDrop` is called with the last error for sends that are given up on.
+func NewOutboundQueue(onDrop func(channel string, err error)) *OutboundQueue {
+	return &OutboundQueue{
+		channelInterval: DefaultOutboundChannelInterval,
+		initialBackoff:  DefaultOutboundInitialBackoff,
+		maxBackoff:      DefaultOutboundMaxBackoff,
+		maxAttempts:     DefaultOutboundMaxAttempts,
+		onDrop:          onDrop,
+		now:             time.Now,
+		queues:          map[string][]*outboundMessage{},
+		working:         map[string]bool{},
+		lastSent:        map[string]time.Time{},
+	}
+}
+
+// OutboundQueue sends the bot's messages (and other writes) to slack in order per channel, at most one per
+// `DefaultOutboundChannelInterval` per channel. Transient failures are retried with exponential backoff,
+// and a `429` pauses the whole queue for the `Retry-After` slack asked for.
+// Sends queue up until the queue is started.
+type OutboundQueue struct {
+	lock sync.Mutex
+
+	channelInterval time.Duration
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	maxAttempts     int
+	onDrop          func(channel string, err error)
+	now             func() time.Time
+
+	running   bool
+	stop      chan struct{}
+	queues    map[string][]*outboundMessage
+	working   map[string]bool
+	lastSent  map[string]time.Time
+	holdUntil time.Time
+
+	sent    int64
+	retries int64
+	dropped int64
+}
+
+// Enqueue adds a send to the end of a channel's queue.
+func (oq *OutboundQueue) Enqueue(channel string, send func() error) {
+	oq.lock.Lock()
+	defer oq.lock.Unlock()
+	oq.queues[channel] = append(oq.queues[channel], &outboundMessage{channel: channel, send: send})
+	oq.startWorker(channel)
+}
+
+// Start starts sending, including anything queued while the queue was stopped.
+func (oq *OutboundQueue) Start() {
+	oq.lock.Lock()
+	defer oq.lock.Unlock()
+	if oq.running {
+		return
+	}
+	oq.running = true
+	oq.stop = make(chan struct{})
+	for channel := range oq.queues {
+		oq.startWorker(channel)
+	}
+}
+
+// Stop stops sending; anything still queued stays queued until the queue is started again.
+func (oq *OutboundQueue) Stop() {
+	oq.lock.Lock()
+	defer oq.lock.Unlock()
+	if !oq.running {
+		return
+	}
+	oq.running = false
+	close(oq.stop)
+}
+
+// Stats returns the queue depth and counters.
+func (oq *OutboundQueue) Stats() OutboundQueueStats {
+	oq.lock.Lock()
+	defer oq.lock.Unlock()
+	depth := 0
+	for _, queue := range oq.queues {
+		depth = depth + len(queue)
+	}
+	return OutboundQueueStats{Depth: depth, Sent: oq.sent, Retries: oq.retries, Dropped: oq.dropped}
+}
+
+// startWorker starts a channel's worker if the queue is running and it doesn't already have one; it must be called under the lock.
+func (oq *OutboundQueue) startWorker(channel string) {
+	if !oq.running || oq.working[channel] || len(oq.queues[channel]) == 0 {
+		return
+	}
+	oq.working[channel] = true
+	go oq.work(channel, oq.stop)
+}
+
+// work sends a channel's queue, one at a time, until it's empty or the queue is stopped.
+func (oq *OutboundQueue) work(channel string, stop chan struct{}) {
+	for {
+		oq.lock.Lock()
+		queue := oq.queues[channel]
+		if len(queue) == 0 || !oq.running || oq.stop != stop {
+			oq.finish(channel)
+			oq.lock.Unlock()
+			return
+		}
+		message := queue[0]
+		wait := oq.waitFor(message)
+		oq.lock.Unlock()
+
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+				continue
+			case <-stop:
+				oq.lock.Lock()
+				oq.finish(channel)
+				oq.lock.Unlock()
+				return
+			}
+		}
+
+		err := oq.safeSend(message)
+
+		oq.lock.Lock()
+		now := oq.now()
+		oq.lastSent[channel] = now
+		if err == nil {
+			oq.sent++
+			oq.pop(channel)
+		} else if IsTransient(err) && message.attempts+1 < oq.maxAttempts {
+			message.attempts++
+			oq.retries++
+			if rateLimited, isRateLimited := err.(*slack.RateLimitedError); isRateLimited {
+				oq.holdUntil = now.Add(rateLimited.RetryAfter)
+			} else {
+				message.notBefore = now.Add(oq.backoff(message.attempts))
+			}
+		} else {
+			oq.dropped++
+			oq.pop(channel)
+			if oq.onDrop != nil {
+				go oq.onDrop(channel, err)
+			}
+		}
+		oq.lock.Unlock()
+	}
+}
+
+// finish ends a channel's worker, starting a new one if the queue was restarted in the meantime; it must be called under the lock.
+func (oq *OutboundQueue) finish(channel string) {
+	delete(oq.working, channel)
+	if len(oq.queues[channel]) == 0 {
+		delete(oq.queues, channel)
+		return
+	}
+	oq.startWorker(channel)
+}
+
+// waitFor returns how long to wait before sending a message; it must be called under the lock.
+func (oq *OutboundQueue) waitFor(message *outboundMessage) time.Duration {
+	now := oq.now()
+	next := oq.lastSent[message.channel].Add(oq.channelInterval)
+	if oq.holdUntil.After(next) {
+		next = oq.holdUntil
+	}
+	if message.notBefore.After(next) {
+		next = message.notBefore
+	}
+	return next.Sub(now)
+}
+
+func (oq *OutboundQueue) backoff(attempts int) time.Duration {
+	backoff := oq.initialBackoff
+	for x := 1; x < attempts && backoff < oq.maxBackoff; x++ {
+		backoff = backoff * 2
+	}
+	if backoff > oq.maxBackoff {
+		return oq.maxBackoff
+	}
+	return backoff
+}
+
+func (oq *OutboundQueue) pop(channel string) {
+	oq.queues[channel] = oq.queues[channel][1:]
+}
+
+// safeSend runs a send, turning a panic into an error so a bad send doesn't take the worker down.
+func (oq *OutboundQueue) safeSend(message *outboundMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exception.Newf("panic sending to `%s`: %v", message.channel, r)
+		}
+	}()
+	return message.send()
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func testOutboundQueue(onDrop func(channel string, err error)) *OutboundQueue {
+	oq := NewOutboundQueue(onDrop)
+	oq.channelInterval = time.Millisecond
+	oq.initialBackoff = time.Millisecond
+	oq.maxBackoff = 4 * time.Millisecond
+	return oq
+}
+
+func waitForOutbound(oq *OutboundQueue, done func(stats OutboundQueueStats) bool) OutboundQueueStats {
+	deadline := time.Now().Add(5 * time.Second)
+	stats := oq.Stats()
+	for !done(stats) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		stats = oq.Stats()
+	}
+	return stats
+}
+
+func TestIsTransient(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(IsTransient(nil))
+	assert.True(IsTransient(&slack.RateLimitedError{RetryAfter: time.Second}))
+	assert.True(IsTransient(exception.New("internal_error")))
+	assert.True(IsTransient(exception.New("Connection is closed.")))
+	assert.True(IsTransient(exception.New("dial tcp: i/o timeout")))
+	assert.False(IsTransient(exception.New("channel_not_found")))
+	assert.False(IsTransient(exception.New("not_in_channel")))
+}
+
+func TestOutboundQueueOrdering(t *testing.T) {
+	assert := assert.New(t)
+
+	var lock sync.Mutex
+	sent := map[string][]int{}
+	oq := testOutboundQueue(nil)
+	for x := 0; x < 5; x++ {
+		for _, channel := range []string{"C01", "C02"} {
+			channel, x := channel, x
+			oq.Enqueue(channel, func() error {
+				lock.Lock()
+				defer lock.Unlock()
+				sent[channel] = append(sent[channel], x)
+				return nil
+			})
+		}
+	}
+	assert.Equal(10, oq.Stats().Depth, "nothing is sent until the queue is started")
+
+	oq.Start()
+	defer oq.Stop()
+	stats := waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Sent == 10 })
+	assert.Equal(0, stats.Depth)
+	assert.Equal(int64(10), stats.Sent)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal([]int{0, 1, 2, 3, 4}, sent["C01"])
+	assert.Equal([]int{0, 1, 2, 3, 4}, sent["C02"])
+}
+
+func TestOutboundQueueRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	var dropLock sync.Mutex
+	var dropped error
+	oq := testOutboundQueue(func(channel string, err error) {
+		dropLock.Lock()
+		defer dropLock.Unlock()
+		dropped = err
+	})
+	oq.Start()
+	defer oq.Stop()
+
+	attempts := 0
+	var order []string
+	oq.Enqueue("C01", func() error {
+		attempts++
+		if attempts < 3 {
+			return exception.New("Connection is closed.")
+		}
+		order = append(order, "first")
+		return nil
+	})
+	oq.Enqueue("C01", func() error {
+		order = append(order, "second")
+		return nil
+	})
+	stats := waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Sent == 2 })
+	assert.Equal(int64(2), stats.Retries)
+	assert.Equal([]string{"first", "second"}, order, "retries hold up the rest of the channel")
+
+	oq.Enqueue("C01", func() error { return exception.New("channel_not_found") })
+	stats = waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Dropped == 1 })
+	assert.Equal(int64(1), stats.Dropped)
+	assert.Equal(int64(2), stats.Retries, "permanent errors aren't retried")
+
+	giveUp := 0
+	oq.Enqueue("C02", func() error {
+		giveUp++
+		return exception.New("internal_error")
+	})
+	stats = waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Dropped == 2 })
+	assert.Equal(DefaultOutboundMaxAttempts, giveUp)
+	assert.Equal(0, stats.Depth)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		dropLock.Lock()
+		done := dropped != nil && dropped.Error() == "internal_error"
+		dropLock.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	dropLock.Lock()
+	defer dropLock.Unlock()
+	assert.Equal("internal_error", dropped.Error())
+}
+
+func TestOutboundQueueRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	oq := testOutboundQueue(nil)
+	oq.Start()
+	defer oq.Stop()
+
+	var lock sync.Mutex
+	var limited, retried time.Time
+	oq.Enqueue("C01", func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		if limited.IsZero() {
+			limited = time.Now()
+			return &slack.RateLimitedError{RetryAfter: 50 * time.Millisecond}
+		}
+		retried = time.Now()
+		return nil
+	})
+	var other time.Time
+	oq.Enqueue("C02", func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		if !limited.IsZero() {
+			other = time.Now()
+			return nil
+		}
+		return exception.New("Connection is closed.")
+	})
+
+	waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Sent == 2 })
+	lock.Lock()
+	defer lock.Unlock()
+	assert.True(retried.Sub(limited) >= 50*time.Millisecond)
+	assert.True(other.Sub(limited) >= 50*time.Millisecond, "a 429 holds every channel")
+}
+
+func TestOutboundQueueStop(t *testing.T) {
+	assert := assert.New(t)
+
+	oq := testOutboundQueue(nil)
+	oq.channelInterval = time.Hour
+	oq.Start()
+	oq.Enqueue("C01", func() error { return nil })
+	oq.Enqueue("C01", func() error { return nil })
+	stats := waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Sent == 1 })
+	assert.Equal(1, stats.Depth)
+
+	oq.Stop()
+	oq.lock.Lock()
+	oq.channelInterval = time.Millisecond
+	oq.lock.Unlock()
+	oq.Start()
+	defer oq.Stop()
+	stats = waitForOutbound(oq, func(stats OutboundQueueStats) bool { return stats.Sent == 2 })
+	assert.Equal(0, stats.Depth)
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// DefaultProcessedMessagesCapacity is the default number of recent messages tracked to avoid handling one twice.
+	DefaultProcessedMessagesCapacity = 1024
+)
+
+// NewProcessedMessages returns a new processed message tracker that remembers up to `capacity` recent messages,
+// forgetting the oldest first.
+func NewProcessedMessages(capacity int) *ProcessedMessages {
+	return &ProcessedMessages{
+		capacity: capacity,
+		seen:     map[string]bool{},
+		latest:   map[string]slack.Timestamp{},
+	}
+}
+
+// ProcessedMessages tracks the newest message the bot has processed in each channel, and which recent messages it's
+// processed, so messages it missed while disconnected can be caught up on without handling any of them twice.
+type ProcessedMessages struct {
+	lock     sync.Mutex
+	capacity int
+	order    []string
+	seen     map[string]bool
+	latest   map[string]slack.Timestamp
+}
+
+// Mark records that a message was processed, returning false if it already was.
+func (pm *ProcessedMessages) Mark(channelID string, ts slack.Timestamp) bool {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	return pm.mark(channelID, ts)
+}
+
+// mark records that a message was processed; it must be called under the lock.
+func (pm *ProcessedMessages) mark(channelID string, ts slack.Timestamp) bool {
+	key := correlationKey(channelID, ts.String())
+	if pm.seen[key] {
+		return false
+	}
+	pm.seen[key] = true
+	pm.order = append(pm.order, key)
+	if pm.capacity > 0 && len(pm.order) > pm.capacity {
+		delete(pm.seen, pm.order[0])
+		pm.order = pm.order[1:]
+	}
+	if latest, hasLatest := pm.latest[channelID]; !hasLatest || timestampBefore(latest, ts) {
+		pm.latest[channelID] = ts
+	}
+	return true
+}
+
+// Restore marks the newest messages processed in each channel by a previous run as processed, for the channels
+// that haven't had newer messages processed since, so they can be caught up on after a restart.
+func (pm *ProcessedMessages) Restore(latest map[string]slack.Timestamp) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	for channelID, ts := range latest {
+		if _, hasLatest := pm.latest[channelID]; !hasLatest {
+			pm.mark(channelID, ts)
+		}
+	}
+}
+
+// Latest returns the timestamp of the newest processed message in each channel.
+func (pm *ProcessedMessages) Latest() map[string]slack.Timestamp {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	latest := map[string]slack.Timestamp{}
+	for channelID, ts := range pm.latest {
+		latest[channelID] = ts
+	}
+	return latest
+}
+
+// timestampBefore returns if `a` is before `b`; slack timestamps are unix seconds and a fixed width sequence number.
+func timestampBefore(a, b slack.Timestamp) bool {
+	if !a.Time().Equal(b.Time()) {
+		return a.Time().Before(b.Time())
+	}
+	return a.UUID() < b.UUID()
+}
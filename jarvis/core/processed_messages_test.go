@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestProcessedMessages(t *testing.T) {
+	assert := assert.New(t)
+
+	pm := NewProcessedMessages(2)
+	assert.True(pm.Mark("C01", testTimestamp("1476900000.000002")))
+	assert.False(pm.Mark("C01", testTimestamp("1476900000.000002")), "messages are only processed once")
+	assert.True(pm.Mark("C01", testTimestamp("1476900000.000001")))
+	assert.True(pm.Mark("C02", testTimestamp("1476900001.000001")))
+
+	latest := pm.Latest()
+	assert.Len(latest, 2)
+	assert.Equal("1476900000.000002", latest["C01"].String(), "older messages don't move the latest back")
+	assert.Equal("1476900001.000001", latest["C02"].String())
+
+	assert.True(pm.Mark("C01", testTimestamp("1476900000.000002")), "the oldest messages are forgotten past capacity")
+}
+
+func TestProcessedMessagesRestore(t *testing.T) {
+	assert := assert.New(t)
+
+	pm := NewProcessedMessages(DefaultProcessedMessagesCapacity)
+	assert.True(pm.Mark("C01", testTimestamp("1476900005.000001")))
+	pm.Restore(map[string]slack.Timestamp{
+		"C01": testTimestamp("1476900000.000001"),
+		"C02": testTimestamp("1476900001.000001"),
+	})
+
+	latest := pm.Latest()
+	assert.Equal("1476900005.000001", latest["C01"].String(), "channels processed since aren't restored")
+	assert.Equal("1476900001.000001", latest["C02"].String())
+	assert.False(pm.Mark("C02", testTimestamp("1476900001.000001")), "restored messages count as processed")
+	assert.True(pm.Mark("C01", testTimestamp("1476900000.000001")))
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+)
+
+const (
+	// rateLimiterPruneThreshold is the number of buckets after which full (idle) buckets are dropped.
+	rateLimiterPruneThreshold = 1024
+)
+
+// ParseRateLimit parses a rate limit in the form `<events>/<duration>`, e.g. `10/1m`.
+// An empty value, `0` or `off` is no limit.
+func ParseRateLimit(value string) (RateLimit, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if len(value) == 0 || value == "off" || value == "0" {
+		return RateLimit{}, nil
+	}
+	pieces := strings.SplitN(value, "/", 2)
+	if len(pieces) != 2 {
+		return RateLimit{}, exception.Newf("invalid rate limit `%s`, should be `<events>/<duration>` (e.g. `10/1m`)", value)
+	}
+	events, err := strconv.Atoi(strings.TrimSpace(pieces[0]))
+	if err != nil || events < 0 {
+		return RateLimit{}, exception.Newf("invalid rate limit `%s`, events should be a positive number", value)
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(pieces[1]))
+	if err != nil || per <= 0 {
+		return RateLimit{}, exception.Newf("invalid rate limit `%s`, duration should be a positive duration", value)
+	}
+	return RateLimit{Events: events, Per: per}, nil
+}
+
+// RateLimit is a number of events allowed per duration; it's also the burst size.
+type RateLimit struct {
+	Events int
+	Per    time.Duration
+}
+
+// IsZero returns if the rate limit is unset, i.e. doesn't limit anything.
+func (rl RateLimit) IsZero() bool {
+	return rl.Events == 0 || rl.Per == 0
+}
+
+// String returns the rate limit in the form `ParseRateLimit` reads.
+func (rl RateLimit) String() string {
+	if rl.IsZero() {
+		return "off"
+	}
+	return strconv.Itoa(rl.Events) + "/" + rl.Per.String()
+}
+
+// RateLimitKey is a bucket key (e.g. `user:U01`) and the limit for it.
+type RateLimitKey struct {
+	Key   string
+	Limit RateLimit
+}
+
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	noticed  bool
+	capacity float64
+	perToken time.Duration
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(tb.updated); elapsed > 0 {
+		tb.tokens = tb.tokens + float64(elapsed)/float64(tb.perToken)
+		if tb.tokens > tb.capacity {
+			tb.tokens = tb.capacity
+		}
+	}
+	tb.updated = now
+}
+
+// NewRateLimiter returns a new rate limiter using the given clock.
+func NewRateLimiter(now func() time.Time) *RateLimiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &RateLimiter{
+		now:     now,
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// RateLimiter is a set of token buckets, one per key, that each hold up to `Events` tokens and
+// refill at `Events` per `Per`.
+type RateLimiter struct {
+	lock    sync.Mutex
+	now     func() time.Time
+	buckets map[string]*tokenBucket
+}
+
+// Allow takes a token from each key's bucket if all of them have one; keys with a zero limit are ignored.
+// If any bucket is empty nothing is taken, and `notify` is true the first time that bucket is hit
+// since it last allowed an event, so the caller can send a single notice.
+func (rl *RateLimiter) Allow(keys ...RateLimitKey) (allowed bool, notify bool) {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	now := rl.now()
+	buckets := []*tokenBucket{}
+	for _, key := range keys {
+		if key.Limit.IsZero() {
+			continue
+		}
+		bucket := rl.bucket(key, now)
+		if bucket.tokens < 1 {
+			notify = !bucket.noticed
+			bucket.noticed = true
+			return false, notify
+		}
+		buckets = append(buckets, bucket)
+	}
+	for _, bucket := range buckets {
+		bucket.tokens = bucket.tokens - 1
+		bucket.noticed = false
+	}
+	if len(rl.buckets) > rateLimiterPruneThreshold {
+		rl.prune(now)
+	}
+	return true, false
+}
+
+// Len returns the number of buckets.
+func (rl *RateLimiter) Len() int {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	return len(rl.buckets)
+}
+
+func (rl *RateLimiter) bucket(key RateLimitKey, now time.Time) *tokenBucket {
+	capacity := float64(key.Limit.Events)
+	perToken := key.Limit.Per / time.Duration(key.Limit.Events)
+	bucket, hasBucket := rl.buckets[key.Key]
+	if !hasBucket || bucket.capacity != capacity || bucket.perToken != perToken {
+		bucket = &tokenBucket{tokens: capacity, updated: now, capacity: capacity, perToken: perToken}
+		rl.buckets[key.Key] = bucket
+		return bucket
+	}
+	bucket.refill(now)
+	return bucket
+}
+
+// prune drops the buckets that have refilled, which are the same as new ones.
+func (rl *RateLimiter) prune(now time.Time) {
+	for key, bucket := range rl.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.capacity {
+			delete(rl.buckets, key)
+		}
+	}
+}
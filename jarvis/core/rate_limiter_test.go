@@ -0,0 +1,89 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	limit, err := ParseRateLimit("10/1m")
+	assert.Nil(err)
+	assert.Equal(10, limit.Events)
+	assert.Equal(time.Minute, limit.Per)
+	assert.Equal("10/1m0s", limit.String())
+
+	for _, off := range []string{"", "off", "0", " OFF "} {
+		limit, err = ParseRateLimit(off)
+		assert.Nil(err)
+		assert.True(limit.IsZero())
+	}
+
+	for _, invalid := range []string{"10", "ten/1m", "10/soon", "10/-1m", "-1/1m"} {
+		_, err = ParseRateLimit(invalid)
+		assert.NotNil(err, invalid)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC)
+	rl := NewRateLimiter(func() time.Time { return now })
+	user := RateLimitKey{Key: "user:U01", Limit: RateLimit{Events: 2, Per: time.Minute}}
+
+	allowed, notify := rl.Allow(user)
+	assert.True(allowed)
+	assert.False(notify)
+	allowed, _ = rl.Allow(user)
+	assert.True(allowed)
+
+	allowed, notify = rl.Allow(user)
+	assert.False(allowed)
+	assert.True(notify)
+	allowed, notify = rl.Allow(user)
+	assert.False(allowed)
+	assert.False(notify, "only the first limited event should notify")
+
+	now = now.Add(30 * time.Second)
+	allowed, _ = rl.Allow(user)
+	assert.True(allowed, "a token refills every 30s")
+	allowed, notify = rl.Allow(user)
+	assert.False(allowed)
+	assert.True(notify, "the notice resets once an event is allowed")
+
+	now = now.Add(time.Hour)
+	for x := 0; x < 2; x++ {
+		allowed, _ = rl.Allow(user)
+		assert.True(allowed)
+	}
+	allowed, _ = rl.Allow(user)
+	assert.False(allowed, "buckets don't fill past the burst size")
+}
+
+func TestRateLimiterMultipleKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC)
+	rl := NewRateLimiter(func() time.Time { return now })
+	channel := RateLimitKey{Key: "channel:C01", Limit: RateLimit{Events: 1, Per: time.Minute}}
+	alice := RateLimitKey{Key: "user:U01", Limit: RateLimit{Events: 5, Per: time.Minute}}
+	bob := RateLimitKey{Key: "user:U02", Limit: RateLimit{Events: 5, Per: time.Minute}}
+	unlimited := RateLimitKey{Key: "action:help"}
+
+	allowed, _ := rl.Allow(alice, channel, unlimited)
+	assert.True(allowed)
+	allowed, _ = rl.Allow(bob, channel, unlimited)
+	assert.False(allowed)
+	assert.Equal(3, rl.Len(), "unlimited keys don't get buckets")
+
+	for x := 0; x < 4; x++ {
+		allowed, _ = rl.Allow(alice)
+		assert.True(allowed, "a limited channel doesn't take tokens from the user")
+	}
+	allowed, _ = rl.Allow(alice)
+	assert.False(allowed)
+}
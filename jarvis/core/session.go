@@ -0,0 +1,267 @@
+package core
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// SessionDirectMessages is the session channel key used for direct messages with a user,
+	// so a session started by messaging a user picks up replies in whatever dm channel they use.
+	SessionDirectMessages = "dm"
+
+	// DefaultSessionTimeout is how long a session waits for a reply before it expires.
+	DefaultSessionTimeout = 10 * time.Minute
+)
+
+// SessionHandler handles a message from a user with an active session.
+type SessionHandler func(b Bot, m *slack.Message, s *Session) error
+
+// SessionChannel returns the session channel key for a channel id.
+func SessionChannel(channelID string) string {
+	if IsDM(channelID) {
+		return SessionDirectMessages
+	}
+	return channelID
+}
+
+// NewSession returns a new session for a user in a channel (or `SessionDirectMessages`).
+func NewSession(userID, channelID string, handler SessionHandler) *Session {
+	now := time.Now().UTC()
+	return &Session{
+		UserID:     userID,
+		ChannelID:  SessionChannel(channelID),
+		Handler:    handler,
+		Timeout:    DefaultSessionTimeout,
+		State:      map[string]interface{}{},
+		Started:    now,
+		LastActive: now,
+	}
+}
+
+// Session is a multi-message exchange with a user; while a session is active the user's
+// messages in its channel go to the session handler instead of the bot's actions.
+type Session struct {
+	UserID     string
+	ChannelID  string
+	ThreadID   string
+	Handler    SessionHandler
+	Timeout    time.Duration
+	State      map[string]interface{}
+	Started    time.Time
+	LastActive time.Time
+
+	// OnExpire is called (optionally) if the session times out.
+	OnExpire func(s *Session)
+
+	// PassThrough (optionally) returns if a message should skip the session and go to the bot's actions as usual.
+	PassThrough func(b Bot, m *slack.Message) bool
+
+	lock        sync.Mutex
+	ended       bool
+	interrupted *Session
+}
+
+// End ends the session once the current message has been handled.
+func (s *Session) End() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ended = true
+}
+
+// IsEnded returns if the session has ended.
+func (s *Session) IsEnded() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.ended
+}
+
+// IsExpired returns if the session has gone longer than its timeout without a message.
+func (s *Session) IsExpired(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Timeout > 0 && now.Sub(s.LastActive) > s.Timeout
+}
+
+// touch marks the session as active.
+func (s *Session) touch(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.LastActive = now
+}
+
+// PassCommands is a `PassThrough` that lets messages matching one of the bot's mention actions (other than the
+// catch all) go to that action, so a user can still run commands while they're in a session.
+func PassCommands(b Bot, m *slack.Message) bool {
+	messageText := strings.TrimSpace(LessMentions(m.Text))
+	for _, action := range b.Actions() {
+		if action.Passive || action.IsReaction() || isCatchAll(action) || IsEmpty(action.MessagePattern) {
+			continue
+		}
+		if Like(messageText, action.MessagePattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCatchAll returns if an action is a catch all; actions without a priority are added with `PriorityNormal`.
+func isCatchAll(action Action) bool {
+	return action.Priority != 0 && action.Priority <= PriorityCatchAll
+}
+
+// NewSessions returns a new session store.
+func NewSessions() *Sessions {
+	return &Sessions{sessions: map[string]*Session{}}
+}
+
+// Sessions is the set of active sessions, at most one per user per channel (and thread) at a time.
+// Starting a session where one is already active interrupts it; it picks back up once the new session ends.
+type Sessions struct {
+	lock     sync.Mutex
+	sessions map[string]*Session
+}
+
+func sessionKey(userID, channelID, threadID string) string {
+	return userID + "/" + SessionChannel(channelID) + "/" + threadID
+}
+
+// Start starts a session, interrupting any existing session for the user in the channel until it ends.
+func (s *Sessions) Start(session *Session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := sessionKey(session.UserID, session.ChannelID, session.ThreadID)
+	session.interrupted = s.sessions[key]
+	s.sessions[key] = session
+}
+
+// Get returns the active session for a user in a channel, or nil if there isn't one.
+// Expired sessions are removed (and their `OnExpire` called) when they're found.
+func (s *Sessions) Get(userID, channelID string) *Session {
+	return s.GetInThread(userID, channelID, "")
+}
+
+// GetInThread returns the active session for a user in a thread of a channel, or nil if there isn't one.
+func (s *Sessions) GetInThread(userID, channelID, threadID string) *Session {
+	s.lock.Lock()
+	session, expired := s.current(sessionKey(userID, channelID, threadID), time.Now().UTC())
+	s.lock.Unlock()
+
+	onExpire(expired)
+	return session
+}
+
+// End ends the session for a user in a channel, resuming the session it interrupted (if any).
+func (s *Sessions) End(userID, channelID string) {
+	s.EndInThread(userID, channelID, "")
+}
+
+// EndInThread ends the session for a user in a thread of a channel, resuming the session it interrupted (if any).
+func (s *Sessions) EndInThread(userID, channelID, threadID string) {
+	s.lock.Lock()
+	key := sessionKey(userID, channelID, threadID)
+	session, expired := s.current(key, time.Now().UTC())
+	if session != nil {
+		session.End()
+		s.pop(key, session)
+	}
+	s.lock.Unlock()
+
+	onExpire(expired)
+}
+
+// Handle routes a message to the sender's active session in the message's channel (and thread), if any,
+// returning if it was handled.
+func (s *Sessions) Handle(b Bot, m *slack.Message) (bool, error) {
+	session := s.GetInThread(m.User, m.Channel, ThreadID(m))
+	if session == nil {
+		return false, nil
+	}
+	if session.PassThrough != nil && session.PassThrough(b, m) {
+		return false, nil
+	}
+
+	session.touch(time.Now().UTC())
+	err := session.Handler(b, m, session)
+	if session.IsEnded() {
+		s.lock.Lock()
+		s.pop(sessionKey(session.UserID, session.ChannelID, session.ThreadID), session)
+		s.lock.Unlock()
+	}
+	return true, err
+}
+
+// Expire removes the sessions that have timed out, calling their `OnExpire`.
+// Interrupted sessions are only checked once the sessions in front of them end.
+func (s *Sessions) Expire() {
+	now := time.Now().UTC()
+	expired := []*Session{}
+
+	s.lock.Lock()
+	for key := range s.sessions {
+		_, expiredForKey := s.current(key, now)
+		expired = append(expired, expiredForKey...)
+	}
+	s.lock.Unlock()
+
+	onExpire(expired)
+}
+
+// Active returns the active sessions, including the ones that are interrupted.
+func (s *Sessions) Active() []*Session {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now().UTC()
+	active := []*Session{}
+	for _, session := range s.sessions {
+		for ; session != nil; session = session.interrupted {
+			if !session.IsEnded() && !session.IsExpired(now) {
+				active = append(active, session)
+			}
+		}
+	}
+	return active
+}
+
+// current returns the session in front for a key, removing the ended and expired sessions ahead of it
+// and returning the expired ones so their `OnExpire` can be called once the lock is released.
+// It must be called with the lock held.
+func (s *Sessions) current(key string, now time.Time) (session *Session, expired []*Session) {
+	for session = s.sessions[key]; session != nil; session = s.sessions[key] {
+		if session.IsEnded() {
+			s.pop(key, session)
+		} else if session.IsExpired(now) {
+			s.pop(key, session)
+			expired = append(expired, session)
+		} else {
+			return
+		}
+	}
+	return
+}
+
+// pop removes a session if it's in front for a key, resuming the session it interrupted.
+// It must be called with the lock held.
+func (s *Sessions) pop(key string, session *Session) {
+	if s.sessions[key] != session {
+		return
+	}
+	if session.interrupted != nil {
+		s.sessions[key] = session.interrupted
+	} else {
+		delete(s.sessions, key)
+	}
+}
+
+// onExpire calls `OnExpire` for sessions that have expired.
+func onExpire(expired []*Session) {
+	for _, session := range expired {
+		if session.OnExpire != nil {
+			session.OnExpire(session)
+		}
+	}
+}
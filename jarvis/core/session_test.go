@@ -0,0 +1,140 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestSessions(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	sessions := NewSessions()
+
+	handled, err := sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "hello"})
+	assert.False(handled)
+	assert.Nil(err)
+
+	replies := []string{}
+	session := NewSession("U01", "D02", func(b Bot, m *slack.Message, s *Session) error {
+		replies = append(replies, m.Text)
+		if m.Text == "done" {
+			s.End()
+		}
+		return nil
+	})
+	assert.Equal(SessionDirectMessages, session.ChannelID)
+	sessions.Start(session)
+
+	// dm sessions pick up messages from any dm channel, but not other channels or users.
+	handled, _ = sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "hello"})
+	assert.True(handled)
+	handled, _ = sessions.Handle(mb, &slack.Message{User: "U01", Channel: "C01", Text: "hello"})
+	assert.False(handled)
+	handled, _ = sessions.Handle(mb, &slack.Message{User: "U02", Channel: "D01", Text: "hello"})
+	assert.False(handled)
+
+	handled, _ = sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "done"})
+	assert.True(handled)
+	assert.Equal([]string{"hello", "done"}, replies)
+	assert.Nil(sessions.Get("U01", "D01"))
+	assert.Empty(sessions.Active())
+}
+
+func TestSessionsExpire(t *testing.T) {
+	assert := assert.New(t)
+
+	sessions := NewSessions()
+	expired := false
+	session := NewSession("U01", "C01", func(b Bot, m *slack.Message, s *Session) error { return nil })
+	session.Timeout = time.Minute
+	session.LastActive = time.Now().UTC().Add(-2 * time.Minute)
+	session.OnExpire = func(s *Session) { expired = true }
+	sessions.Start(session)
+
+	assert.Nil(sessions.Get("U01", "C01"))
+	assert.True(expired)
+
+	sessions.Start(NewSession("U01", "C01", nil))
+	assert.NotNil(sessions.Get("U01", "C01"))
+	sessions.End("U01", "C01")
+	assert.Nil(sessions.Get("U01", "C01"))
+}
+
+func TestSessionsInterrupt(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	sessions := NewSessions()
+
+	replies := map[string][]string{}
+	handler := func(name string) SessionHandler {
+		return func(b Bot, m *slack.Message, s *Session) error {
+			replies[name] = append(replies[name], m.Text)
+			if m.Text == "done" {
+				s.End()
+			}
+			return nil
+		}
+	}
+	first := NewSession("U01", "D01", handler("first"))
+	sessions.Start(first)
+	second := NewSession("U01", "D01", handler("second"))
+	sessions.Start(second)
+	assert.Len(sessions.Active(), 2)
+
+	sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "done"})
+	assert.Equal([]string{"done"}, replies["second"])
+	assert.Empty(replies["first"])
+
+	// the interrupted session picks back up once the session in front of it ends.
+	assert.True(first == sessions.Get("U01", "D01"))
+	sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "hello"})
+	assert.Equal([]string{"hello"}, replies["first"])
+
+	// ending the session in front resumes the one it interrupted, skipping any that ended in the meantime.
+	third := NewSession("U01", "D01", handler("third"))
+	sessions.Start(third)
+	fourth := NewSession("U01", "D01", handler("fourth"))
+	sessions.Start(fourth)
+	third.End()
+	sessions.End("U01", "D01")
+	assert.True(first == sessions.Get("U01", "D01"))
+
+	// expired sessions are skipped too.
+	expired := false
+	fifth := NewSession("U01", "D01", handler("fifth"))
+	fifth.Timeout = time.Minute
+	fifth.LastActive = time.Now().UTC().Add(-2 * time.Minute)
+	fifth.OnExpire = func(s *Session) { expired = true }
+	sessions.Start(fifth)
+	sessions.Expire()
+	assert.True(expired)
+	assert.True(first == sessions.Get("U01", "D01"))
+
+	sessions.End("U01", "D01")
+	assert.Nil(sessions.Get("U01", "D01"))
+	assert.Empty(sessions.Active())
+}
+
+func TestSessionsPassCommands(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := NewMockBot(slack.UUIDv4().ToShortString())
+	mb.AddAction(Action{ID: "time", MessagePattern: "^time"})
+	mb.AddAction(Action{ID: "catchall", MessagePattern: "(.*)", Priority: PriorityCatchAll})
+	mb.AddAction(Action{ID: "passive", MessagePattern: "^lunch", Passive: true})
+
+	sessions := NewSessions()
+	session := NewSession("U01", "D01", func(b Bot, m *slack.Message, s *Session) error { return nil })
+	session.PassThrough = PassCommands
+	sessions.Start(session)
+
+	handled, _ := sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "time in utc"})
+	assert.False(handled)
+	handled, _ = sessions.Handle(mb, &slack.Message{User: "U01", Channel: "D01", Text: "lunch was good"})
+	assert.True(handled, "passive and catch all actions don't count as commands")
+}
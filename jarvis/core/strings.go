@@ -95,6 +95,15 @@ func LessMentions(message string) string {
 	return output
 }
 
+// MentionID returns the id from a user or channel mention like `<@U024BE7LH|bob>` or `<#C024BE7LR>`.
+func MentionID(mention string) string {
+	id := strings.TrimSuffix(strings.TrimLeft(mention, "<@#"), ">")
+	if index := strings.Index(id, "|"); index >= 0 {
+		return id[:index]
+	}
+	return id
+}
+
 // LessSpecificMention removes a specific mention from a message.
 func LessSpecificMention(message, userID string) string {
 	output := ""
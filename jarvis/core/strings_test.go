@@ -38,6 +38,13 @@ func TestLessMentions(t *testing.T) {
 	a.Equal("this is a test of mentions ", lessMentions)
 }
 
+func TestMentionID(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("U024BE7LH", MentionID("<@U024BE7LH|bob>"))
+	a.Equal("C024BE7LR", MentionID("<#C024BE7LR>"))
+	a.Equal("C024BE7LR", MentionID("<#C024BE7LR|general>"))
+}
+
 func TestLessSpecificMention(t *testing.T) {
 	a := assert.New(t)
 
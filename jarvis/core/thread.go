@@ -0,0 +1,32 @@
+package core
+
+import "github.com/wcharczuk/jarvis/jarvis/slack"
+
+// ThreadID returns the timestamp of the thread a message is in, or empty if it isn't in a thread.
+func ThreadID(m *slack.Message) string {
+	if m == nil || m.ThreadTimestamp == nil {
+		return ""
+	}
+	return m.ThreadTimestamp.String()
+}
+
+// ThreadTimestamp returns the thread timestamp to reply to a message with in a `slack.ChatMessage`,
+// or nil if the message isn't in a thread.
+func ThreadTimestamp(m *slack.Message) *string {
+	threadID := ThreadID(m)
+	if len(threadID) == 0 {
+		return nil
+	}
+	return &threadID
+}
+
+// InThread returns a copy of a message that is in a thread on itself if it isn't already in a thread,
+// so replies to it start a thread.
+func InThread(m *slack.Message) *slack.Message {
+	if m.ThreadTimestamp != nil || m.Timestamp == nil {
+		return m
+	}
+	threaded := *m
+	threaded.ThreadTimestamp = m.Timestamp
+	return &threaded
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestThreadTimestamp(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(ThreadTimestamp(&slack.Message{Channel: "C01", Text: "not threaded"}))
+
+	var threaded slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"channel":"C01","text":"threaded","ts":"1476900000.000003","thread_ts":"1476900000.000002"}`), &threaded))
+	assert.NotNil(ThreadTimestamp(&threaded))
+	assert.Equal("1476900000.000002", *ThreadTimestamp(&threaded))
+}
+
+func TestInThread(t *testing.T) {
+	assert := assert.New(t)
+
+	var message slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"channel":"C01","text":"help","ts":"1476900000.000003"}`), &message))
+	assert.Empty(ThreadID(&message))
+
+	threaded := InThread(&message)
+	assert.Equal("1476900000.000003", ThreadID(threaded))
+	assert.Equal("C01", threaded.Channel)
+	assert.Empty(ThreadID(&message), "the original message shouldn't change")
+
+	var reply slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"channel":"C01","text":"help","ts":"1476900000.000003","thread_ts":"1476900000.000002"}`), &reply))
+	assert.Equal("1476900000.000002", ThreadID(InThread(&reply)), "messages already in a thread stay in it")
+}
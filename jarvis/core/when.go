@@ -0,0 +1,280 @@
+package core
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// DefaultHour is the hour used when a time names a day but not a time of day.
+	DefaultHour = 9
+)
+
+var (
+	weekdayNames = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+
+	durationUnits = map[string]time.Duration{
+		"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+		"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+		"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+		"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "wks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	}
+
+	durationPartExpr = regexp.MustCompile(`(\d+|\ban|\ba)\s*([a-z]+)`)
+	clockExpr        = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a\.m\.|p\.m\.)?$`)
+	isoDateExpr      = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	shortDateExpr    = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})$`)
+)
+
+// UserLocation returns the time zone for a user, or UTC if it isn't known.
+func UserLocation(user *slack.User) *time.Location {
+	if user == nil || len(user.TZ) == 0 {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(user.TZ)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// ParseWeekday parses a weekday name (`friday`, `fri` or `fridays`).
+func ParseWeekday(text string) (time.Weekday, bool) {
+	text = strings.ToLower(text)
+	if weekday, isWeekday := weekdayNames[text]; isWeekday {
+		return weekday, true
+	}
+	weekday, isWeekday := weekdayNames[strings.TrimSuffix(text, "s")]
+	return weekday, isWeekday
+}
+
+// ParseDuration parses a natural language duration, e.g. `2h`, `90 minutes`, `an hour` or `1 hour and 30 minutes`.
+func ParseDuration(text string) (time.Duration, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.Replace(text, "half an hour", "30 minutes", -1)
+	text = strings.Replace(strings.Replace(text, ",", " ", -1), " and ", " ", -1)
+	if len(text) == 0 {
+		return 0, exception.New("duration is empty")
+	}
+
+	var total time.Duration
+	for _, match := range durationPartExpr.FindAllStringSubmatch(text, -1) {
+		unit, hasUnit := durationUnits[match[2]]
+		if !hasUnit {
+			return 0, exception.Newf("unknown unit of time `%s`", match[2])
+		}
+		count := 1
+		if match[1] != "a" && match[1] != "an" {
+			count, _ = strconv.Atoi(match[1])
+		}
+		total = total + time.Duration(count)*unit
+	}
+
+	remainder := durationPartExpr.ReplaceAllString(text, "")
+	remainder = strings.TrimSpace(remainder)
+	if len(remainder) != 0 || total == 0 {
+		return 0, exception.Newf("invalid duration `%s`", text)
+	}
+	return total, nil
+}
+
+// ParseClock parses a time of day, e.g. `4pm`, `4:30 pm`, `16:30`, `noon` or `midnight`.
+func ParseClock(text string) (hour, minute int, err error) {
+	hour, minute, consumed, ok := parseClockTokens(strings.Fields(strings.ToLower(text)))
+	if !ok || consumed != len(strings.Fields(text)) {
+		return 0, 0, exception.Newf("invalid time of day `%s`", text)
+	}
+	return hour, minute, nil
+}
+
+// ParseWhen parses a natural language time relative to `now`, e.g. `in 2h`, `in 1 hour 30 minutes`,
+// `at 4pm`, `tomorrow at 9:30am`, `friday at 4pm`, `next monday` or `on 2016-10-21 at noon`.
+// Times of day are in now's location; a day without a time of day is at 9am, and a time of day
+// without a day is the next time that time of day comes around.
+func ParseWhen(text string, now time.Time) (time.Time, error) {
+	tokens := strings.Fields(strings.ToLower(strings.TrimSpace(text)))
+	if len(tokens) == 0 {
+		return time.Time{}, exception.New("time is empty")
+	}
+
+	if tokens[0] == "in" {
+		duration, err := ParseDuration(strings.Join(tokens[1:], " "))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(duration), nil
+	}
+
+	location := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+	var day *time.Time
+	var weekday *time.Weekday
+	hour, minute := DefaultHour, 0
+	hasClock := false
+
+	setDay := func(value time.Time) error {
+		if day != nil {
+			return exception.Newf("`%s` names more than one day", text)
+		}
+		day = &value
+		return nil
+	}
+
+	for index := 0; index < len(tokens); index++ {
+		token := tokens[index]
+		var err error
+		switch {
+		case token == "at" || token == "on":
+			continue
+		case token == "today":
+			err = setDay(today)
+		case token == "tomorrow":
+			err = setDay(today.AddDate(0, 0, 1))
+		case token == "next" && index+1 < len(tokens):
+			next, isWeekday := ParseWeekday(tokens[index+1])
+			if !isWeekday {
+				return time.Time{}, exception.Newf("invalid time `%s`", text)
+			}
+			err = setDay(today.AddDate(0, 0, daysUntil(now.Weekday(), next, false)))
+			index++
+		case isWeekdayName(token):
+			parsed, _ := ParseWeekday(token)
+			weekday = &parsed
+			err = setDay(today.AddDate(0, 0, daysUntil(now.Weekday(), parsed, true)))
+		case isoDateExpr.MatchString(token):
+			parts := isoDateExpr.FindStringSubmatch(token)
+			year, _ := strconv.Atoi(parts[1])
+			month, _ := strconv.Atoi(parts[2])
+			dayOfMonth, _ := strconv.Atoi(parts[3])
+			err = setDay(time.Date(year, time.Month(month), dayOfMonth, 0, 0, 0, 0, location))
+		case shortDateExpr.MatchString(token):
+			parts := shortDateExpr.FindStringSubmatch(token)
+			month, _ := strconv.Atoi(parts[1])
+			dayOfMonth, _ := strconv.Atoi(parts[2])
+			date := time.Date(now.Year(), time.Month(month), dayOfMonth, 0, 0, 0, 0, location)
+			if date.Before(today) {
+				date = date.AddDate(1, 0, 0)
+			}
+			err = setDay(date)
+		default:
+			if hasClock {
+				return time.Time{}, exception.Newf("invalid time `%s`", text)
+			}
+			var consumed int
+			var ok bool
+			hour, minute, consumed, ok = parseClockTokens(tokens[index:])
+			if !ok {
+				return time.Time{}, exception.Newf("invalid time `%s`", text)
+			}
+			hasClock = true
+			index = index + consumed - 1
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if day == nil && !hasClock {
+		return time.Time{}, exception.Newf("invalid time `%s`", text)
+	}
+
+	if day == nil {
+		result := time.Date(today.Year(), today.Month(), today.Day(), hour, minute, 0, 0, location)
+		if !result.After(now) {
+			result = time.Date(today.Year(), today.Month(), today.Day()+1, hour, minute, 0, 0, location)
+		}
+		return result, nil
+	}
+
+	result := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, location)
+	if !result.After(now) && weekday != nil {
+		result = time.Date(day.Year(), day.Month(), day.Day()+7, hour, minute, 0, 0, location)
+	}
+	if !result.After(now) {
+		return time.Time{}, exception.Newf("`%s` is in the past", text)
+	}
+	return result, nil
+}
+
+func isWeekdayName(token string) bool {
+	_, isWeekday := ParseWeekday(token)
+	return isWeekday
+}
+
+// daysUntil returns the number of days from one weekday to the next occurrence of another.
+func daysUntil(from, to time.Weekday, includeToday bool) int {
+	days := (int(to) - int(from) + 7) % 7
+	if days == 0 && !includeToday {
+		return 7
+	}
+	return days
+}
+
+// parseClockTokens parses a time of day from the start of a token list, returning how many tokens it used.
+func parseClockTokens(tokens []string) (hour, minute, consumed int, ok bool) {
+	if len(tokens) == 0 {
+		return
+	}
+	switch tokens[0] {
+	case "noon", "midday":
+		return 12, 0, 1, true
+	case "midnight":
+		return 0, 0, 1, true
+	}
+
+	token := tokens[0]
+	consumed = 1
+	if len(tokens) > 1 && EqualsAny(tokens[1], "am", "pm", "a.m.", "p.m.") && !strings.HasSuffix(token, "m.") && !strings.HasSuffix(token, "m") {
+		token = token + tokens[1]
+		consumed = 2
+	}
+
+	parts := clockExpr.FindStringSubmatch(token)
+	if parts == nil {
+		return 0, 0, 0, false
+	}
+	hour, _ = strconv.Atoi(parts[1])
+	if len(parts[2]) != 0 {
+		minute, _ = strconv.Atoi(parts[2])
+	}
+	if minute > 59 {
+		return 0, 0, 0, false
+	}
+
+	switch strings.Replace(parts[3], ".", "", -1) {
+	case "am":
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false
+		}
+		if hour != 12 {
+			hour = hour + 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, 0, false
+		}
+	}
+	return hour, minute, consumed, true
+}
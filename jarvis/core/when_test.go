@@ -0,0 +1,107 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestParseDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]time.Duration{
+		"2h":                    2 * time.Hour,
+		"90 minutes":            90 * time.Minute,
+		"an hour":               time.Hour,
+		"half an hour":          30 * time.Minute,
+		"1 hour and 30 minutes": 90 * time.Minute,
+		"1h30m":                 90 * time.Minute,
+		"2 days, 3 hours":       51 * time.Hour,
+		"a week":                7 * 24 * time.Hour,
+		"10 secs":               10 * time.Second,
+	}
+	for text, expected := range cases {
+		actual, err := ParseDuration(text)
+		assert.Nil(err, text)
+		assert.Equal(expected, actual, text)
+	}
+
+	for _, text := range []string{"", "soon", "2 fortnights", "1 hour to deploy"} {
+		_, err := ParseDuration(text)
+		assert.NotNil(err, text)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string][2]int{
+		"4pm":      {16, 0},
+		"4:30 pm":  {16, 30},
+		"12am":     {0, 0},
+		"12pm":     {12, 0},
+		"16:30":    {16, 30},
+		"9":        {9, 0},
+		"noon":     {12, 0},
+		"midnight": {0, 0},
+	}
+	for text, expected := range cases {
+		hour, minute, err := ParseClock(text)
+		assert.Nil(err, text)
+		assert.Equal(expected[0], hour, text)
+		assert.Equal(expected[1], minute, text)
+	}
+
+	for _, text := range []string{"13pm", "24:00", "4:61", "four", "4pm tomorrow"} {
+		_, _, err := ParseClock(text)
+		assert.NotNil(err, text)
+	}
+}
+
+func TestParseWhen(t *testing.T) {
+	assert := assert.New(t)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(err)
+	// wednesday
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, newYork)
+
+	cases := map[string]time.Time{
+		"in 2h":                 now.Add(2 * time.Hour),
+		"in 1 hour 30 minutes":  now.Add(90 * time.Minute),
+		"at 4pm":                time.Date(2016, 10, 19, 16, 0, 0, 0, newYork),
+		"at 9am":                time.Date(2016, 10, 20, 9, 0, 0, 0, newYork),
+		"tomorrow":              time.Date(2016, 10, 20, 9, 0, 0, 0, newYork),
+		"tomorrow at 9:30am":    time.Date(2016, 10, 20, 9, 30, 0, 0, newYork),
+		"at noon tomorrow":      time.Date(2016, 10, 20, 12, 0, 0, 0, newYork),
+		"friday at 4pm":         time.Date(2016, 10, 21, 16, 0, 0, 0, newYork),
+		"on friday":             time.Date(2016, 10, 21, 9, 0, 0, 0, newYork),
+		"wednesday at 4pm":      time.Date(2016, 10, 19, 16, 0, 0, 0, newYork),
+		"wednesday at 9am":      time.Date(2016, 10, 26, 9, 0, 0, 0, newYork),
+		"next wednesday at 4pm": time.Date(2016, 10, 26, 16, 0, 0, 0, newYork),
+		"on 2016-11-07 at noon": time.Date(2016, 11, 7, 12, 0, 0, 0, newYork),
+		"1/2":                   time.Date(2017, 1, 2, 9, 0, 0, 0, newYork),
+		"today at 5 pm":         time.Date(2016, 10, 19, 17, 0, 0, 0, newYork),
+	}
+	for text, expected := range cases {
+		actual, err := ParseWhen(text, now)
+		assert.Nil(err, text)
+		assert.True(expected.Equal(actual), text+" got "+actual.String())
+	}
+
+	for _, text := range []string{"", "whenever", "today at 9am", "tomorrow friday", "2016-01-01", "at 4pm at 5pm", "in a while"} {
+		_, err := ParseWhen(text, now)
+		assert.NotNil(err, text)
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.UTC, UserLocation(nil))
+	assert.Equal(time.UTC, UserLocation(&slack.User{}))
+	assert.Equal(time.UTC, UserLocation(&slack.User{TZ: "Not/AZone"}))
+	assert.Equal("America/Los_Angeles", UserLocation(&slack.User{TZ: "America/Los_Angeles"}).String())
+}
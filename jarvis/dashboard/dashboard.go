@@ -0,0 +1,297 @@
+package dashboard
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/blendlabs/go-web"
+	"github.com/wcharczuk/jarvis/jarvis/api"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/modules"
+)
+
+const (
+	// EnvironmentToken is the environment variable for the token used to log in to the dashboard.
+	EnvironmentToken = "JARVIS_DASHBOARD_TOKEN"
+
+	// Prefix is the path the dashboard is served under.
+	Prefix = "/dashboard"
+
+	// AuditUser is the user recorded in the audit log for changes made through the dashboard.
+	AuditUser = "dashboard"
+
+	// DefaultRecentLimit is the number of audit entries, errors and job runs shown per bot.
+	DefaultRecentLimit = 15
+
+	// dashboardUserID is the go-web session user id for logged in admins; the token is shared, so there's only one.
+	dashboardUserID = 1
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// New returns a new dashboard for the bots `bots` returns. Admins log in with `token`; the dashboard is disabled if it's empty.
+func New(bots func() []core.Bot, token string) *Dashboard {
+	return &Dashboard{bots: bots, token: token}
+}
+
+// Dashboard is a server rendered admin ui for the bots, showing their health, modules, jobs, recent commands and
+// errors, and letting admins load and unload modules, set config and run jobs.
+type Dashboard struct {
+	bots  func() []core.Bot
+	token string
+}
+
+// Handler returns the dashboard as an http handler.
+func (d *Dashboard) Handler() http.Handler {
+	app := web.New()
+	views := template.New("").Funcs(app.ViewCache().FuncMap()).Funcs(template.FuncMap{"csrf": csrfToken})
+	app.ViewCache().SetTemplates(template.Must(views.ParseFS(templateFiles, "templates/*.html")))
+	app.Auth().SetLoginRedirectHandler(func(from *url.URL) *url.URL {
+		return &url.URL{Path: Prefix + "/login"}
+	})
+	app.Register(d)
+	return app
+}
+
+// Register implements web.Controller.
+func (d *Dashboard) Register(app *web.App) {
+	app.GET(Prefix, d.index, web.SessionRequired, web.ViewProviderAsDefault)
+	app.GET(Prefix+"/login", d.login, web.SessionAware, web.ViewProviderAsDefault)
+	app.POST(Prefix+"/login", d.doLogin, web.ViewProviderAsDefault)
+	app.POST(Prefix+"/logout", d.logout, d.requireCSRF, web.SessionRequired, web.ViewProviderAsDefault)
+	app.GET(Prefix+"/bots/:bot", d.bot, web.SessionRequired, web.ViewProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/modules/:module/load", d.loadModule, d.requireCSRF, web.SessionRequired, web.ViewProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/modules/:module/unload", d.unloadModule, d.requireCSRF, web.SessionRequired, web.ViewProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/config", d.setConfig, d.requireCSRF, web.SessionRequired, web.ViewProviderAsDefault)
+	app.POST(Prefix+"/bots/:bot/jobs/:job/run", d.runJob, d.requireCSRF, web.SessionRequired, web.ViewProviderAsDefault)
+}
+
+type flash struct {
+	Message string
+	Error   string
+}
+
+type loginViewModel struct {
+	flash
+	Enabled bool
+}
+
+type botHealth struct {
+	api.BotSummary
+	LoadedModules int
+	Jobs          int
+	FailedJobs    int
+	Errors        int
+	LastError     *core.ErrorEntry
+}
+
+type indexViewModel struct {
+	Bots []botHealth
+}
+
+type moduleView struct {
+	Name   string
+	Loaded bool
+}
+
+type configEntry struct {
+	Key   string
+	Value string
+}
+
+type jobView struct {
+	api.JobStatus
+	Runs []core.JobRun
+}
+
+type botViewModel struct {
+	api.BotStatus
+	flash
+	Modules []moduleView
+	Options []configEntry
+	Jobs    []jobView
+	Audit   []core.AuditEntry
+	Errors  []core.ErrorEntry
+}
+
+func newBotHealth(b core.Bot) botHealth {
+	health := botHealth{
+		BotSummary:    api.NewBotSummary(b),
+		LoadedModules: b.LoadedModules().Len(),
+		Errors:        b.Errors().Len(),
+	}
+	for _, task := range b.JobManager().Status() {
+		health.Jobs++
+		if last := b.JobHistory().Last(task.Name); last != nil && last.Failed() {
+			health.FailedJobs++
+		}
+	}
+	if recent := b.Errors().Recent(1); len(recent) != 0 {
+		health.LastError = &recent[0]
+	}
+	return health
+}
+
+func newBotViewModel(b core.Bot, ctx *web.Ctx) botViewModel {
+	status := api.NewBotStatus(b)
+	viewModel := botViewModel{
+		BotStatus: status,
+		flash:     flash{Message: ctx.Request.URL.Query().Get("message"), Error: ctx.Request.URL.Query().Get("error")},
+		Audit:     b.AuditLog().Recent(DefaultRecentLimit),
+		Errors:    b.Errors().Recent(DefaultRecentLimit),
+	}
+	loaded := b.LoadedModules()
+	for _, name := range status.RegisteredModules {
+		viewModel.Modules = append(viewModel.Modules, moduleView{Name: name, Loaded: loaded.Contains(name)})
+	}
+	for key, value := range status.Configuration {
+		viewModel.Options = append(viewModel.Options, configEntry{Key: key, Value: value})
+	}
+	sort.Slice(viewModel.Options, func(i, j int) bool { return viewModel.Options[i].Key < viewModel.Options[j].Key })
+	for _, job := range status.Jobs {
+		runs := b.JobHistory().Runs(job.Name)
+		if len(runs) > DefaultRecentLimit {
+			runs = runs[:DefaultRecentLimit]
+		}
+		viewModel.Jobs = append(viewModel.Jobs, jobView{JobStatus: job, Runs: runs})
+	}
+	return viewModel
+}
+
+func (d *Dashboard) index(ctx *web.Ctx) web.Result {
+	viewModel := indexViewModel{Bots: []botHealth{}}
+	for _, b := range d.bots() {
+		viewModel.Bots = append(viewModel.Bots, newBotHealth(b))
+	}
+	return ctx.View().View("index", viewModel)
+}
+
+func (d *Dashboard) login(ctx *web.Ctx) web.Result {
+	if ctx.Session() != nil {
+		return ctx.RedirectWithMethodf("GET", Prefix)
+	}
+	return ctx.View().View("login", loginViewModel{Enabled: len(d.token) != 0})
+}
+
+func (d *Dashboard) doLogin(ctx *web.Ctx) web.Result {
+	token := ctx.Request.FormValue("token")
+	if len(d.token) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(d.token)) != 1 {
+		viewModel := loginViewModel{Enabled: len(d.token) != 0, flash: flash{Error: "that token isn't right."}}
+		result := ctx.View().View("login", viewModel).(*web.ViewResult)
+		result.StatusCode = http.StatusForbidden
+		return result
+	}
+	if _, err := ctx.Auth().Login(dashboardUserID, ctx); err != nil {
+		return ctx.View().InternalError(err)
+	}
+	return ctx.RedirectWithMethodf("GET", Prefix)
+}
+
+func (d *Dashboard) logout(ctx *web.Ctx) web.Result {
+	if err := ctx.Auth().Logout(ctx.Session(), ctx); err != nil {
+		return ctx.View().InternalError(err)
+	}
+	return ctx.RedirectWithMethodf("GET", Prefix+"/login")
+}
+
+func (d *Dashboard) bot(ctx *web.Ctx) web.Result {
+	b := d.findBot(ctx)
+	if b == nil {
+		return ctx.View().NotFound()
+	}
+	return ctx.View().View("bot", newBotViewModel(b, ctx))
+}
+
+func (d *Dashboard) loadModule(ctx *web.Ctx) web.Result {
+	return d.change(ctx, modules.ActionModuleLoad, "module", "loaded module `%s`", func(b core.Bot, name string) error {
+		return modules.LoadModule(b, name)
+	})
+}
+
+func (d *Dashboard) unloadModule(ctx *web.Ctx) web.Result {
+	return d.change(ctx, modules.ActionModuleUnload, "module", "unloaded module `%s`", func(b core.Bot, name string) error {
+		return modules.UnloadModule(b, name)
+	})
+}
+
+func (d *Dashboard) setConfig(ctx *web.Ctx) web.Result {
+	key := strings.TrimSpace(ctx.Request.FormValue("key"))
+	if len(key) == 0 {
+		return ctx.View().BadRequest("`key` is required")
+	}
+	value := ctx.Request.FormValue("value")
+	return d.record(ctx, modules.ActionConfigSet, modules.AuditConfigArguments(key, value), "set `"+key+"`", func(b core.Bot) error {
+		modules.SetConfig(b, key, value)
+		return nil
+	})
+}
+
+func (d *Dashboard) runJob(ctx *web.Ctx) web.Result {
+	return d.change(ctx, modules.ActionJobRun, "job", "started job `%s`", func(b core.Bot, name string) error {
+		return modules.RunJob(b, name)
+	})
+}
+
+// change runs a change against the bot in the route, with the `param` route parameter (if any), records it in the
+// bot's audit log, and redirects back to the bot's page with `message` (formatted with the parameter) or the error.
+func (d *Dashboard) change(ctx *web.Ctx, actionID, param, message string, action func(b core.Bot, name string) error) web.Result {
+	var name string
+	if len(param) != 0 {
+		name, _ = ctx.RouteParam(param)
+		message = strings.Replace(message, "%s", name, 1)
+	}
+	return d.record(ctx, actionID, name, message, func(b core.Bot) error { return action(b, name) })
+}
+
+// record runs a change against the bot in the route, records it in the bot's audit log with `arguments`, and
+// redirects back to the bot's page with `message` or the error.
+func (d *Dashboard) record(ctx *web.Ctx, actionID, arguments, message string, action func(b core.Bot) error) web.Result {
+	b := d.findBot(ctx)
+	if b == nil {
+		return ctx.View().NotFound()
+	}
+
+	query := url.Values{}
+	if err := b.AuditLog().Record(AuditUser, actionID, arguments, func() error { return action(b) }); err != nil {
+		query.Set("error", err.Error())
+	} else {
+		query.Set("message", message)
+	}
+	return ctx.RedirectWithMethodf("GET", "%s/bots/%s?%s", Prefix, url.PathEscape(b.ID()), query.Encode())
+}
+
+func (d *Dashboard) findBot(ctx *web.Ctx) core.Bot {
+	id, err := ctx.RouteParam("bot")
+	if err != nil {
+		return nil
+	}
+	return api.FindBot(d.bots(), id)
+}
+
+// requireCSRF rejects form posts that don't carry the session's csrf token, so other sites can't post on an admin's behalf.
+func (d *Dashboard) requireCSRF(action web.Action) web.Action {
+	return func(ctx *web.Ctx) web.Result {
+		expected := csrfToken(ctx)
+		if len(expected) == 0 || subtle.ConstantTimeCompare([]byte(ctx.Request.FormValue("csrf")), []byte(expected)) != 1 {
+			return ctx.View().NotAuthorized()
+		}
+		return action(ctx)
+	}
+}
+
+// csrfToken returns the csrf token for the request's session, or an empty string if there isn't one.
+func csrfToken(ctx *web.Ctx) string {
+	if ctx == nil || ctx.Session() == nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("csrf:" + ctx.Session().SessionID))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,163 @@
+package dashboard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-web"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/modules"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func testDashboard(assert *assert.Assertions) (*web.App, *core.MockBot) {
+	b := core.NewMockBot(slack.UUIDv4().ToShortString())
+	b.RegisterModule(new(modules.Util))
+	b.SetConfig("jira_credentials", "user:hunter2")
+
+	app, isApp := New(func() []core.Bot { return []core.Bot{b} }, "sekrit").Handler().(*web.App)
+	assert.True(isApp)
+	return app, b
+}
+
+func login(assert *assert.Assertions, app *web.App) *http.Cookie {
+	res, err := app.Mock().Post("/dashboard/login").WithFormValue("token", "sekrit").Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusFound, res.StatusCode)
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == web.DefaultSessionParamName {
+			return cookie
+		}
+	}
+	assert.FailNow("login didn't set a session cookie")
+	return nil
+}
+
+func body(assert *assert.Assertions, res *http.Response) string {
+	contents, err := ioutil.ReadAll(res.Body)
+	assert.Nil(err)
+	return string(contents)
+}
+
+func csrf(cookie *http.Cookie) string {
+	sum := sha256.Sum256([]byte("csrf:" + cookie.Value))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLogin(t *testing.T) {
+	assert := assert.New(t)
+	app, _ := testDashboard(assert)
+
+	res, err := app.Mock().Get("/dashboard").Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusTemporaryRedirect, res.StatusCode)
+	assert.Equal("/dashboard/login", res.Header.Get("Location"))
+
+	res, err = app.Mock().Post("/dashboard/login").WithFormValue("token", "not-sekrit").Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusForbidden, res.StatusCode)
+	assert.Contains("that token", body(assert, res))
+
+	cookie := login(assert, app)
+	res, err = app.Mock().Get("/dashboard").WithCookie(cookie).Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+}
+
+func TestBotPage(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testDashboard(assert)
+	cookie := login(assert, app)
+
+	res, err := app.Mock().Get("/dashboard").WithCookie(cookie).Response()
+	assert.Nil(err)
+	assert.Contains("/dashboard/bots/"+b.ID(), body(assert, res))
+
+	res, err = app.Mock().Get("/dashboard/bots/%s", b.ID()).WithCookie(cookie).Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, res.StatusCode)
+	contents := body(assert, res)
+	assert.Contains(modules.ModuleUtil, contents)
+	assert.Contains("jira_credentials", contents)
+	assert.Contains(html.EscapeString(modules.RedactedConfigValue), contents)
+	assert.Contains(csrf(cookie), contents)
+
+	res, err = app.Mock().Get("/dashboard/bots/not-a-bot").WithCookie(cookie).Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, res.StatusCode)
+}
+
+func TestChangesRequireCSRF(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testDashboard(assert)
+	cookie := login(assert, app)
+
+	res, err := app.Mock().Post("/dashboard/bots/%s/modules/%s/load", b.ID(), modules.ModuleUtil).WithCookie(cookie).Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusForbidden, res.StatusCode)
+	assert.False(b.LoadedModules().Contains(modules.ModuleUtil))
+	assert.Empty(b.AuditLog().Recent(0))
+
+	res, err = app.Mock().Post("/dashboard/bots/%s/modules/%s/load", b.ID(), modules.ModuleUtil).
+		WithCookie(cookie).WithFormValue("csrf", csrf(cookie)).Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusFound, res.StatusCode)
+	assert.Contains("message=", res.Header.Get("Location"))
+	assert.True(b.LoadedModules().Contains(modules.ModuleUtil))
+
+	entries := b.AuditLog().Recent(1)
+	assert.Len(entries, 1)
+	assert.Equal(AuditUser, entries[0].User)
+	assert.Equal(modules.ActionModuleLoad, entries[0].Action)
+	assert.Equal(modules.ModuleUtil, entries[0].Arguments)
+}
+
+func TestChangesWhileRendering(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testDashboard(assert)
+	cookie := login(assert, app)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := 0; x < 20; x++ {
+			app.Mock().Post("/dashboard/bots/%s/config", b.ID()).WithCookie(cookie).
+				WithFormValue("csrf", csrf(cookie)).WithFormValue("key", modules.ConfigOptionPassive).WithFormValue("value", "on").Response()
+			app.Mock().Post("/dashboard/bots/%s/modules/%s/load", b.ID(), modules.ModuleUtil).WithCookie(cookie).WithFormValue("csrf", csrf(cookie)).Response()
+			app.Mock().Post("/dashboard/bots/%s/modules/%s/unload", b.ID(), modules.ModuleUtil).WithCookie(cookie).WithFormValue("csrf", csrf(cookie)).Response()
+		}
+	}()
+
+	for x := 0; x < 20; x++ {
+		modules.SetConfig(b, "welcome.message", "hello")
+		res, err := app.Mock().Get("/dashboard/bots/%s", b.ID()).WithCookie(cookie).Response()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, res.StatusCode)
+	}
+	<-done
+	assert.Equal("true", b.Configuration()[modules.ConfigOptionPassive])
+}
+
+func TestSetConfig(t *testing.T) {
+	assert := assert.New(t)
+	app, b := testDashboard(assert)
+	cookie := login(assert, app)
+
+	res, err := app.Mock().Post("/dashboard/bots/%s/config", b.ID()).WithCookie(cookie).
+		WithFormValue("csrf", csrf(cookie)).WithFormValue("key", modules.ConfigOptionPassive).WithFormValue("value", "yes").Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusFound, res.StatusCode)
+	assert.Equal("true", b.Configuration()[modules.ConfigOptionPassive])
+	assert.Equal(modules.ConfigOptionPassive+" yes", b.AuditLog().Recent(1)[0].Arguments)
+
+	res, err = app.Mock().Post("/dashboard/bots/%s/jobs/%s/run", b.ID(), "not-a-job").WithCookie(cookie).
+		WithFormValue("csrf", csrf(cookie)).Response()
+	assert.Nil(err)
+	assert.Equal(http.StatusFound, res.StatusCode)
+	assert.Contains("error=", res.Header.Get("Location"))
+}
@@ -0,0 +1,92 @@
+package jarvis
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/modules"
+	"github.com/wcharczuk/jarvis/jarvis/slacktest"
+)
+
+// TestEndToEnd runs the scripts in `testdata/scripts` against a bot with all its modules, connected to a fake slack.
+func TestEndToEnd(t *testing.T) {
+	assert := assert.New(t)
+	paths, err := filepath.Glob("testdata/scripts/*.json")
+	assert.Nil(err)
+	assert.NotEmpty(paths)
+
+	for _, path := range paths {
+		script, err := slacktest.ReadScript(path)
+		assert.Nil(err, path)
+
+		s := endToEnd(t, func(s *slacktest.Server) {
+			assert.Nil(s.Run(script, 5*time.Second), path)
+		})
+		expected := 0
+		for _, step := range script {
+			if len(step.Expect) != 0 {
+				expected++
+			}
+		}
+		assert.Len(s.Messages(), expected, path+": the bot only replies when it's expected to")
+	}
+}
+
+func TestEndToEndReply(t *testing.T) {
+	assert := assert.New(t)
+	endToEnd(t, func(s *slacktest.Server) {
+		_, err := s.Say("U1", "C1", "<@UJARVIS> user <@U1>")
+		assert.Nil(err)
+		m, err := s.Expect("C1", "alice", 5*time.Second)
+		assert.Nil(err)
+		assert.Equal("chat.postMessage", m.Method)
+		assert.Len(s.Calls("chat.postMessage"), 1)
+	})
+}
+
+func TestEndToEndReplyMessageEdit(t *testing.T) {
+	assert := assert.New(t)
+	endToEnd(t, func(s *slacktest.Server) {
+		ts, err := s.Say("U1", "C1", "<@UJARVIS> time")
+		assert.Nil(err)
+		_, err = s.Expect("C1", "^$", 5*time.Second)
+		assert.Nil(err)
+		// give the bot a moment to note the reply before the edit arrives.
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Nil(s.Send(map[string]interface{}{
+			"type":             "message",
+			"subtype":          "message_changed",
+			"channel":          "C1",
+			"message":          map[string]string{"type": "message", "user": "U1", "text": "<@UJARVIS> user <@U1>", "ts": ts},
+			"previous_message": map[string]string{"type": "message", "user": "U1", "text": "<@UJARVIS> time", "ts": ts},
+		}))
+		m, err := s.Expect("C1", "alice", 5*time.Second)
+		assert.Nil(err)
+		assert.Equal("chat.update", m.Method, "replies with attachments are updated too")
+	})
+}
+
+// endToEnd starts a bot against a fake slack with two users and a channel, with its data in a temp dir, runs a test,
+// and stops the bot.
+func endToEnd(t *testing.T, test func(s *slacktest.Server)) *slacktest.Server {
+	assert := assert.New(t)
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddUser("U1", "alice")
+	s.AddUser("U2", "bob")
+	s.AddChannel("C1", "general")
+
+	b := NewBot("xoxb-test")
+	b.newClient = s.Client
+	b.SetConfig(modules.ConfigDataPath, t.TempDir())
+	assert.Nil(b.Init())
+	assert.Nil(b.Start())
+	defer b.Stop()
+	assert.Nil(s.WaitForConnection(5 * time.Second))
+
+	test(s)
+	return s
+}
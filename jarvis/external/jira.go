@@ -2,7 +2,6 @@ package external
 
 import (
 	"encoding/json"
-	"io/ioutil"
 
 	"github.com/blendlabs/go-exception"
 	"github.com/wcharczuk/jarvis/jarvis/core"
@@ -85,15 +84,35 @@ type JiraError struct {
 	ErrorMessages []string
 }
 
+// JiraIssueCreate is the body of a request to create a JIRA issue.
+type JiraIssueCreate struct {
+	Fields JiraIssueCreateFields `json:"fields"`
+}
+
+// JiraIssueCreateFields are the fields of a JIRA issue to create.
+type JiraIssueCreateFields struct {
+	Project     JiraProjectReference   `json:"project"`
+	Summary     string                 `json:"summary"`
+	Description string                 `json:"description"`
+	IssueType   JiraIssueTypeReference `json:"issuetype"`
+}
+
+// JiraProjectReference references a JIRA project by key.
+type JiraProjectReference struct {
+	Key string `json:"key"`
+}
+
+// JiraIssueTypeReference references a JIRA issue type by name.
+type JiraIssueTypeReference struct {
+	Name string `json:"name"`
+}
+
 // GetJiraIssue gets the metadata for a given issueID.
 func GetJiraIssue(user, password, host, issueID string) (*JiraIssue, error) {
-	res, err := core.NewExternalRequest().AsGet().WithBasicAuth(user, password).WithScheme("https").WithHost(host).WithPathf("rest/api/2/issue/%s", issueID).Fetch()
+	body, err := core.NewExternalRequest().AsGet().WithBasicAuth(user, password).WithScheme("https").WithHost(host).WithPathf("rest/api/2/issue/%s", issueID).Bytes()
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-
-	body, err := ioutil.ReadAll(res.Body)
 
 	var je JiraError
 	err = json.Unmarshal(body, &je)
@@ -105,3 +124,35 @@ func GetJiraIssue(user, password, host, issueID string) (*JiraIssue, error) {
 	err = json.Unmarshal(body, &ji)
 	return &ji, err
 }
+
+// CreateJiraIssue creates a task in a given project, returning the new issue (which only has its id, key and self link set).
+func CreateJiraIssue(user, password, host, projectKey, summary, description string) (*JiraIssue, error) {
+	create := JiraIssueCreate{
+		Fields: JiraIssueCreateFields{
+			Project:     JiraProjectReference{Key: projectKey},
+			Summary:     summary,
+			Description: description,
+			IssueType:   JiraIssueTypeReference{Name: "Task"},
+		},
+	}
+	body, err := core.NewExternalRequest().AsPost().WithBasicAuth(user, password).WithScheme("https").WithHost(host).WithPath("rest/api/2/issue").WithPostBodyAsJSON(create).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var je JiraError
+	err = json.Unmarshal(body, &je)
+	if err == nil && len(je.ErrorMessages) != 0 {
+		return nil, exception.Newf("Errors returned from jira: %s\n", je.ErrorMessages[0])
+	}
+
+	var ji JiraIssue
+	err = json.Unmarshal(body, &ji)
+	if err != nil {
+		return nil, err
+	}
+	if len(ji.Key) == 0 {
+		return nil, exception.Newf("Jira didn't create the issue: %s", string(body))
+	}
+	return &ji, nil
+}
@@ -0,0 +1,43 @@
+package external
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+)
+
+func TestGetJiraIssue(t *testing.T) {
+	assert := assert.New(t)
+	defer core.ClearMockedResponses()
+
+	core.MockResponseFromFile("GET", "https://example.atlassian.net/rest/api/2/issue/OPS-2", http.StatusOK, "testdata/jira_issue.json")
+	core.MockResponseFromFile("GET", "https://example.atlassian.net/rest/api/2/issue/OPS-404", http.StatusNotFound, "testdata/jira_error.json")
+
+	issue, err := GetJiraIssue("jarvis", "sekrit", "example.atlassian.net", "OPS-2")
+	assert.Nil(err)
+	assert.Equal("OPS-2", issue.Key)
+	assert.Equal("Rotate the deploy keys", issue.Fields.Summary)
+	assert.Equal("Alice", issue.Fields.Assignee.DisplayName)
+	assert.Equal("In Progress", issue.Fields.Status.Name)
+
+	_, err = GetJiraIssue("jarvis", "sekrit", "example.atlassian.net", "OPS-404")
+	assert.NotNil(err)
+	assert.Contains("Issue does not exist", err.Error())
+}
+
+func TestCreateJiraIssue(t *testing.T) {
+	assert := assert.New(t)
+	defer core.ClearMockedResponses()
+
+	core.MockResponseFromFile("POST", "https://example.atlassian.net/rest/api/2/issue", http.StatusCreated, "testdata/jira_issue_created.json")
+
+	issue, err := CreateJiraIssue("jarvis", "sekrit", "example.atlassian.net", "OPS", "Rotate the deploy keys", "")
+	assert.Nil(err)
+	assert.Equal("OPS-3", issue.Key)
+
+	requests := core.MockedRequests()
+	assert.Len(requests, 1)
+	assert.Equal("POST", requests[0].Verb)
+}
@@ -1,9 +1,11 @@
 package external
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
 )
 
 func TestGenerateStockInfoFormat(t *testing.T) {
@@ -33,3 +35,23 @@ func TestStockInfoParseInvalid(t *testing.T) {
 	assert.Nil(err)
 	assert.True(stock.IsZero(), stock.String())
 }
+
+func TestStockPrice(t *testing.T) {
+	assert := assert.New(t)
+	defer core.ClearMockedResponses()
+
+	core.MockResponseFromFile("GET", "http://download.finance.yahoo.com/d/quotes.csv", http.StatusOK, "testdata/yahoo_quotes.csv")
+
+	stocks, err := StockPrice([]string{"goog", "balls"})
+	assert.Nil(err)
+	assert.Len(stocks, 1, "unknown tickers are skipped")
+	assert.Equal("goog", stocks[0].Ticker)
+
+	requests := core.MockedRequests()
+	assert.Len(requests, 1)
+	assert.Contains("s=goog%2Bballs", requests[0].URL)
+
+	core.MockResponseFromString("GET", "http://download.finance.yahoo.com/d/quotes.csv", http.StatusServiceUnavailable, "")
+	_, err = StockPrice([]string{"goog"})
+	assert.NotNil(err)
+}
@@ -2,8 +2,8 @@ package jobs
 
 import (
 	"github.com/blendlabs/go-chronometer"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 // NewClock returns a new clock job instance.
@@ -23,8 +23,7 @@ func (t Clock) Name() string {
 
 // Execute is the actual code that runs when the job is fired.
 func (t Clock) Execute(ct *chronometer.CancellationToken) error {
-	for x := 0; x < len(t.Bot.ActiveChannels()); x++ {
-		channelID := t.Bot.ActiveChannels()[x]
+	for _, channelID := range t.Bot.JobOutputChannels(t.Name()) {
 		err := t.Bot.TriggerAction("time", &slack.Message{Channel: channelID})
 		if err != nil {
 			return err
@@ -33,7 +32,7 @@ func (t Clock) Execute(ct *chronometer.CancellationToken) error {
 	return nil
 }
 
-// Schedule returns the job schedule.
+// Schedule returns the job schedule, which defaults to on the hour.
 func (t Clock) Schedule() chronometer.Schedule {
-	return chronometer.OnTheHour{}
+	return ScheduleFor(t.Bot, t.Name(), chronometer.OnTheHour{})
 }
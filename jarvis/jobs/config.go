@@ -0,0 +1,18 @@
+package jobs
+
+const (
+	// ConfigJobSchedule is the config entry format for a cron expression that overrides a job's schedule, keyed by job name.
+	ConfigJobSchedule = "job.%s.schedule"
+
+	// ConfigJobScheduleExpr matches a job schedule config entry, capturing the job name.
+	ConfigJobScheduleExpr = `^job\.(.+)\.schedule$`
+
+	// ConfigJobChannels is the config entry format for the comma separated channels a job posts its output to, keyed by job name.
+	ConfigJobChannels = "job.%s.channels"
+
+	// ConfigJobOwner is the config entry format for the channel a job's failures are reported to, keyed by job name.
+	ConfigJobOwner = "job.%s.owner"
+
+	// ConfigJobsOwner is the config entry for the channel failures are reported to for jobs without an owner.
+	ConfigJobsOwner = "jobs.owner"
+)
@@ -0,0 +1,368 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+)
+
+const (
+	// cronMaxSearchDays is how far ahead we look for a matching day before giving up (covers leap day + weekday combinations).
+	cronMaxSearchDays = 366 * 28
+)
+
+var (
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+
+	cronDayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+type cronBounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronSeconds     = cronBounds{0, 59, nil}
+	cronMinutes     = cronBounds{0, 59, nil}
+	cronHours       = cronBounds{0, 23, nil}
+	cronDaysOfMonth = cronBounds{1, 31, nil}
+	cronMonths      = cronBounds{1, 12, cronMonthNames}
+	cronDaysOfWeek  = cronBounds{0, 7, cronDayNames}
+)
+
+// cronAllHours is the hours field with every hour set, as from `*`, `?`, `*/1` or `0-23`.
+const cronAllHours uint64 = 1<<24 - 1
+
+// MustParseCron parses a cron expression and panics if it is invalid.
+func MustParseCron(expr string) *CronSchedule {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// ParseCron parses a cron expression into a schedule.
+//
+// Both the standard 5 field form (minute hour day-of-month month day-of-week) and the
+// 6 field form with a leading seconds field are supported, as are the `@hourly`, `@daily`,
+// `@midnight`, `@weekly`, `@monthly`, `@yearly` and `@annually` macros.
+// Fields accept `*`, `?`, lists (`1,2`), ranges (`1-5`), steps (`*/15`, `9-17/2`) and
+// month / weekday names (`JAN`, `MON-FRI`). An optional `CRON_TZ=<zone>` (or `TZ=<zone>`)
+// prefix sets the time zone the expression is evaluated in; the default is UTC.
+func ParseCron(expr string) (*CronSchedule, error) {
+	cs := &CronSchedule{Expression: expr, Location: time.UTC}
+
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		pieces := strings.SplitN(spec, " ", 2)
+		zone := pieces[0][strings.Index(pieces[0], "=")+1:]
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, exception.Newf("invalid cron time zone `%s`", zone)
+		}
+		cs.Location = location
+		if len(pieces) < 2 {
+			return nil, exception.Newf("cron expression `%s` is empty", expr)
+		}
+		spec = strings.TrimSpace(pieces[1])
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		macro, hasMacro := cronMacros[strings.ToLower(spec)]
+		if !hasMacro {
+			return nil, exception.Newf("unknown cron macro `%s`", spec)
+		}
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, exception.Newf("cron expression `%s` must have 5 or 6 fields, has %d", expr, len(fields))
+	}
+
+	var err error
+	if cs.seconds, err = parseCronField(fields[0], cronSeconds); err != nil {
+		return nil, err
+	}
+	if cs.minutes, err = parseCronField(fields[1], cronMinutes); err != nil {
+		return nil, err
+	}
+	if cs.hours, err = parseCronField(fields[2], cronHours); err != nil {
+		return nil, err
+	}
+	if cs.daysOfMonth, err = parseCronField(fields[3], cronDaysOfMonth); err != nil {
+		return nil, err
+	}
+	if cs.months, err = parseCronField(fields[4], cronMonths); err != nil {
+		return nil, err
+	}
+	if cs.daysOfWeek, err = parseCronField(fields[5], cronDaysOfWeek); err != nil {
+		return nil, err
+	}
+	// sunday can be written as either 0 or 7.
+	if cs.daysOfWeek&(1<<7) != 0 {
+		cs.daysOfWeek = cs.daysOfWeek | 1
+	}
+
+	cs.hoursWildcard = cs.hours == cronAllHours
+	cs.daysOfMonthWildcard = isCronWildcard(fields[3])
+	cs.daysOfWeekWildcard = isCronWildcard(fields[5])
+	return cs, nil
+}
+
+// isCronWildcard returns if a day field starts with `*` or `?`, which (like `*/2`) makes the day of month and day of
+// week both have to match, rather than either.
+func isCronWildcard(field string) bool {
+	return strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?")
+}
+
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		if len(part) == 0 {
+			return 0, exception.Newf("invalid cron field `%s`", field)
+		}
+
+		rangePart := part
+		step := 1
+		hasStep := false
+		if index := strings.Index(part, "/"); index >= 0 {
+			rangePart = part[:index]
+			parsedStep, err := strconv.Atoi(part[index+1:])
+			if err != nil || parsedStep < 1 {
+				return 0, exception.Newf("invalid cron step in `%s`", part)
+			}
+			step = parsedStep
+			hasStep = true
+		}
+
+		var start, end int
+		var err error
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			start, end = bounds.min, bounds.max
+		case strings.Contains(rangePart, "-"):
+			rangePieces := strings.SplitN(rangePart, "-", 2)
+			if start, err = parseCronValue(rangePieces[0], bounds); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(rangePieces[1], bounds); err != nil {
+				return 0, err
+			}
+		default:
+			if start, err = parseCronValue(rangePart, bounds); err != nil {
+				return 0, err
+			}
+			end = start
+			if hasStep {
+				end = bounds.max
+			}
+		}
+
+		if start > end {
+			return 0, exception.Newf("invalid cron range `%s`", rangePart)
+		}
+		for value := start; value <= end; value += step {
+			bits = bits | 1<<uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, bounds cronBounds) (int, error) {
+	if bounds.names != nil {
+		if named, hasName := bounds.names[strings.ToLower(value)]; hasName {
+			return named, nil
+		}
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, exception.Newf("invalid cron value `%s`", value)
+	}
+	if parsed < bounds.min || parsed > bounds.max {
+		return 0, exception.Newf("cron value `%d` is out of range (%d-%d)", parsed, bounds.min, bounds.max)
+	}
+	return parsed, nil
+}
+
+// CronSchedule is a chronometer.Schedule that fires according to a cron expression.
+// Use `ParseCron` to create one.
+type CronSchedule struct {
+	Expression string
+	Location   *time.Location
+
+	seconds     uint64
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	hoursWildcard       bool
+	daysOfMonthWildcard bool
+	daysOfWeekWildcard  bool
+}
+
+// String returns the cron expression.
+func (cs *CronSchedule) String() string {
+	return cs.Expression
+}
+
+// GetNextRunTime implements chronometer.Schedule.
+//
+// Matching is done against the wall clock in the schedule's location. A wall clock time that
+// is skipped when clocks go forward fires at the moment the clocks were set forward to, and a
+// time that is repeated when clocks go back fires once, unless the schedule runs every hour,
+// in which case it fires for both occurrences.
+func (cs *CronSchedule) GetNextRunTime(after *time.Time) *time.Time {
+	var from time.Time
+	if after == nil {
+		from = chronometer.Now()
+	} else {
+		from = *after
+	}
+
+	local := from.In(cs.location())
+	firstDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	for dayOffset := 0; dayOffset < cronMaxSearchDays; dayOffset++ {
+		day := firstDay.AddDate(0, 0, dayOffset)
+		if !cs.matchesDay(day) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !hasCronBit(cs.hours, hour) {
+				continue
+			}
+			// clock changes move the wall clock by at most a couple hours, so we can skip well before `from`.
+			if dayOffset == 0 && hour < local.Hour()-2 {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if !hasCronBit(cs.minutes, minute) {
+					continue
+				}
+				for second := 0; second < 60; second++ {
+					if !hasCronBit(cs.seconds, second) {
+						continue
+					}
+					for _, instant := range cs.instants(day, hour, minute, second) {
+						if instant.After(from) {
+							next := instant.UTC()
+							return &next
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (cs *CronSchedule) location() *time.Location {
+	if cs.Location == nil {
+		return time.UTC
+	}
+	return cs.Location
+}
+
+func (cs *CronSchedule) matchesDay(day time.Time) bool {
+	if !hasCronBit(cs.months, int(day.Month())) {
+		return false
+	}
+	domMatch := hasCronBit(cs.daysOfMonth, day.Day())
+	dowMatch := hasCronBit(cs.daysOfWeek, int(day.Weekday()))
+	if cs.daysOfMonthWildcard || cs.daysOfWeekWildcard {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// instants returns the moments, in order, that a wall clock time occurs on a given day in the schedule location.
+func (cs *CronSchedule) instants(day time.Time, hour, minute, second int) []time.Time {
+	location := cs.location()
+	wanted := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, time.UTC)
+	instant := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, location)
+
+	if actual := wallClock(instant); !actual.Equal(wanted) {
+		// the wall clock time was skipped; the wildcard hours will fire after the gap on their own.
+		if cs.hoursWildcard {
+			return nil
+		}
+		shifted := instant.Add(wanted.Sub(actual))
+		if shifted.After(instant) {
+			return []time.Time{shifted}
+		}
+		return []time.Time{instant}
+	}
+
+	_, offsetBefore := instant.Add(-3 * time.Hour).Zone()
+	_, offsetAfter := instant.Add(3 * time.Hour).Zone()
+	if offsetBefore == offsetAfter {
+		return []time.Time{instant}
+	}
+
+	shift := time.Duration(offsetBefore-offsetAfter) * time.Second
+	results := []time.Time{instant}
+	for _, candidate := range []time.Time{instant.Add(-shift), instant.Add(shift)} {
+		if wallClock(candidate).Equal(wanted) && !candidate.Equal(instant) {
+			if candidate.Before(instant) {
+				results = []time.Time{candidate, instant}
+			} else {
+				results = append(results, candidate)
+			}
+		}
+	}
+	if !cs.hoursWildcard {
+		return results[:1]
+	}
+	return results
+}
+
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+func hasCronBit(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
+
+// ScheduleFor returns the schedule configured for a job with the `job.<name>.schedule` config entry,
+// or the default schedule if there is no entry or it is invalid.
+func ScheduleFor(b core.Bot, jobName string, defaultSchedule chronometer.Schedule) chronometer.Schedule {
+	expr, hasExpr := b.Configuration()[fmt.Sprintf(ConfigJobSchedule, jobName)]
+	if !hasExpr || len(strings.TrimSpace(expr)) == 0 {
+		return defaultSchedule
+	}
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		b.Logf("invalid schedule for job `%s`: %v", jobName, err)
+		return defaultSchedule
+	}
+	return schedule
+}
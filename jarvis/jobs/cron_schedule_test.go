@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-chronometer"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	a := assert.New(t)
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * FOO *",
+		"@fortnightly",
+		"CRON_TZ=Not/AZone * * * * *",
+	}
+
+	for _, expr := range invalid {
+		_, err := ParseCron(expr)
+		a.NotNil(err, expr)
+	}
+}
+
+func TestCronScheduleGetNextRunTime(t *testing.T) {
+	a := assert.New(t)
+
+	cases := []struct {
+		expr     string
+		after    string
+		expected string
+	}{
+		{"* * * * *", "2016-01-01T11:46:30Z", "2016-01-01T11:47:00Z"},
+		{"*/15 * * * *", "2016-01-01T11:46:00Z", "2016-01-01T12:00:00Z"},
+		{"*/15 * * * *", "2016-01-01T11:30:00Z", "2016-01-01T11:45:00Z"},
+		{"*/15 9-17 * * MON-FRI", "2016-01-01T17:46:00Z", "2016-01-04T09:00:00Z"},
+		{"*/15 9-17 * * 1-5", "2016-01-04T08:59:59Z", "2016-01-04T09:00:00Z"},
+		{"0 9 * * SUN", "2016-01-01T00:00:00Z", "2016-01-03T09:00:00Z"},
+		{"0 9 * * 7", "2016-01-01T00:00:00Z", "2016-01-03T09:00:00Z"},
+		{"30 4 1,15 * *", "2016-01-02T00:00:00Z", "2016-01-15T04:30:00Z"},
+		{"0 0 1 * 1", "2016-01-02T00:00:00Z", "2016-01-04T00:00:00Z"},
+		{"0 0 29 FEB *", "2016-03-01T00:00:00Z", "2020-02-29T00:00:00Z"},
+		{"0 12 * JUN-AUG *", "2016-01-01T00:00:00Z", "2016-06-01T12:00:00Z"},
+		{"*/10 * * * * *", "2016-01-01T11:46:31Z", "2016-01-01T11:46:40Z"},
+		{"15 30 6 * * *", "2016-01-01T06:30:15Z", "2016-01-02T06:30:15Z"},
+		{"0 0-10/5 * * *", "2016-01-01T05:00:00Z", "2016-01-01T10:00:00Z"},
+		{"@hourly", "2016-01-01T11:46:00Z", "2016-01-01T12:00:00Z"},
+		{"@daily", "2016-01-01T11:46:00Z", "2016-01-02T00:00:00Z"},
+		{"@weekly", "2016-01-01T11:46:00Z", "2016-01-03T00:00:00Z"},
+		{"@monthly", "2016-01-01T11:46:00Z", "2016-02-01T00:00:00Z"},
+		{"@yearly", "2016-01-01T11:46:00Z", "2017-01-01T00:00:00Z"},
+		{"CRON_TZ=America/New_York 0 9 * * *", "2016-01-01T00:00:00Z", "2016-01-01T14:00:00Z"},
+		{"TZ=Asia/Tokyo 0 9 * * *", "2016-01-01T00:00:00Z", "2016-01-02T00:00:00Z"},
+
+		// spring forward; 02:30 doesn't exist and fires at 03:30 EDT instead.
+		{"CRON_TZ=America/New_York 30 2 * * *", "2016-03-13T05:00:00Z", "2016-03-13T07:30:00Z"},
+		{"CRON_TZ=America/New_York 30 2 * * *", "2016-03-13T07:30:00Z", "2016-03-14T06:30:00Z"},
+		{"CRON_TZ=America/New_York 0 * * * *", "2016-03-13T06:00:00Z", "2016-03-13T07:00:00Z"},
+		{"CRON_TZ=America/New_York 0 9 * * *", "2016-03-12T14:00:00Z", "2016-03-13T13:00:00Z"},
+		{"CRON_TZ=America/New_York 30 */2 * * *", "2016-03-13T06:00:00Z", "2016-03-13T07:30:00Z"},
+
+		// fall back; 01:30 happens twice but fires once, unless the hour is a wildcard.
+		{"CRON_TZ=America/New_York 30 1 * * *", "2016-11-06T04:00:00Z", "2016-11-06T05:30:00Z"},
+		{"CRON_TZ=America/New_York 30 1 * * *", "2016-11-06T05:30:00Z", "2016-11-07T06:30:00Z"},
+		{"CRON_TZ=America/New_York 30 * * * *", "2016-11-06T05:30:00Z", "2016-11-06T06:30:00Z"},
+		{"CRON_TZ=America/New_York 30 * * * *", "2016-11-06T06:30:00Z", "2016-11-06T07:30:00Z"},
+		{"CRON_TZ=America/New_York 30 1-23/2 * * *", "2016-11-06T05:30:00Z", "2016-11-06T08:30:00Z"},
+	}
+
+	for _, c := range cases {
+		schedule, err := ParseCron(c.expr)
+		a.Nil(err, c.expr)
+
+		after, err := time.Parse(time.RFC3339, c.after)
+		a.Nil(err)
+		expected, err := time.Parse(time.RFC3339, c.expected)
+		a.Nil(err)
+
+		next := schedule.GetNextRunTime(&after)
+		a.NotNil(next, c.expr)
+		a.True(expected.Equal(*next), c.expr+" after "+c.after+" expected "+c.expected+" got "+next.Format(time.RFC3339))
+	}
+}
+
+func TestCronScheduleGetNextRunTimeNil(t *testing.T) {
+	a := assert.New(t)
+
+	schedule := MustParseCron("* * * * * *")
+	now := chronometer.Now()
+	next := schedule.GetNextRunTime(nil)
+	a.NotNil(next)
+	a.True(next.After(now))
+	a.True(next.Sub(now) <= time.Second)
+}
+
+func TestCronScheduleNeverMatches(t *testing.T) {
+	a := assert.New(t)
+
+	schedule := MustParseCron("0 0 31 FEB *")
+	after := time.Date(2016, 01, 01, 0, 0, 0, 0, time.UTC)
+	a.Nil(schedule.GetNextRunTime(&after))
+}
+
+func TestScheduleFor(t *testing.T) {
+	a := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	clock := NewClock(mb)
+	_, isDefault := clock.Schedule().(chronometer.OnTheHour)
+	a.True(isDefault)
+
+	mb.SetConfig("job.clock.schedule", "*/15 9-17 * * MON-FRI")
+	schedule, isCron := clock.Schedule().(*CronSchedule)
+	a.True(isCron)
+	a.Equal("*/15 9-17 * * MON-FRI", schedule.String())
+
+	mb.SetConfig("job.clock.schedule", "not a schedule")
+	_, isDefault = clock.Schedule().(chronometer.OnTheHour)
+	a.True(isDefault)
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+)
+
+const (
+	// JobDirectorySync is the name of the directory sync job.
+	JobDirectorySync = "directory"
+)
+
+// NewDirectorySync returns a new directory sync job instance.
+func NewDirectorySync(b core.Bot) *DirectorySync {
+	return &DirectorySync{Bot: b}
+}
+
+// DirectorySync is a job that reconciles the bot's user and channel directory with slack,
+// in case it missed any events (e.g. while it was disconnected).
+type DirectorySync struct {
+	Bot core.Bot
+}
+
+// Name returns the name of the chronometer job.
+func (ds DirectorySync) Name() string {
+	return JobDirectorySync
+}
+
+// Execute fetches the users and channels from slack.
+func (ds DirectorySync) Execute(ct *chronometer.CancellationToken) error {
+	users, err := ds.Bot.Client().UsersList()
+	if err != nil {
+		return err
+	}
+	channels, err := ds.Bot.Client().ChannelsList(false)
+	if err != nil {
+		return err
+	}
+	ds.Bot.Directory().SetUsers(users)
+	ds.Bot.Directory().SetChannels(channels)
+	return nil
+}
+
+// Schedule returns the job schedule, which is every hour by default.
+func (ds DirectorySync) Schedule() chronometer.Schedule {
+	return ScheduleFor(ds.Bot, JobDirectorySync, chronometer.Every(time.Hour))
+}
+
+// ShowMessages disables the job manager's start and complete messages.
+func (ds DirectorySync) ShowMessages() bool {
+	return false
+}
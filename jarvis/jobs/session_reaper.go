@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+)
+
+// NewSessionReaper returns a new session reaper job instance.
+func NewSessionReaper(b core.Bot) *SessionReaper {
+	return &SessionReaper{Bot: b}
+}
+
+// SessionReaper is a job that expires sessions (and conversations) that have timed out waiting for a reply.
+type SessionReaper struct {
+	Bot core.Bot
+}
+
+// Name returns the name of the chronometer job.
+func (sr SessionReaper) Name() string {
+	return "sessions"
+}
+
+// Execute expires the timed out sessions.
+func (sr SessionReaper) Execute(ct *chronometer.CancellationToken) error {
+	sr.Bot.Sessions().Expire()
+	return nil
+}
+
+// Schedule returns the job schedule, which is every 15 seconds.
+func (sr SessionReaper) Schedule() chronometer.Schedule {
+	return chronometer.Every(15 * time.Second)
+}
+
+// ShowMessages disables the job manager's start and complete messages, as the job runs constantly.
+func (sr SessionReaper) ShowMessages() bool {
+	return false
+}
@@ -0,0 +1,101 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/blendlabs/go-util"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// ModuleAudit is the name of the audit module.
+	ModuleAudit = "audit"
+
+	// ActionAuditRecent is the recent audit entries action id.
+	ActionAuditRecent = "audit.recent"
+
+	// ActionAuditUser is the audit entries for a user action id.
+	ActionAuditUser = "audit.user"
+
+	// ConfigAuditPath is the config entry for the JSON-lines file the audit log is written to;
+	// it defaults to `audit.jsonl` in the data path.
+	ConfigAuditPath = "audit.path"
+
+	// DefaultAuditQueryLimit is the number of entries the audit actions print.
+	DefaultAuditQueryLimit = 15
+
+	auditFile = "audit.jsonl"
+)
+
+// Audit is the module that writes the bot's audit log to disk and lets people query it.
+type Audit struct{}
+
+// Init sets the audit log output file.
+func (a *Audit) Init(b core.Bot) error {
+	path := b.Configuration()[ConfigAuditPath]
+	if core.IsEmpty(path) {
+		path = DataFilePath(b, auditFile)
+	}
+	return b.AuditLog().SetOutput(path)
+}
+
+// Name returns the name of the module.
+func (a *Audit) Name() string {
+	return ModuleAudit
+}
+
+// Actions returns the actions for the module.
+func (a *Audit) Actions() []core.Action {
+	return []core.Action{
+		{ID: ActionAuditRecent, MessagePattern: "^audit:recent", Description: "Prints the most recent commands people ran.", Handler: a.handleAuditRecent, ReplyInThread: true},
+		{ID: ActionAuditUser, MessagePattern: "^audit:user", Description: "Prints the most recent commands a user ran.", Handler: a.handleAuditUser, ReplyInThread: true},
+	}
+}
+
+func (a *Audit) handleAuditRecent(b core.Bot, m *slack.Message) error {
+	entries := b.AuditLog().Recent(DefaultAuditQueryLimit)
+	if len(entries) == 0 {
+		return b.Reply(m, "the audit log is empty.")
+	}
+	return b.Replyf(m, "most recent commands:\n%s", formatAuditEntries(entries))
+}
+
+func (a *Audit) handleAuditUser(b core.Bot, m *slack.Message) error {
+	messageText := util.String.TrimWhitespace(core.LessSpecificMention(m.Text, b.ID()))
+	var user *slack.User
+	if mentions := core.Mentions(messageText); len(mentions) != 0 {
+		user = b.FindUser(mentions[0])
+	} else if pieces := strings.Fields(messageText); len(pieces) > 1 {
+		user = b.Directory().UserByName(pieces[1])
+	}
+	if user == nil {
+		return exception.New("usage: `audit:user @user`")
+	}
+
+	entries := b.AuditLog().ByUser(user.ID, DefaultAuditQueryLimit)
+	if len(entries) == 0 {
+		return b.Replyf(m, "<@%s> hasn't run any commands.", user.ID)
+	}
+	return b.Replyf(m, "most recent commands by <@%s>:\n%s", user.ID, formatAuditEntries(entries))
+}
+
+// formatAuditEntries returns the description of audit entries, one per line.
+func formatAuditEntries(entries []core.AuditEntry) string {
+	lines := []string{}
+	for _, entry := range entries {
+		line := fmt.Sprintf(">%s <@%s> in <#%s> `%s`", entry.Time.Format(time.Stamp), entry.User, entry.Channel, entry.Action)
+		if len(entry.Arguments) != 0 {
+			line = line + fmt.Sprintf(" %s", entry.Arguments)
+		}
+		line = line + fmt.Sprintf(" - %s", entry.Result)
+		if len(entry.Error) != 0 {
+			line = line + fmt.Sprintf(" (%s)", entry.Error)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+package modules
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestAudit(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigDataPath, dir)
+	mb.Directory().SetUsers([]slack.User{{ID: "U01", Name: "alice"}, {ID: "U02", Name: "bob"}})
+	defer mb.AuditLog().Close()
+
+	var said []string
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		said = append(said, m.Text)
+		return nil
+	})
+
+	a := &Audit{}
+	assert.Nil(a.Init(mb))
+	_, err = os.Stat(DataFilePath(mb, auditFile))
+	assert.Nil(err)
+
+	assert.Nil(a.handleAuditRecent(mb, &slack.Message{Channel: "C01", User: "U01", Text: "audit:recent"}))
+	assert.Equal("the audit log is empty.", said[0])
+
+	assert.Nil(mb.AuditLog().Add(core.AuditEntry{User: "U01", Channel: "C01", Action: ActionConfigSet, Arguments: "foo bar", Result: core.AuditResultSuccess}))
+	assert.Nil(mb.AuditLog().Add(core.AuditEntry{User: "U02", Channel: "C01", Action: ActionModuleUnload, Arguments: "jira", Result: core.AuditResultDenied, Error: "admins only"}))
+
+	said = nil
+	assert.Nil(a.handleAuditRecent(mb, &slack.Message{Channel: "C01", User: "U01", Text: "audit:recent"}))
+	assert.Contains("`config.set` foo bar - success", said[0])
+	assert.Contains("`module.unload` jira - denied (admins only)", said[0])
+
+	said = nil
+	assert.Nil(a.handleAuditUser(mb, &slack.Message{Channel: "C01", User: "U01", Text: "audit:user <@U02>"}))
+	assert.Contains("module.unload", said[0])
+	assert.False(strings.Contains(said[0], "config.set"))
+
+	said = nil
+	assert.Nil(a.handleAuditUser(mb, &slack.Message{Channel: "C01", User: "U02", Text: "audit:user @alice"}))
+	assert.Contains("config.set", said[0])
+
+	assert.NotNil(a.handleAuditUser(mb, &slack.Message{Channel: "C01", User: "U02", Text: "audit:user"}))
+}
@@ -2,18 +2,28 @@ package modules
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/blendlabs/go-exception"
 	"github.com/blendlabs/go-util"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/jobs"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
 	// EnvironmentModules is the modules environment variable entry.
 	EnvironmentModules = "MODULES"
 
+	// EnvironmentDataPath is the environment variable for the directory modules persist data to.
+	EnvironmentDataPath = "DATA_PATH"
+
+	// ConfigDataPath is the config entry for the directory modules persist data to.
+	ConfigDataPath = "data.path"
+
 	// ConfigModules is the modules config entry.
 	ConfigModules = "modules"
 
@@ -23,6 +33,42 @@ const (
 	// ConfigOptionPassiveCatchAll is a user configurable option to enable or disable passive command processing.
 	ConfigOptionPassiveCatchAll = "option.passive.catch_all"
 
+	// ConfigRateLimitUser is the config entry for how many commands a user can run, e.g. `10/1m` (or `off`).
+	ConfigRateLimitUser = "ratelimit.user"
+
+	// ConfigRateLimitChannel is the config entry for how many commands can be run in a channel, e.g. `30/1m` (or `off`).
+	ConfigRateLimitChannel = "ratelimit.channel"
+
+	// ConfigRateLimitAction is the config entry for how many times any one action can be run, e.g. `30/1m` (or `off`).
+	ConfigRateLimitAction = "ratelimit.action"
+
+	// ConfigRateLimitActionFormat is the config entry format for the rate limit of a specific action, keyed by action id.
+	ConfigRateLimitActionFormat = "ratelimit.action.%s"
+
+	// ConfigCatchUpMaxAge is the config entry for how old a message the bot missed while disconnected can be and
+	// still be answered when it reconnects, e.g. `10m` (or `off`).
+	ConfigCatchUpMaxAge = "catchup.max_age"
+
+	// DefaultCatchUpMaxAge is the default catch up max age.
+	DefaultCatchUpMaxAge = "10m"
+
+	// ConfigIntentThreshold is the config entry for how closely a mention that doesn't match any message pattern has
+	// to match an action's examples for the action to run, from 0 to 1 (or `off`).
+	ConfigIntentThreshold = "intent.threshold"
+
+	// ConfigIntentSuggestThreshold is the config entry for how closely a mention has to match an action's examples
+	// for the bot to ask "did you mean" instead, from 0 to 1 (or `off`).
+	ConfigIntentSuggestThreshold = "intent.suggest_threshold"
+
+	// DefaultRateLimitUser is the default user rate limit.
+	DefaultRateLimitUser = "10/1m"
+
+	// DefaultRateLimitChannel is the default channel rate limit.
+	DefaultRateLimitChannel = "30/1m"
+
+	// DefaultRateLimitAction is the default action rate limit.
+	DefaultRateLimitAction = "30/1m"
+
 	// ModuleConfig is the name of the config module.
 	ModuleConfig = "config"
 
@@ -43,14 +89,113 @@ const (
 
 	// ActionModule is the list config values action.
 	ActionModule = "module"
+
+	// RedactedConfigValue is shown in place of secret config values.
+	RedactedConfigValue = "<redacted>"
 )
 
+var secretConfigExpr = regexp.MustCompile(`(?i)(token|secret|password|credential|api_?key)`)
+
+// IsSecretConfig returns if a config entry holds a secret (a token, password or credentials) that shouldn't be shown.
+func IsSecretConfig(key string) bool {
+	return secretConfigExpr.MatchString(key)
+}
+
+// RedactConfigValue returns a config entry's value, or `RedactedConfigValue` if the key is a secret.
+func RedactConfigValue(key, value string) string {
+	if IsSecretConfig(key) {
+		return RedactedConfigValue
+	}
+	return value
+}
+
+// RedactConfiguration returns a copy of a configuration with the secret values redacted.
+func RedactConfiguration(configuration map[string]string) map[string]string {
+	redacted := map[string]string{}
+	for key, value := range configuration {
+		redacted[key] = RedactConfigValue(key, value)
+	}
+	return redacted
+}
+
+// RedactCommand returns a `config:<key> <value>` command with the value redacted if the key is a secret,
+// and any other message text as is.
+func RedactCommand(messageText string) string {
+	parts := core.ExtractSubMatches(messageText, "^config:([^ ]+) (.+)")
+	if len(parts) < 3 || !IsSecretConfig(parts[1]) {
+		return messageText
+	}
+	return fmt.Sprintf("config:%s %s", parts[1], RedactedConfigValue)
+}
+
+// AuditConfigArguments returns the audit log arguments for setting a config entry, with the value redacted if
+// the key is a secret.
+func AuditConfigArguments(key, value string) string {
+	return fmt.Sprintf("%s %s", key, RedactConfigValue(key, value))
+}
+
+// SetConfig sets a config entry, normalizing `yes`, `on`, `1` etc. to `true` and `no`, `off`, `0` etc. to `false`.
+// It returns the value that was set.
+func SetConfig(b core.Bot, key, value string) string {
+	setting := value
+	if core.LikeAny(value, "^true$", "^yes$", "^on$", "^1$") {
+		setting = "true"
+	} else if core.LikeAny(value, "^false$", "^no$", "^off$", "^0$") {
+		setting = "false"
+	}
+	b.SetConfig(key, setting)
+	return setting
+}
+
+// LoadModule loads a registered module that isn't already loaded.
+func LoadModule(b core.Bot, name string) error {
+	if b.LoadedModules().Contains(name) {
+		return exception.Newf("Module `%s` is already loaded.", name)
+	}
+	if !b.RegisteredModules().Contains(name) {
+		return exception.Newf("Module `%s` isn't registered.", name)
+	}
+	return b.LoadModule(name)
+}
+
+// UnloadModule unloads a loaded module.
+func UnloadModule(b core.Bot, name string) error {
+	if err := checkUnloadModule(b, name); err != nil {
+		return err
+	}
+	b.UnloadModule(name)
+	return nil
+}
+
+func checkUnloadModule(b core.Bot, name string) error {
+	if !b.LoadedModules().Contains(name) {
+		return exception.Newf("Module `%s` isn't loaded.", name)
+	}
+	if !b.RegisteredModules().Contains(name) {
+		return exception.Newf("Module `%s` isn't registered.", name)
+	}
+	return nil
+}
+
+// DataFilePath returns the path of a module data file within the configured data path,
+// falling back to the `DATA_PATH` environment variable and then the working directory.
+func DataFilePath(b core.Bot, fileName string) string {
+	dataPath := b.Configuration()[ConfigDataPath]
+	if core.IsEmpty(dataPath) {
+		dataPath = os.Getenv(EnvironmentDataPath)
+	}
+	if core.IsEmpty(dataPath) {
+		dataPath = "."
+	}
+	return filepath.Join(dataPath, fileName)
+}
+
 // Config is the module that governs configuration manipulation.
 type Config struct{}
 
 // Init for this module does nothing.
 func (c *Config) Init(b core.Bot) error {
-	b.Configuration()[ConfigOptionPassive] = "true"
+	b.SetConfig(ConfigOptionPassive, "true")
 	return nil
 }
 
@@ -62,35 +207,32 @@ func (c *Config) Name() string {
 // Actions returns the actions for the module.
 func (c *Config) Actions() []core.Action {
 	return []core.Action{
-		core.Action{ID: ActionConfigSet, MessagePattern: "^config:(.+) (.+)", Description: "Set config values", Handler: c.handleConfigSet},
+		core.Action{ID: ActionConfigSet, MessagePattern: "^config:([^ ]+) (.+)", Description: "Set config values", Handler: c.handleConfigSet},
 		core.Action{ID: ActionConfigGet, MessagePattern: "^config:(.+)", Description: "Get config values", Handler: c.handleConfigGet},
-		core.Action{ID: ActionConfig, MessagePattern: "^config", Description: "Prints the current config", Handler: c.handleConfig},
+		core.Action{ID: ActionConfig, MessagePattern: "^config", Description: "Prints the current config", Handler: c.handleConfig, ReplyInThread: true},
 
 		core.Action{ID: ActionModuleLoad, MessagePattern: "^module:load (.+)", Description: "Loads a module", Handler: c.handleLoadModule},
-		core.Action{ID: ActionModuleUnload, MessagePattern: "^module:unload (.+)", Description: "Unloads a module", Handler: c.handleUnloadModule},
-		core.Action{ID: ActionModule, MessagePattern: "^module", Description: "Prints the current loaded modules", Handler: c.handleModule},
+		core.Action{ID: ActionModuleUnload, MessagePattern: "^module:unload (.+)", Description: "Unloads a module (after asking you to confirm)", Handler: c.handleUnloadModule},
+		core.Action{ID: ActionModule, MessagePattern: "^module", Description: "Prints the current loaded modules", Handler: c.handleModule, ReplyInThread: true},
 	}
 }
 
 func (c *Config) handleConfigSet(b core.Bot, m *slack.Message) error {
 	messageWithoutMentions := util.String.TrimWhitespace(core.LessMentions(m.Text))
-	parts := core.ExtractSubMatches(messageWithoutMentions, "^config:(.+) (.+)")
+	parts := core.ExtractSubMatches(messageWithoutMentions, "^config:([^ ]+) (.+)")
 
 	if len(parts) < 3 {
 		return exception.Newf("malformed message for `%s`", ActionConfigSet)
 	}
 
 	key := parts[1]
-	value := parts[2]
-
-	setting := value
-	if core.LikeAny(value, "true", "yes", "on", "1") {
-		setting = "true"
-	} else if core.LikeAny(value, "false", "off", "0") {
-		setting = "false"
+	if core.Like(key, jobs.ConfigJobScheduleExpr) {
+		if _, err := jobs.ParseCron(parts[2]); err != nil {
+			return b.Replyf(m, "> %s: `%s` isn't a valid schedule: %v", ActionConfigSet, parts[2], err)
+		}
 	}
-	b.Configuration()[key] = setting
-	return b.Sayf(m.Channel, "> %s: `%s` = %s", ActionConfigSet, key, setting)
+	setting := SetConfig(b, key, parts[2])
+	return b.Replyf(m, "> %s: `%s` = %s", ActionConfigSet, key, RedactConfigValue(key, setting))
 }
 
 func (c *Config) handleConfigGet(b core.Bot, m *slack.Message) error {
@@ -103,7 +245,7 @@ func (c *Config) handleConfigGet(b core.Bot, m *slack.Message) error {
 
 	key := parts[1]
 	value := b.Configuration()[key]
-	return b.Sayf(m.Channel, "> %s: `%s` = %s", ActionConfigGet, key, value)
+	return b.Replyf(m, "> %s: `%s` = %s", ActionConfigGet, key, RedactConfigValue(key, value))
 }
 
 func (c *Config) handleConfig(b core.Bot, m *slack.Message) error {
@@ -114,7 +256,7 @@ func (c *Config) handleConfig(b core.Bot, m *slack.Message) error {
 		}
 	}
 
-	return b.Say(m.Channel, configText)
+	return b.Reply(m, configText)
 }
 
 func (c *Config) handleLoadModule(b core.Bot, m *slack.Message) error {
@@ -125,15 +267,10 @@ func (c *Config) handleLoadModule(b core.Bot, m *slack.Message) error {
 	}
 
 	key := parts[1]
-	if b.LoadedModules().Contains(key) {
-		return b.Sayf(m.Channel, "Module `%s` is already loaded.", key)
+	if err := LoadModule(b, key); err != nil {
+		return b.Reply(m, err.Error())
 	}
-	if !b.RegisteredModules().Contains(key) {
-		return b.Sayf(m.Channel, "Module `%s` isn't registered.", key)
-	}
-
-	b.LoadModule(key)
-	return b.Sayf(m.Channel, "Loaded Module `%s`.", key)
+	return b.Replyf(m, "Loaded Module `%s`.", key)
 }
 
 func (c *Config) handleUnloadModule(b core.Bot, m *slack.Message) error {
@@ -144,15 +281,18 @@ func (c *Config) handleUnloadModule(b core.Bot, m *slack.Message) error {
 	}
 
 	key := parts[1]
-	if !b.LoadedModules().Contains(key) {
-		return b.Sayf(m.Channel, "Module `%s` isn't loaded.", key)
-	}
-	if !b.RegisteredModules().Contains(key) {
-		return b.Sayf(m.Channel, "Module `%s` isn't registered.", key)
+	if err := checkUnloadModule(b, key); err != nil {
+		return b.Reply(m, err.Error())
 	}
 
-	b.UnloadModule(key)
-	return b.Sayf(m.Channel, "Unloaded Module `%s`.", key)
+	return core.NewConversation(m).Confirm(b, fmt.Sprintf("are you sure you want to unload `%s`?", key), func(b core.Bot, reply *slack.Message) error {
+		err := UnloadModule(b, key)
+		auditConfirmed(b, reply, ActionModuleUnload, fmt.Sprintf("module:unload %s (confirmed)", key), err)
+		if err != nil {
+			return b.Reply(m, err.Error())
+		}
+		return b.Replyf(m, "Unloaded Module `%s`.", key)
+	})
 }
 
 func (c *Config) handleModule(b core.Bot, m *slack.Message) error {
@@ -160,5 +300,16 @@ func (c *Config) handleModule(b core.Bot, m *slack.Message) error {
 	for key := range b.LoadedModules() {
 		moduleText = moduleText + fmt.Sprintf("> `%s`\n", key)
 	}
-	return b.Say(m.Channel, moduleText)
+	return b.Reply(m, moduleText)
+}
+
+// auditConfirmed records a change made once a conversation was confirmed, since the action that asked for the
+// confirmation is audited before the change happens.
+func auditConfirmed(b core.Bot, reply *slack.Message, actionID, arguments string, err error) {
+	entry := core.AuditEntry{User: reply.User, Channel: reply.Channel, Action: actionID, Arguments: arguments, Result: core.AuditResultSuccess}
+	if err != nil {
+		entry.Result = core.AuditResultError
+		entry.Error = err.Error()
+	}
+	b.AuditLog().Add(entry)
 }
@@ -5,8 +5,8 @@ import (
 	"testing"
 
 	"github.com/blendlabs/go-assert"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 func TestHandleConfigSet(t *testing.T) {
@@ -23,7 +23,7 @@ func TestHandleConfigGet(t *testing.T) {
 	assert := assert.New(t)
 	c := &Config{}
 	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
-	mb.Configuration()["foo"] = "bar"
+	mb.SetConfig("foo", "bar")
 
 	gotMessage := ""
 	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
@@ -38,12 +38,117 @@ func TestHandleConfigGet(t *testing.T) {
 	assert.True(strings.Contains(gotMessage, "bar"))
 }
 
+func TestHandleConfigRedactsSecrets(t *testing.T) {
+	assert := assert.New(t)
+	c := &Config{}
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+
+	gotMessages := []string{}
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		gotMessages = append(gotMessages, m.Text)
+		return nil
+	})
+
+	assert.Nil(c.handleConfigSet(mb, core.MockMessage("config:jira.password hunter2")))
+	assert.Equal("hunter2", mb.Configuration()["jira.password"])
+	assert.Nil(c.handleConfigGet(mb, core.MockMessage("config:jira.password")))
+
+	assert.Len(gotMessages, 2)
+	for _, message := range gotMessages {
+		assert.False(strings.Contains(message, "hunter2"))
+		assert.True(strings.Contains(message, RedactedConfigValue))
+	}
+}
+
 func TestHandleConfig(t *testing.T) {
 	assert := assert.New(t)
 	c := &Config{}
 	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
-	mb.Configuration()["foo"] = "bar"
+	mb.SetConfig("foo", "bar")
 
 	handleErr := c.handleConfig(mb, core.MockMessage("config"))
 	assert.Nil(handleErr)
 }
+
+func TestHandleConfigSetSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &Config{}
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	handleErr := c.handleConfigSet(mb, core.MockMessage("config:job.clock.schedule */15 9-17 * * 1-5"))
+	assert.Nil(handleErr)
+	assert.Equal("*/15 9-17 * * 1-5", mb.Configuration()["job.clock.schedule"])
+
+	handleErr = c.handleConfigSet(mb, core.MockMessage("config:job.clock.schedule every tuesday"))
+	assert.Nil(handleErr)
+	assert.Equal("*/15 9-17 * * 1-5", mb.Configuration()["job.clock.schedule"], "invalid schedules aren't set")
+
+	handleErr = c.handleConfigSet(mb, core.MockMessage("config:option.passive on"))
+	assert.Nil(handleErr)
+	assert.Equal("true", mb.Configuration()["option.passive"])
+}
+
+func TestHandleUnloadModuleConfirms(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &Config{}
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.RegisterModule(&Util{})
+	assert.Nil(mb.LoadModule(ModuleUtil))
+
+	unload := core.MockMessage("module:unload util")
+	unload.User = "U01"
+	assert.Nil(c.handleUnloadModule(mb, unload))
+	assert.True(mb.LoadedModules().Contains(ModuleUtil))
+
+	reply := core.MockMessage("yes")
+	reply.User = "U01"
+	handled, err := mb.Sessions().Handle(mb, reply)
+	assert.True(handled)
+	assert.Nil(err)
+	assert.False(mb.LoadedModules().Contains(ModuleUtil))
+
+	entries := mb.AuditLog().Recent(1)
+	assert.Len(entries, 1)
+	assert.Equal("U01", entries[0].User)
+	assert.Equal(ActionModuleUnload, entries[0].Action)
+	assert.Equal("module:unload util (confirmed)", entries[0].Arguments)
+	assert.Equal(core.AuditResultSuccess, entries[0].Result)
+}
+
+func TestRedactConfiguration(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsSecretConfig("SLACK_API_TOKEN"))
+	assert.True(IsSecretConfig(ConfigJiraCredentials))
+	assert.True(IsSecretConfig("stocks.api_key"))
+	assert.False(IsSecretConfig(ConfigOptionPassive))
+
+	configuration := map[string]string{"SLACK_API_TOKEN": "xoxb-1234", ConfigOptionPassive: "true"}
+	redacted := RedactConfiguration(configuration)
+	assert.Equal(RedactedConfigValue, redacted["SLACK_API_TOKEN"])
+	assert.Equal("true", redacted[ConfigOptionPassive])
+	assert.Equal("xoxb-1234", configuration["SLACK_API_TOKEN"], "the configuration itself isn't changed")
+}
+
+func TestRedactCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("config:jira.password "+RedactedConfigValue, RedactCommand("config:jira.password hunter2"))
+	assert.Equal("config:option.passive off", RedactCommand("config:option.passive off"))
+	assert.Equal("stock:price goog", RedactCommand("stock:price goog"))
+	assert.Equal("SLACK_API_TOKEN "+RedactedConfigValue, AuditConfigArguments("SLACK_API_TOKEN", "xoxb-1234"))
+	assert.Equal("option.passive on", AuditConfigArguments(ConfigOptionPassive, "on"))
+}
+
+func TestLoadModule(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.RegisterModule(&Util{})
+	assert.NotNil(LoadModule(mb, "not-a-module"))
+	assert.Nil(LoadModule(mb, ModuleUtil))
+	assert.NotNil(LoadModule(mb, ModuleUtil))
+	assert.Nil(UnloadModule(mb, ModuleUtil))
+	assert.NotNil(UnloadModule(mb, ModuleUtil))
+}
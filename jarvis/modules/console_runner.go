@@ -10,8 +10,8 @@ import (
 
 	"github.com/blendlabs/go-exception"
 	"github.com/blendlabs/go-util"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
@@ -39,7 +39,7 @@ func (cr *ConsoleRunner) Name() string {
 // Actions returns the module actions.
 func (cr *ConsoleRunner) Actions() []core.Action {
 	return []core.Action{
-		core.Action{ID: ActionConsoleRunnerRun, MessagePattern: "^run", Description: "Runs a given console command", Handler: cr.handleConsoleRunnerRun},
+		core.Action{ID: ActionConsoleRunnerRun, MessagePattern: "^run", Description: "Runs a given console command", Handler: cr.handleConsoleRunnerRun, ReplyInThread: true},
 	}
 }
 
@@ -124,5 +124,5 @@ func (cr *ConsoleRunner) handleConsoleRunnerRun(b core.Bot, m *slack.Message) er
 		outputText = "> empty"
 	}
 
-	return b.Say(m.Channel, outputText)
+	return b.Reply(m, outputText)
 }
@@ -6,8 +6,8 @@ import (
 	"time"
 
 	"github.com/blendlabs/go-util"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
@@ -53,10 +53,13 @@ func (c *Core) Name() string {
 // Actions returns mention commands for the core module.
 func (c *Core) Actions() []core.Action {
 	return []core.Action{
-		core.Action{ID: ActionHelp, MessagePattern: "^help", Description: "Prints help info.", Handler: c.handleHelp},
-		core.Action{ID: ActionTime, MessagePattern: "^time", Description: "Prints the current time.", Handler: c.handleTime},
+		core.Action{ID: ActionHelp, MessagePattern: "^help", Description: "Prints help info.", Handler: c.handleHelp, ReplyInThread: true,
+			Command: "help", Examples: []string{"what can you do", "what commands do you know", "how do I use you"}},
+		core.Action{ID: ActionTime, MessagePattern: "^time", Description: "Prints the current time.", Handler: c.handleTime,
+			Command: "time", Examples: []string{"what time is it", "what's the current time"}},
 		core.Action{ID: ActionTell, MessagePattern: "^tell", Description: "Tell people things.", Handler: c.handleTell},
-		core.Action{ID: ActionChannels, MessagePattern: "^channels", Description: "Prints the channels I'm currently listening to.", Handler: c.handleChannels},
+		core.Action{ID: ActionChannels, MessagePattern: "^channels", Description: "Prints the channels I'm currently listening to.", Handler: c.handleChannels,
+			Command: "channels", Examples: []string{"which channels are you listening to", "what channels are you in"}},
 
 		core.Action{ID: ActionMentionCatchAll, MessagePattern: "(.*)", Description: "I'll do the best I can.", Handler: c.handleMentionCatchAll, Priority: core.PriorityCatchAll},
 		core.Action{ID: ActionPassiveCatchAll, Passive: true, MessagePattern: "(.*)", Description: "I'll do the best I can (passively).", Handler: c.handlePassiveCatchAll, Priority: core.PriorityCatchAll},
@@ -69,7 +72,7 @@ func (c *Core) Actions() []core.Action {
 func (c *Core) handleHelp(b core.Bot, m *slack.Message) error {
 	responseText := "Here are the commands that are currently configured:"
 	for _, actionHandler := range b.Actions() {
-		if !actionHandler.Passive {
+		if !actionHandler.Passive && !actionHandler.IsReaction() {
 			if len(actionHandler.MessagePattern) != 0 {
 				responseText = responseText + fmt.Sprintf("\n>`%s` - %s", actionHandler.MessagePattern, actionHandler.Description)
 			} else {
@@ -79,7 +82,7 @@ func (c *Core) handleHelp(b core.Bot, m *slack.Message) error {
 	}
 	responseText = responseText + "\nWith the following passive commands:"
 	for _, actionHandler := range b.Actions() {
-		if actionHandler.Passive {
+		if actionHandler.Passive && !actionHandler.IsReaction() {
 			if len(actionHandler.MessagePattern) != 0 {
 				responseText = responseText + fmt.Sprintf("\n>`%s` - %s", actionHandler.MessagePattern, actionHandler.Description)
 			} else {
@@ -87,12 +90,19 @@ func (c *Core) handleHelp(b core.Bot, m *slack.Message) error {
 			}
 		}
 	}
-	return b.Say(m.Channel, responseText)
+	responseText = responseText + "\nAnd the following reactions:"
+	for _, actionHandler := range b.Actions() {
+		if actionHandler.IsReaction() {
+			responseText = responseText + fmt.Sprintf("\n>:%s: - %s", actionHandler.Reaction, actionHandler.Description)
+		}
+	}
+	return b.Reply(m, responseText)
 }
 
 func (c *Core) handleTime(b core.Bot, m *slack.Message) error {
 	timeText := fmt.Sprintf("%s UTC", time.Now().UTC().Format(time.Kitchen))
 	message := slack.NewChatMessage(m.Channel, "")
+	message.ThreadTimestamp = core.ThreadTimestamp(m)
 	message.AsUser = slack.OptionalBool(true)
 	message.UnfurlLinks = slack.OptionalBool(false)
 	message.UnfurlMedia = slack.OptionalBool(false)
@@ -105,7 +115,7 @@ func (c *Core) handleTime(b core.Bot, m *slack.Message) error {
 		},
 	}
 
-	_, err := b.Client().ChatPostMessage(message)
+	err := b.ReplyMessage(m, message)
 	if err != nil {
 		fmt.Printf("issue posting message: %v\n", err)
 	}
@@ -130,12 +140,12 @@ func (c *Core) handleTell(b core.Bot, m *slack.Message) error {
 	}
 	tellMessage = core.ReplaceAny(tellMessage, "you are", "shes", "she's", "she is", "hes", "he's", "he is", "theyre", "they're", "they are")
 	resultMessage := fmt.Sprintf("%s %s", destinationUser, tellMessage)
-	return b.Say(m.Channel, resultMessage)
+	return b.Reply(m, resultMessage)
 }
 
 func (c *Core) handleChannels(b core.Bot, m *slack.Message) error {
 	if len(b.ActiveChannels()) == 0 {
-		return b.Say(m.Channel, "currently listening to *no* channels.")
+		return b.Reply(m, "currently listening to *no* channels.")
 	}
 	activeChannelsText := "currently listening to the following channels:\n"
 	for _, channelID := range b.ActiveChannels() {
@@ -143,13 +153,13 @@ func (c *Core) handleChannels(b core.Bot, m *slack.Message) error {
 			activeChannelsText = activeChannelsText + fmt.Sprintf(">#%s (id:%s)\n", channel.Name, channel.ID)
 		}
 	}
-	return b.Say(m.Channel, activeChannelsText)
+	return b.Reply(m, activeChannelsText)
 }
 
 func (c *Core) handleSalutation(b core.Bot, m *slack.Message) error {
 	user := b.FindUser(m.User)
 	salutation := []string{"hey %s", "hi %s", "hello %s", "ohayo gozaimasu %s", "salut %s", "bonjour %s", "yo %s", "sup %s"}
-	return b.Sayf(m.Channel, core.Random(salutation), strings.ToLower(user.Profile.FirstName))
+	return b.Replyf(m, core.Random(salutation), strings.ToLower(core.FirstName(user)))
 }
 
 func (c *Core) handleMentionCatchAll(b core.Bot, m *slack.Message) error {
@@ -166,11 +176,11 @@ func (c *Core) handlePassiveCatchAll(b core.Bot, m *slack.Message) error {
 		if core.IsAngry(message) {
 			user := b.FindUser(m.User)
 			response := []string{"slow down %s", "maybe calm down %s", "%s you should really relax", "chill %s", "it's ok %s, let it out"}
-			return b.Sayf(m.Channel, core.Random(response), strings.ToLower(user.Profile.FirstName))
+			return b.Replyf(m, core.Random(response), strings.ToLower(core.FirstName(user)))
 		}
 		if core.IsEmpty(message) {
 			user := b.FindUser(m.User)
-			return b.Sayf("hello %s", user.Profile.FirstName)
+			return b.Replyf(m, "hello %s", core.FirstName(user))
 		}
 	}
 
@@ -178,5 +188,5 @@ func (c *Core) handlePassiveCatchAll(b core.Bot, m *slack.Message) error {
 }
 
 func (c *Core) handleUnknown(b core.Bot, m *slack.Message) error {
-	return b.Sayf(m.Channel, "I don't know how to respond to this\n>%s", m.Text)
+	return b.Replyf(m, "I don't know how to respond to this\n>%s", m.Text)
 }
@@ -5,8 +5,8 @@ import (
 	"testing"
 
 	"github.com/blendlabs/go-assert"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 func TestHandleHelp(t *testing.T) {
@@ -78,3 +78,17 @@ func TestHandleMentionCatchNonSalutation(t *testing.T) {
 	println(gotMessage)
 	assert.True(strings.Contains(gotMessage, "how to respond"))
 }
+
+func TestHandleTime(t *testing.T) {
+	assert := assert.New(t)
+	c := &Core{}
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+
+	assert.Nil(c.handleTime(mb, core.MockMessage("time")))
+	posted := mb.Outbox().Kind(core.MockOutboundReply).Last()
+	assert.NotNil(posted)
+	assert.Equal("CTESTCHANNEL", posted.Channel)
+	assert.Len(posted.Message.Attachments, 1)
+	assert.Equal("The time is now:", *posted.Message.Attachments[0].Pretext)
+	assert.True(strings.HasSuffix(*posted.Message.Attachments[0].Text, "UTC"))
+}
@@ -7,9 +7,9 @@ import (
 
 	"github.com/blendlabs/go-exception"
 	"github.com/blendlabs/go-util"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
 	"github.com/wcharczuk/jarvis/jarvis/external"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
@@ -25,6 +25,12 @@ const (
 	// ConfigJiraHost is the jira host bot config entry.
 	ConfigJiraHost = "jira_host"
 
+	// EnvironmentJiraProject is the environment variable name for the jira project issues are created in.
+	EnvironmentJiraProject = "JIRA_PROJECT"
+
+	// ConfigJiraProject is the jira project (key) bot config entry issues are created in.
+	ConfigJiraProject = "jira_project"
+
 	// ModuleJira is the name of the jira module.
 	ModuleJira = "jira"
 
@@ -39,30 +45,43 @@ const (
 
 	// ActionJiraREL is the name of the bugs action.
 	ActionJiraREL = "jira.rel"
+
+	// ActionJiraCreate is the name of the create issue reaction action.
+	ActionJiraCreate = "jira.create"
+
+	// ReactionJiraCreate is the reaction that creates a jira issue from a message.
+	ReactionJiraCreate = "jira"
+
+	jiraSummaryLength = 80
 )
 
 // Jira is the jira module.
 type Jira struct{}
 
-// Init for this module does nothing.
+// Init reads the jira credentials, host and project from the environment if they aren't configured.
+// The module won't load without credentials and a host.
 func (j *Jira) Init(b core.Bot) error {
 	if _, hasEntry := b.Configuration()[ConfigJiraCredentials]; !hasEntry {
 		envCredentials := os.Getenv(EnvironmentJiraCredentials)
 		if len(envCredentials) != 0 {
-			b.Configuration()[ConfigJiraCredentials] = envCredentials
+			b.SetConfig(ConfigJiraCredentials, envCredentials)
 		} else {
-			b.Logf("No `%s` provided, module `%s` cannot load.", EnvironmentJiraHost, ModuleJira)
-			return nil
+			return exception.Newf("no `%s` provided, module `%s` cannot load", EnvironmentJiraCredentials, ModuleJira)
 		}
 	}
 
 	if _, hasEntry := b.Configuration()[ConfigJiraHost]; !hasEntry {
 		envHost := os.Getenv(EnvironmentJiraHost)
 		if len(envHost) != 0 {
-			b.Configuration()[ConfigJiraHost] = envHost
+			b.SetConfig(ConfigJiraHost, envHost)
 		} else {
-			b.Logf("No `%s` provided, module `%s` cannot load.", EnvironmentJiraHost, ModuleJira)
-			return nil
+			return exception.Newf("no `%s` provided, module `%s` cannot load", EnvironmentJiraHost, ModuleJira)
+		}
+	}
+
+	if _, hasEntry := b.Configuration()[ConfigJiraProject]; !hasEntry {
+		if envProject := os.Getenv(EnvironmentJiraProject); len(envProject) != 0 {
+			b.SetConfig(ConfigJiraProject, envProject)
 		}
 	}
 
@@ -81,6 +100,7 @@ func (j *Jira) Actions() []core.Action {
 		core.Action{ID: ActionJiraBUGS, Passive: true, MessagePattern: "(BUGS-[0-9]+)", Description: "Fetch jira BUGS task info.", Handler: j.handleJira},
 		core.Action{ID: ActionJiraIMP, Passive: true, MessagePattern: "(IMP-[0-9]+)", Description: "Fetch jira IMP task info.", Handler: j.handleJira},
 		core.Action{ID: ActionJiraREL, Passive: true, MessagePattern: "(REL-[0-9]+)", Description: "Fetch jira REL task info.", Handler: j.handleJira},
+		core.Action{ID: ActionJiraCreate, Reaction: ReactionJiraCreate, Description: "Creates a jira task from the message.", Handler: j.handleJiraCreate},
 	}
 }
 
@@ -102,8 +122,9 @@ func (j *Jira) handleJira(b core.Bot, m *slack.Message) error {
 
 	user := b.FindUser(m.User)
 
-	leadText := fmt.Sprintf("*%s* has mentioned the following jira issues (%d): ", user.Profile.FirstName, len(issues))
+	leadText := fmt.Sprintf("*%s* has mentioned the following jira issues (%d): ", core.FirstName(user), len(issues))
 	message := slack.NewChatMessage(m.Channel, leadText)
+	message.ThreadTimestamp = core.ThreadTimestamp(m)
 	message.AsUser = slack.OptionalBool(true)
 	message.UnfurlLinks = slack.OptionalBool(false)
 	for _, issue := range issues {
@@ -131,13 +152,37 @@ func (j *Jira) handleJira(b core.Bot, m *slack.Message) error {
 		}
 	}
 
-	_, err = b.Client().ChatPostMessage(message)
+	err = b.ReplyMessage(m, message)
 	if err != nil {
 		fmt.Printf("issue posting message: %v\n", err)
 	}
 	return err
 }
 
+func (j *Jira) handleJiraCreate(b core.Bot, m *slack.Message) error {
+	projectKey := b.Configuration()[ConfigJiraProject]
+	if core.IsEmpty(projectKey) {
+		return exception.Newf("Jarvis is not configured with a Jira project; set it with `config:%s <project key>`.", ConfigJiraProject)
+	}
+	jiraUser, jiraPassword, jiraHost, err := j.jiraCredentials(b)
+	if err != nil {
+		return err
+	}
+
+	messageText := core.LessMentions(m.Message.Text)
+	summary := jiraSummary(messageText)
+	description := messageText
+	if author := b.FindUser(m.Message.User); author != nil {
+		description = fmt.Sprintf("%s\n\n(from %s in slack)", messageText, author.Name)
+	}
+
+	issue, err := external.CreateJiraIssue(jiraUser, jiraPassword, jiraHost, projectKey, summary, description)
+	if err != nil {
+		return err
+	}
+	return b.Replyf(core.InThread(m.Message), "<@%s> created https://%s/browse/%s", m.User, jiraHost, issue.Key)
+}
+
 func (j *Jira) extractJiraIssues(text string) []string {
 	issueIds := []string{}
 	issueIds = append(issueIds, core.Extract(text, "(DSP-[0-9]+)")...)
@@ -149,27 +194,11 @@ func (j *Jira) extractJiraIssues(text string) []string {
 
 func (j *Jira) fetchJiraIssues(b core.Bot, issueIds []string) ([]*external.JiraIssue, error) {
 	issues := []*external.JiraIssue{}
-	credentials, hasCredentials := b.Configuration()[ConfigJiraCredentials]
-
-	if !hasCredentials {
-		return issues, exception.New("Jarvis is not configured with Jira credentials.")
-	}
-
-	credentialPieces := strings.Split(credentials, ":")
-
-	if len(credentialPieces) != 2 {
-		return issues, exception.New("Jira credentials are not formatted correctly.")
-	}
-
-	jiraUser := credentialPieces[0]
-	jiraPassword := credentialPieces[1]
-
-	jiraHost, hasJiraHost := b.Configuration()[ConfigJiraHost]
-	if !hasJiraHost {
-		return issues, exception.New("Jarvis is not configured with a Jira host.")
+	jiraUser, jiraPassword, jiraHost, err := j.jiraCredentials(b)
+	if err != nil {
+		return issues, err
 	}
 
-	var err error
 	var issue *external.JiraIssue
 	for _, issueID := range issueIds {
 		issue, err = external.GetJiraIssue(jiraUser, jiraPassword, jiraHost, issueID)
@@ -182,3 +211,33 @@ func (j *Jira) fetchJiraIssues(b core.Bot, issueIds []string) ([]*external.JiraI
 
 	return issues, nil
 }
+
+func (j *Jira) jiraCredentials(b core.Bot) (user, password, host string, err error) {
+	credentials, hasCredentials := b.Configuration()[ConfigJiraCredentials]
+	if !hasCredentials {
+		err = exception.New("Jarvis is not configured with Jira credentials.")
+		return
+	}
+
+	credentialPieces := strings.Split(credentials, ":")
+	if len(credentialPieces) != 2 {
+		err = exception.New("Jira credentials are not formatted correctly.")
+		return
+	}
+
+	host, hasJiraHost := b.Configuration()[ConfigJiraHost]
+	if !hasJiraHost {
+		err = exception.New("Jarvis is not configured with a Jira host.")
+		return
+	}
+	return credentialPieces[0], credentialPieces[1], host, nil
+}
+
+// jiraSummary returns the first line of a message, shortened to fit in an issue summary.
+func jiraSummary(messageText string) string {
+	summary := strings.TrimSpace(strings.SplitN(messageText, "\n", 2)[0])
+	if runes := []rune(summary); len(runes) > jiraSummaryLength {
+		summary = string(runes[:jiraSummaryLength-3]) + "..."
+	}
+	return summary
+}
@@ -1,9 +1,13 @@
 package modules
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 func TestExtractJiraIssues(t *testing.T) {
@@ -18,3 +22,32 @@ func TestExtractJiraIssues(t *testing.T) {
 	a.Equal("DSP-4321", issueIds[1])
 	a.Equal("BUGS-1234", issueIds[2])
 }
+
+func TestJiraSummary(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("the build is broken", jiraSummary(" the build is broken \nsince this morning"))
+
+	summary := jiraSummary(strings.Repeat("é", 100))
+	a.True(utf8.ValidString(summary))
+	a.Equal(jiraSummaryLength, utf8.RuneCountInString(summary))
+	a.True(strings.HasSuffix(summary, "..."))
+}
+
+func TestJiraInit(t *testing.T) {
+	a := assert.New(t)
+	t.Setenv(EnvironmentJiraCredentials, "")
+	t.Setenv(EnvironmentJiraHost, "")
+	t.Setenv(EnvironmentJiraProject, "")
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	jb := &Jira{}
+	a.NotNil(jb.Init(mb), "jira can't load without credentials")
+
+	t.Setenv(EnvironmentJiraCredentials, "jarvis:hunter2")
+	a.NotNil(jb.Init(mb), "jira can't load without a host")
+
+	mb.SetConfig(ConfigJiraHost, "jira.example.com")
+	a.Nil(jb.Init(mb))
+	a.Equal("jarvis:hunter2", mb.Configuration()[ConfigJiraCredentials])
+}
@@ -3,12 +3,13 @@ package modules
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/blendlabs/go-exception"
 	"github.com/blendlabs/go-util"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
 	"github.com/wcharczuk/jarvis/jarvis/jobs"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
@@ -18,6 +19,9 @@ const (
 	//ActionJobs is the jobs action id.
 	ActionJobs = "jobs"
 
+	//ActionJobHistory is the job history action id.
+	ActionJobHistory = "job.history"
+
 	//ActionJobRun is the jobs run action id.
 	ActionJobRun = "job.run"
 
@@ -31,12 +35,38 @@ const (
 	ActionJobDisable = "job.disable"
 )
 
+// RunJob runs a job now, outside of its schedule.
+func RunJob(b core.Bot, jobName string) error {
+	if !b.JobManager().HasJob(jobName) {
+		return exception.Newf("job `%s` isn't loaded", jobName)
+	}
+	if b.JobManager().IsDisabled(jobName) {
+		return exception.Newf("job `%s` is disabled", jobName)
+	}
+	return b.JobManager().RunJob(jobName)
+}
+
+// EnableJob enables a disabled job.
+func EnableJob(b core.Bot, jobName string) error {
+	return b.JobManager().EnableJob(jobName)
+}
+
+// DisableJob disables a job so it won't run on its schedule.
+func DisableJob(b core.Bot, jobName string) error {
+	return b.JobManager().DisableJob(jobName)
+}
+
 // Jobs is the module that governs jobs within a bot.
 type Jobs struct{}
 
-// Init does nothing right now.
+// Init loads the clock job through the bot, so its runs are tracked, and leaves it disabled.
 func (j *Jobs) Init(b core.Bot) error {
-	b.JobManager().LoadJob(jobs.NewClock(b))
+	if !b.JobManager().HasJob("clock") {
+		err := b.LoadJob(jobs.NewClock(b))
+		if err != nil {
+			return err
+		}
+	}
 	b.JobManager().DisableJob("clock")
 	return nil
 }
@@ -49,7 +79,9 @@ func (j *Jobs) Name() string {
 // Actions are all the actions the module provides.
 func (j *Jobs) Actions() []core.Action {
 	return []core.Action{
-		core.Action{ID: ActionJobs, MessagePattern: "^jobs", Description: "Prints the current jobs and their statuses.", Handler: j.handleJobsStatus},
+		core.Action{ID: ActionJobs, MessagePattern: "^jobs$", Description: "Prints the current jobs and their statuses.", Handler: j.handleJobsStatus, ReplyInThread: true,
+			Command: "jobs", Examples: []string{"which jobs are running", "show the job statuses", "list the scheduled jobs"}},
+		core.Action{ID: ActionJobHistory, MessagePattern: "^jobs:history", Description: "Prints the recent runs of a job.", Handler: j.handleJobHistory, ReplyInThread: true},
 		core.Action{ID: ActionJobRun, MessagePattern: "^job:run", Description: "Runs all jobs", Handler: j.handleJobRun},
 		core.Action{ID: ActionJobCancel, MessagePattern: "^job:cancel", Description: "Cancels a running job.", Handler: j.handleJobCancel},
 		core.Action{ID: ActionJobEnable, MessagePattern: "^job:enable", Description: "Enables a job.", Handler: j.handleJobEnable},
@@ -61,12 +93,43 @@ func (j *Jobs) handleJobsStatus(b core.Bot, m *slack.Message) error {
 	statusText := "current job statuses:\n"
 	for _, status := range b.JobManager().Status() {
 		if len(status.RunningFor) != 0 {
-			statusText = statusText + fmt.Sprintf(">`%s` - state: %s running for: %s\n", status.Name, status.State, status.RunningFor)
+			statusText = statusText + fmt.Sprintf(">`%s` - state: %s running for: %s", status.Name, status.State, status.RunningFor)
 		} else {
-			statusText = statusText + fmt.Sprintf(">`%s` - state: %s\n", status.Name, status.State)
+			statusText = statusText + fmt.Sprintf(">`%s` - state: %s", status.Name, status.State)
 		}
+		if last := b.JobHistory().Last(status.Name); last != nil {
+			statusText = statusText + fmt.Sprintf(" last run: %s", formatJobRun(*last))
+		}
+		statusText = statusText + "\n"
 	}
-	return b.Say(m.Channel, statusText)
+	return b.Reply(m, statusText)
+}
+
+func (j *Jobs) handleJobHistory(b core.Bot, m *slack.Message) error {
+	messageWithoutMentions := util.String.TrimWhitespace(core.LessMentions(m.Text))
+	pieces := strings.Split(messageWithoutMentions, " ")
+	if len(pieces) < 2 {
+		return exception.New("usage: `jobs:history <job name>`")
+	}
+
+	jobName := pieces[len(pieces)-1]
+	runs := b.JobHistory().Runs(jobName)
+	if len(runs) == 0 {
+		return b.Replyf(m, "job `%s` hasn't run yet", jobName)
+	}
+
+	historyText := fmt.Sprintf("last %d runs of `%s`:\n", len(runs), jobName)
+	for _, run := range runs {
+		historyText = historyText + fmt.Sprintf(">%s - %s\n", run.Started.Format(time.RFC3339), formatJobRun(run))
+	}
+	return b.Reply(m, historyText)
+}
+
+func formatJobRun(run core.JobRun) string {
+	if run.Failed() {
+		return fmt.Sprintf("failed after %v: %s", run.Elapsed, run.Error)
+	}
+	return fmt.Sprintf("succeeded in %v", run.Elapsed)
 }
 
 func (j *Jobs) handleJobRun(b core.Bot, m *slack.Message) error {
@@ -74,12 +137,14 @@ func (j *Jobs) handleJobRun(b core.Bot, m *slack.Message) error {
 	pieces := strings.Split(messageWithoutMentions, " ")
 	if len(pieces) > 1 {
 		jobName := pieces[len(pieces)-1]
-		b.JobManager().RunJob(jobName)
-		return b.Sayf(m.Channel, "ran job `%s`", jobName)
+		if err := RunJob(b, jobName); err != nil {
+			return err
+		}
+		return b.Replyf(m, "ran job `%s`", jobName)
 	}
 
 	b.JobManager().RunAllJobs()
-	return b.Say(m.Channel, "ran all jobs")
+	return b.Reply(m, "ran all jobs")
 }
 
 func (j *Jobs) handleJobCancel(b core.Bot, m *slack.Message) error {
@@ -88,7 +153,7 @@ func (j *Jobs) handleJobCancel(b core.Bot, m *slack.Message) error {
 	if len(pieces) > 1 {
 		taskName := pieces[len(pieces)-1]
 		b.JobManager().CancelTask(taskName)
-		return b.Sayf(m.Channel, "canceled task `%s`", taskName)
+		return b.Replyf(m, "canceled task `%s`", taskName)
 	}
 	return exception.New("unhandled response.")
 }
@@ -98,8 +163,10 @@ func (j *Jobs) handleJobEnable(b core.Bot, m *slack.Message) error {
 	pieces := strings.Split(messageWithoutMentions, " ")
 	if len(pieces) > 1 {
 		taskName := pieces[len(pieces)-1]
-		b.JobManager().EnableJob(taskName)
-		return b.Sayf(m.Channel, "enabled job `%s`", taskName)
+		if err := EnableJob(b, taskName); err != nil {
+			return err
+		}
+		return b.Replyf(m, "enabled job `%s`", taskName)
 	}
 	return exception.New("unhandled response.")
 }
@@ -109,8 +176,10 @@ func (j *Jobs) handleJobDisable(b core.Bot, m *slack.Message) error {
 	pieces := strings.Split(messageWithoutMentions, " ")
 	if len(pieces) > 1 {
 		taskName := pieces[len(pieces)-1]
-		b.JobManager().DisableJob(taskName)
-		return b.Sayf(m.Channel, "disabled job `%s`", taskName)
+		if err := DisableJob(b, taskName); err != nil {
+			return err
+		}
+		return b.Replyf(m, "disabled job `%s`", taskName)
 	}
 	return exception.New("unhandled response.")
 }
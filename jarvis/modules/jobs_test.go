@@ -0,0 +1,38 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestHandleJobHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	j := &Jobs{}
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+
+	gotMessage := ""
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		gotMessage = m.Text
+		return nil
+	})
+
+	assert.NotNil(j.handleJobHistory(mb, core.MockMessage("jobs:history")))
+
+	assert.Nil(j.handleJobHistory(mb, core.MockMessage("jobs:history clock")))
+	assert.True(strings.Contains(gotMessage, "hasn't run"))
+
+	started := time.Date(2016, 01, 01, 12, 00, 00, 00, time.UTC)
+	mb.JobHistory().Add(core.JobRun{Job: "clock", Started: started, Elapsed: time.Second})
+	mb.JobHistory().Add(core.JobRun{Job: "clock", Started: started.Add(time.Hour), Elapsed: time.Second, Error: "no channels"})
+
+	assert.Nil(j.handleJobHistory(mb, core.MockMessage("jobs:history clock")))
+	assert.True(strings.Contains(gotMessage, "last 2 runs"))
+	assert.True(strings.Contains(gotMessage, "failed after 1s: no channels"))
+	assert.True(strings.Index(gotMessage, "13:00:00") < strings.Index(gotMessage, "12:00:00"))
+}
@@ -0,0 +1,143 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// ModulePins is the name of the pins module.
+	ModulePins = "pins"
+
+	// ActionPin is the pin message reaction action id.
+	ActionPin = "pin"
+
+	// ActionUnpin is the unpin message reaction action id.
+	ActionUnpin = "unpin"
+
+	// ActionPins is the list pins action id.
+	ActionPins = "pins"
+
+	// ReactionPin is the reaction that saves a message to the channel's pin log.
+	ReactionPin = "pushpin"
+
+	pinsFile = "pins.json"
+)
+
+// Pin is a message saved to a channel's pin log.
+type Pin struct {
+	Channel   string    `json:"channel"`
+	Timestamp string    `json:"ts"`
+	User      string    `json:"user"`
+	Text      string    `json:"text"`
+	PinnedBy  string    `json:"pinned_by"`
+	Pinned    time.Time `json:"pinned"`
+}
+
+// NewPins returns a new pins module.
+func NewPins() *Pins {
+	return &Pins{
+		now: time.Now,
+	}
+}
+
+// Pins is the module that keeps a log of the messages people react to with :pushpin:, per channel.
+// Pins are persisted to `pins.json` in the data path.
+type Pins struct {
+	lock  sync.Mutex
+	store *core.JSONFileStore
+	pins  []Pin
+	now   func() time.Time
+}
+
+// Init loads the stored pins.
+func (p *Pins) Init(b core.Bot) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.store = core.NewJSONFileStore(DataFilePath(b, pinsFile))
+	p.pins = []Pin{}
+	return p.store.Load(&p.pins)
+}
+
+// Name returns the name of the module.
+func (p *Pins) Name() string {
+	return ModulePins
+}
+
+// Actions returns the actions for the module.
+func (p *Pins) Actions() []core.Action {
+	return []core.Action{
+		{ID: ActionPin, Reaction: ReactionPin, Description: "Saves the message to the channel's pin log.", Handler: p.handlePin},
+		{ID: ActionUnpin, Reaction: ReactionPin, ReactionRemoved: true, Description: "Removes the message from the channel's pin log.", Handler: p.handleUnpin},
+		{ID: ActionPins, MessagePattern: "^pins$", Description: "Prints the channel's pin log.", Handler: p.handlePins, ReplyInThread: true},
+	}
+}
+
+// PinsInChannel returns the pins for a channel, oldest first.
+func (p *Pins) PinsInChannel(channelID string) []Pin {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pins := []Pin{}
+	for _, pin := range p.pins {
+		if pin.Channel == channelID {
+			pins = append(pins, pin)
+		}
+	}
+	return pins
+}
+
+func (p *Pins) handlePin(b core.Bot, m *slack.Message) error {
+	pinned := m.Message
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, pin := range p.pins {
+		if pin.Channel == pinned.Channel && pin.Timestamp == pinned.Timestamp.String() {
+			return nil
+		}
+	}
+	p.pins = append(p.pins, Pin{
+		Channel:   pinned.Channel,
+		Timestamp: pinned.Timestamp.String(),
+		User:      pinned.User,
+		Text:      pinned.Text,
+		PinnedBy:  m.User,
+		Pinned:    p.now().UTC(),
+	})
+	return p.store.Save(p.pins)
+}
+
+func (p *Pins) handleUnpin(b core.Bot, m *slack.Message) error {
+	unpinned := m.Message
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	remaining := []Pin{}
+	for _, pin := range p.pins {
+		if pin.Channel == unpinned.Channel && pin.Timestamp == unpinned.Timestamp.String() {
+			continue
+		}
+		remaining = append(remaining, pin)
+	}
+	if len(remaining) == len(p.pins) {
+		return nil
+	}
+	p.pins = remaining
+	return p.store.Save(p.pins)
+}
+
+func (p *Pins) handlePins(b core.Bot, m *slack.Message) error {
+	pins := p.PinsInChannel(m.Channel)
+	if len(pins) == 0 {
+		return b.Replyf(m, "nothing has been pinned here yet; react to a message with :%s: to pin it.", ReactionPin)
+	}
+
+	pinsText := fmt.Sprintf("pinned messages (%d):", len(pins))
+	for _, pin := range pins {
+		pinsText = pinsText + fmt.Sprintf("\n>%s <@%s>: %s", pin.Pinned.Format("Jan 2"), pin.User, strings.Replace(pin.Text, "\n", " ", -1))
+	}
+	return b.Reply(m, pinsText)
+}
@@ -0,0 +1,54 @@
+package modules
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func mockReaction(assert *assert.Assertions, eventType, user, channel, ts, text string) *slack.Message {
+	var m slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"type":"`+eventType+`","user":"`+user+`","reaction":"pushpin","item":{"type":"message","channel":"`+channel+`","ts":"`+ts+`"},"message":{"user":"U02","text":"`+text+`","ts":"`+ts+`"}}`), &m))
+	m.Channel = channel
+	m.Message.Channel = channel
+	return &m
+}
+
+func TestPins(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigDataPath, dir)
+
+	p := NewPins()
+	p.now = func() time.Time { return time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC) }
+	assert.Nil(p.Init(mb))
+
+	assert.Nil(p.handlePin(mb, mockReaction(assert, "reaction_added", "U01", "C01", "1476900000.000001", "ship it")))
+	assert.Nil(p.handlePin(mb, mockReaction(assert, "reaction_added", "U03", "C01", "1476900000.000001", "ship it")))
+	assert.Nil(p.handlePin(mb, mockReaction(assert, "reaction_added", "U01", "C02", "1476900000.000002", "elsewhere")))
+
+	pins := p.PinsInChannel("C01")
+	assert.Len(pins, 1)
+	assert.Equal("ship it", pins[0].Text)
+	assert.Equal("U01", pins[0].PinnedBy)
+	assert.Equal("U02", pins[0].User)
+
+	reloaded := NewPins()
+	assert.Nil(reloaded.Init(mb))
+	assert.Len(reloaded.PinsInChannel("C01"), 1)
+
+	assert.Nil(p.handleUnpin(mb, mockReaction(assert, "reaction_removed", "U01", "C01", "1476900000.000001", "ship it")))
+	assert.Empty(p.PinsInChannel("C01"))
+	assert.Len(p.PinsInChannel("C02"), 1)
+}
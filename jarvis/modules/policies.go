@@ -0,0 +1,546 @@
+package modules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/blendlabs/go-util"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// ModulePolicies is the name of the channel policies module.
+	ModulePolicies = "policies"
+
+	// ActionPolicy is the show channel policy action id.
+	ActionPolicy = "policy"
+
+	// ActionPolicyKeep is the keep users in a channel action id.
+	ActionPolicyKeep = "policy.keep"
+
+	// ActionPolicyUnkeep is the stop keeping users in a channel action id.
+	ActionPolicyUnkeep = "policy.unkeep"
+
+	// ActionPolicyKeeping is the list kept users action id.
+	ActionPolicyKeeping = "policy.keeping"
+
+	// ActionPolicyBan is the ban keyword action id.
+	ActionPolicyBan = "policy.ban"
+
+	// ActionPolicyUnban is the unban keyword action id.
+	ActionPolicyUnban = "policy.unban"
+
+	// ActionPolicyTopic is the set topic policy action id.
+	ActionPolicyTopic = "policy.topic"
+
+	// ActionPolicyPurpose is the set purpose policy action id.
+	ActionPolicyPurpose = "policy.purpose"
+
+	// ActionPolicyProtect is the protect from archiving action id.
+	ActionPolicyProtect = "policy.protect"
+
+	// ActionPolicyUnprotect is the stop protecting from archiving action id.
+	ActionPolicyUnprotect = "policy.unprotect"
+
+	// ActionPolicyLog is the enforcement log action id.
+	ActionPolicyLog = "policy.log"
+
+	// PolicyRequiredMembers is the policy that keeps required members in a channel.
+	PolicyRequiredMembers = "required_members"
+
+	// PolicyBannedKeywords is the policy that warns people who use banned keywords.
+	PolicyBannedKeywords = "banned_keywords"
+
+	// PolicyTopic is the policy that keeps a channel's topic.
+	PolicyTopic = "topic"
+
+	// PolicyPurpose is the policy that keeps a channel's purpose.
+	PolicyPurpose = "purpose"
+
+	// PolicyArchive is the policy that protects a channel from being archived.
+	PolicyArchive = "archive"
+
+	// DefaultPolicyLogCapacity is the number of enforcements kept in the log.
+	DefaultPolicyLogCapacity = 500
+
+	policiesFile = "policies.json"
+)
+
+// ChannelPolicy is the set of rules enforced in a channel.
+type ChannelPolicy struct {
+	Channel         string   `json:"channel"`
+	RequiredMembers []string `json:"required_members,omitempty"`
+	BannedKeywords  []string `json:"banned_keywords,omitempty"`
+	Topic           string   `json:"topic,omitempty"`
+	Purpose         string   `json:"purpose,omitempty"`
+	ProtectArchive  bool     `json:"protect_archive,omitempty"`
+}
+
+// IsEmpty returns if the policy doesn't enforce anything.
+func (cp ChannelPolicy) IsEmpty() bool {
+	return len(cp.RequiredMembers) == 0 && len(cp.BannedKeywords) == 0 && len(cp.Topic) == 0 && len(cp.Purpose) == 0 && !cp.ProtectArchive
+}
+
+// BannedKeyword returns the first banned keyword in a message, or empty if there isn't one.
+func (cp ChannelPolicy) BannedKeyword(messageText string) string {
+	messageText = strings.ToLower(messageText)
+	for _, keyword := range cp.BannedKeywords {
+		if core.Like(messageText, fmt.Sprintf(`(^|\W)%s($|\W)`, regexp.QuoteMeta(strings.ToLower(keyword)))) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// PolicyEnforcement is a record of a policy being enforced (or set).
+type PolicyEnforcement struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Policy  string    `json:"policy"`
+	User    string    `json:"user,omitempty"`
+	Detail  string    `json:"detail"`
+}
+
+type policiesState struct {
+	Policies map[string]*ChannelPolicy `json:"policies"`
+	Log      []PolicyEnforcement       `json:"log"`
+}
+
+// NewPolicies returns a new channel policies module.
+func NewPolicies() *Policies {
+	return &Policies{
+		now: time.Now,
+	}
+}
+
+// Policies is the module that enforces per channel policies: keeping required members in the channel,
+// warning people who use banned keywords, keeping the topic and purpose, and protecting the channel from being archived.
+// Policies and a log of every enforcement are persisted to `policies.json` in the data path.
+type Policies struct {
+	lock  sync.Mutex
+	store *core.JSONFileStore
+	state policiesState
+	now   func() time.Time
+}
+
+// Init loads the stored policies.
+func (p *Policies) Init(b core.Bot) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.store = core.NewJSONFileStore(DataFilePath(b, policiesFile))
+	p.state = policiesState{}
+	if err := p.store.Load(&p.state); err != nil {
+		return err
+	}
+	if p.state.Policies == nil {
+		p.state.Policies = map[string]*ChannelPolicy{}
+	}
+	return nil
+}
+
+// Name returns the name of the module.
+func (p *Policies) Name() string {
+	return ModulePolicies
+}
+
+// Actions returns the actions for the module.
+func (p *Policies) Actions() []core.Action {
+	return []core.Action{
+		{ID: ActionPolicy, MessagePattern: "^policy$", Description: "Prints the channel's policy.", Handler: p.handlePolicy, ReplyInThread: true},
+		{ID: ActionPolicyKeeping, MessagePattern: "^keeping", Description: "Lists the users kept in the channel.", Handler: p.handleKeeping},
+		{ID: ActionPolicyKeep, MessagePattern: `^keep\b`, Description: "Keeps users in the channel (re-inviting them if they leave).", Handler: p.handleKeep},
+		{ID: ActionPolicyUnkeep, MessagePattern: `^unkeep\b`, Description: "Stops keeping users in the channel.", Handler: p.handleUnkeep},
+		{ID: ActionPolicyBan, MessagePattern: "^policy:ban ", Description: "Bans a keyword in the channel; people who use it get a warning.", Handler: p.handleBan},
+		{ID: ActionPolicyUnban, MessagePattern: "^policy:unban ", Description: "Unbans a keyword in the channel.", Handler: p.handleUnban},
+		{ID: ActionPolicyTopic, MessagePattern: "^policy:topic", Description: "Sets (and keeps) the channel topic, or `off`.", Handler: p.handleTopic},
+		{ID: ActionPolicyPurpose, MessagePattern: "^policy:purpose", Description: "Sets (and keeps) the channel purpose, or `off`.", Handler: p.handlePurpose},
+		{ID: ActionPolicyProtect, MessagePattern: "^policy:protect", Description: "Protects the channel from being archived.", Handler: p.handleProtect},
+		{ID: ActionPolicyUnprotect, MessagePattern: "^policy:unprotect", Description: "Stops protecting the channel from being archived.", Handler: p.handleUnprotect},
+		{ID: ActionPolicyLog, MessagePattern: "^policy:log", Description: "Prints the recent policy enforcements in the channel.", Handler: p.handlePolicyLog, ReplyInThread: true},
+	}
+}
+
+// Subscriptions returns the events the module subscribes to.
+func (p *Policies) Subscriptions() []core.EventSubscription {
+	return []core.EventSubscription{
+		{ID: "policies.message", Event: slack.EventMessage, Handler: p.enforceBannedKeywords},
+		{ID: "policies.channel_leave", Event: slack.EventSubtypeChannelLeave, Handler: p.enforceRequiredMembers},
+		{ID: "policies.channel_topic", Event: slack.EventSubtypeChannelTopic, Handler: p.enforceTopic},
+		{ID: "policies.channel_purpose", Event: slack.EventSubtypeChannelPurpose, Handler: p.enforcePurpose},
+		{ID: "policies.channel_archive", Event: slack.EventChannelArchive, Handler: p.enforceArchive},
+	}
+}
+
+// Policy returns (a copy of) the policy for a channel.
+func (p *Policies) Policy(channelID string) ChannelPolicy {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if policy, hasPolicy := p.state.Policies[channelID]; hasPolicy {
+		copied := *policy
+		copied.RequiredMembers = append([]string{}, policy.RequiredMembers...)
+		copied.BannedKeywords = append([]string{}, policy.BannedKeywords...)
+		return copied
+	}
+	return ChannelPolicy{Channel: channelID}
+}
+
+// Log returns the enforcements in a channel, newest first.
+func (p *Policies) Log(channelID string) []PolicyEnforcement {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	log := []PolicyEnforcement{}
+	for index := len(p.state.Log) - 1; index >= 0; index-- {
+		if p.state.Log[index].Channel == channelID {
+			log = append(log, p.state.Log[index])
+		}
+	}
+	return log
+}
+
+// update changes the policy for a channel and records the change in the log.
+func (p *Policies) update(channelID, policyName, userID, detail string, change func(policy *ChannelPolicy)) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	policy, hasPolicy := p.state.Policies[channelID]
+	if !hasPolicy {
+		policy = &ChannelPolicy{Channel: channelID}
+		p.state.Policies[channelID] = policy
+	}
+	change(policy)
+	if policy.IsEmpty() {
+		delete(p.state.Policies, channelID)
+	}
+	p.appendLog(PolicyEnforcement{Channel: channelID, Policy: policyName, User: userID, Detail: detail})
+	return p.store.Save(p.state)
+}
+
+// record adds an enforcement to the log.
+func (p *Policies) record(b core.Bot, enforcement PolicyEnforcement) {
+	b.Logf("policy `%s` enforced in `%s`: %s", enforcement.Policy, enforcement.Channel, enforcement.Detail)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.appendLog(enforcement)
+	if err := p.store.Save(p.state); err != nil {
+		b.Logf("error saving the policy log: %v", err)
+	}
+}
+
+func (p *Policies) appendLog(enforcement PolicyEnforcement) {
+	enforcement.Time = p.now().UTC()
+	p.state.Log = append(p.state.Log, enforcement)
+	if len(p.state.Log) > DefaultPolicyLogCapacity {
+		p.state.Log = p.state.Log[len(p.state.Log)-DefaultPolicyLogCapacity:]
+	}
+}
+
+func (p *Policies) handlePolicy(b core.Bot, m *slack.Message) error {
+	policy := p.Policy(m.Channel)
+	if policy.IsEmpty() {
+		return b.Reply(m, "this channel doesn't have a policy.")
+	}
+	return b.Reply(m, "policy for this channel:\n"+formatPolicy(policy))
+}
+
+func (p *Policies) handleKeep(b core.Bot, m *slack.Message) error {
+	users := policyMentionedUsers(b, m)
+	if len(users) == 0 {
+		return b.Reply(m, "Need to mention (1) valid user.")
+	}
+	err := p.update(m.Channel, PolicyRequiredMembers, m.User, fmt.Sprintf("keeping %s", formatUserMentions(users)), func(policy *ChannelPolicy) {
+		for _, user := range users {
+			if !core.EqualsAny(user, policy.RequiredMembers...) {
+				policy.RequiredMembers = append(policy.RequiredMembers, user)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return b.Replyf(m, "Keeping %s in this channel", formatUserMentions(users))
+}
+
+func (p *Policies) handleUnkeep(b core.Bot, m *slack.Message) error {
+	users := policyMentionedUsers(b, m)
+	if len(users) == 0 {
+		return b.Reply(m, "Need to mention (1) valid user.")
+	}
+	err := p.update(m.Channel, PolicyRequiredMembers, m.User, fmt.Sprintf("no longer keeping %s", formatUserMentions(users)), func(policy *ChannelPolicy) {
+		policy.RequiredMembers = filterStrings(policy.RequiredMembers, users...)
+	})
+	if err != nil {
+		return err
+	}
+	return b.Replyf(m, "No longer keeping %s in this channel", formatUserMentions(users))
+}
+
+func (p *Policies) handleKeeping(b core.Bot, m *slack.Message) error {
+	policy := p.Policy(m.Channel)
+	if len(policy.RequiredMembers) == 0 {
+		return b.Reply(m, "Not keeping any users in this channel")
+	}
+	return b.Replyf(m, "Keeping (%d) users in this channel: %s", len(policy.RequiredMembers), formatUserMentions(policy.RequiredMembers))
+}
+
+func (p *Policies) handleBan(b core.Bot, m *slack.Message) error {
+	keyword := policyArgument(m, "policy:ban")
+	if len(keyword) == 0 {
+		return exception.New("usage: `policy:ban <keyword>`")
+	}
+	err := p.update(m.Channel, PolicyBannedKeywords, m.User, fmt.Sprintf("banned `%s`", keyword), func(policy *ChannelPolicy) {
+		if !core.EqualsAny(keyword, policy.BannedKeywords...) {
+			policy.BannedKeywords = append(policy.BannedKeywords, keyword)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return b.Replyf(m, "banned `%s` in this channel.", keyword)
+}
+
+func (p *Policies) handleUnban(b core.Bot, m *slack.Message) error {
+	keyword := policyArgument(m, "policy:unban")
+	if len(keyword) == 0 {
+		return exception.New("usage: `policy:unban <keyword>`")
+	}
+	err := p.update(m.Channel, PolicyBannedKeywords, m.User, fmt.Sprintf("unbanned `%s`", keyword), func(policy *ChannelPolicy) {
+		policy.BannedKeywords = filterStrings(policy.BannedKeywords, keyword)
+	})
+	if err != nil {
+		return err
+	}
+	return b.Replyf(m, "unbanned `%s` in this channel.", keyword)
+}
+
+func (p *Policies) handleTopic(b core.Bot, m *slack.Message) error {
+	topic := policyArgument(m, "policy:topic")
+	if len(topic) == 0 {
+		return exception.New("usage: `policy:topic <topic>` (or `policy:topic off`)")
+	}
+	if strings.ToLower(topic) == "off" {
+		topic = ""
+	}
+	err := p.update(m.Channel, PolicyTopic, m.User, fmt.Sprintf("set the topic to `%s`", topic), func(policy *ChannelPolicy) {
+		policy.Topic = topic
+	})
+	if err != nil {
+		return err
+	}
+	if len(topic) == 0 {
+		return b.Reply(m, "no longer keeping the topic.")
+	}
+	b.OutboundQueue().Enqueue(m.Channel, func() error {
+		return b.Client().ChannelsSetTopic(m.Channel, topic)
+	})
+	return nil
+}
+
+func (p *Policies) handlePurpose(b core.Bot, m *slack.Message) error {
+	purpose := policyArgument(m, "policy:purpose")
+	if len(purpose) == 0 {
+		return exception.New("usage: `policy:purpose <purpose>` (or `policy:purpose off`)")
+	}
+	if strings.ToLower(purpose) == "off" {
+		purpose = ""
+	}
+	err := p.update(m.Channel, PolicyPurpose, m.User, fmt.Sprintf("set the purpose to `%s`", purpose), func(policy *ChannelPolicy) {
+		policy.Purpose = purpose
+	})
+	if err != nil {
+		return err
+	}
+	if len(purpose) == 0 {
+		return b.Reply(m, "no longer keeping the purpose.")
+	}
+	b.OutboundQueue().Enqueue(m.Channel, func() error {
+		return b.Client().ChannelsSetPurpose(m.Channel, purpose)
+	})
+	return nil
+}
+
+func (p *Policies) handleProtect(b core.Bot, m *slack.Message) error {
+	err := p.update(m.Channel, PolicyArchive, m.User, "protected from archiving", func(policy *ChannelPolicy) {
+		policy.ProtectArchive = true
+	})
+	if err != nil {
+		return err
+	}
+	return b.Reply(m, "this channel is now protected from being archived.")
+}
+
+func (p *Policies) handleUnprotect(b core.Bot, m *slack.Message) error {
+	err := p.update(m.Channel, PolicyArchive, m.User, "no longer protected from archiving", func(policy *ChannelPolicy) {
+		policy.ProtectArchive = false
+	})
+	if err != nil {
+		return err
+	}
+	return b.Reply(m, "this channel is no longer protected from being archived.")
+}
+
+func (p *Policies) handlePolicyLog(b core.Bot, m *slack.Message) error {
+	log := p.Log(m.Channel)
+	if len(log) == 0 {
+		return b.Reply(m, "no policies have been enforced in this channel.")
+	}
+	if len(log) > 10 {
+		log = log[:10]
+	}
+	logText := "recent policy enforcements:"
+	for _, enforcement := range log {
+		logText = logText + fmt.Sprintf("\n>%s `%s` %s", enforcement.Time.Format(time.RFC822), enforcement.Policy, enforcement.Detail)
+		if len(enforcement.User) != 0 {
+			logText = logText + fmt.Sprintf(" (<@%s>)", enforcement.User)
+		}
+	}
+	return b.Reply(m, logText)
+}
+
+func (p *Policies) enforceBannedKeywords(b core.Bot, m *slack.Message) error {
+	if len(m.SubType) != 0 || len(m.User) == 0 || m.User == b.ID() {
+		return nil
+	}
+	policy := p.Policy(m.Channel)
+	keyword := policy.BannedKeyword(m.Text)
+	if len(keyword) == 0 {
+		return nil
+	}
+	p.record(b, PolicyEnforcement{Channel: m.Channel, Policy: PolicyBannedKeywords, User: m.User, Detail: fmt.Sprintf("warned about `%s`", keyword)})
+	return b.DirectMessagef(m.User, "heads up, `%s` isn't allowed in <#%s>.", keyword, m.Channel)
+}
+
+func (p *Policies) enforceRequiredMembers(b core.Bot, m *slack.Message) error {
+	policy := p.Policy(m.Channel)
+	if !core.EqualsAny(m.User, policy.RequiredMembers...) {
+		return nil
+	}
+	p.record(b, PolicyEnforcement{Channel: m.Channel, Policy: PolicyRequiredMembers, User: m.User, Detail: fmt.Sprintf("re-invited <@%s>", m.User)})
+	return b.InviteUser(m.Channel, m.User)
+}
+
+func (p *Policies) enforceTopic(b core.Bot, m *slack.Message) error {
+	policy := p.Policy(m.Channel)
+	if len(policy.Topic) == 0 || m.User == b.ID() {
+		return nil
+	}
+	var event struct {
+		Topic string `json:"topic"`
+	}
+	if err := core.DecodeEvent(m, &event); err != nil {
+		return err
+	}
+	if event.Topic == policy.Topic {
+		return nil
+	}
+	p.record(b, PolicyEnforcement{Channel: m.Channel, Policy: PolicyTopic, User: m.User, Detail: fmt.Sprintf("reset the topic from `%s`", event.Topic)})
+	b.OutboundQueue().Enqueue(m.Channel, func() error {
+		return b.Client().ChannelsSetTopic(m.Channel, policy.Topic)
+	})
+	return nil
+}
+
+func (p *Policies) enforcePurpose(b core.Bot, m *slack.Message) error {
+	policy := p.Policy(m.Channel)
+	if len(policy.Purpose) == 0 || m.User == b.ID() {
+		return nil
+	}
+	var event struct {
+		Purpose string `json:"purpose"`
+	}
+	if err := core.DecodeEvent(m, &event); err != nil {
+		return err
+	}
+	if event.Purpose == policy.Purpose {
+		return nil
+	}
+	p.record(b, PolicyEnforcement{Channel: m.Channel, Policy: PolicyPurpose, User: m.User, Detail: fmt.Sprintf("reset the purpose from `%s`", event.Purpose)})
+	b.OutboundQueue().Enqueue(m.Channel, func() error {
+		return b.Client().ChannelsSetPurpose(m.Channel, policy.Purpose)
+	})
+	return nil
+}
+
+func (p *Policies) enforceArchive(b core.Bot, m *slack.Message) error {
+	// `channel_archive` is both an event and a message subtype; only act on the event.
+	if m.Type != slack.EventChannelArchive {
+		return nil
+	}
+	if !p.Policy(m.Channel).ProtectArchive {
+		return nil
+	}
+	p.record(b, PolicyEnforcement{Channel: m.Channel, Policy: PolicyArchive, User: m.User, Detail: "unarchived the channel"})
+	b.OutboundQueue().Enqueue(m.Channel, func() error {
+		return b.Client().ChannelsUnarchive(m.Channel)
+	})
+	return b.Reply(m, "this channel is protected from being archived.")
+}
+
+// formatPolicy returns the description of a policy.
+func formatPolicy(policy ChannelPolicy) string {
+	lines := []string{}
+	if len(policy.RequiredMembers) != 0 {
+		lines = append(lines, fmt.Sprintf(">keeping: %s", formatUserMentions(policy.RequiredMembers)))
+	}
+	if len(policy.BannedKeywords) != 0 {
+		keywords := []string{}
+		for _, keyword := range policy.BannedKeywords {
+			keywords = append(keywords, fmt.Sprintf("`%s`", keyword))
+		}
+		sort.Strings(keywords)
+		lines = append(lines, fmt.Sprintf(">banned: %s", strings.Join(keywords, ", ")))
+	}
+	if len(policy.Topic) != 0 {
+		lines = append(lines, fmt.Sprintf(">topic: %s", policy.Topic))
+	}
+	if len(policy.Purpose) != 0 {
+		lines = append(lines, fmt.Sprintf(">purpose: %s", policy.Purpose))
+	}
+	if policy.ProtectArchive {
+		lines = append(lines, ">protected from archiving")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatUserMentions(userIDs []string) string {
+	mentions := []string{}
+	for _, userID := range userIDs {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", userID))
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// policyMentionedUsers returns the known users mentioned in a message, other than the bot.
+func policyMentionedUsers(b core.Bot, m *slack.Message) []string {
+	users := []string{}
+	for _, userID := range core.Mentions(core.LessSpecificMention(m.Text, b.ID())) {
+		if user := b.FindUser(userID); user != nil && !core.EqualsAny(user.ID, users...) {
+			users = append(users, user.ID)
+		}
+	}
+	return users
+}
+
+// policyArgument returns the text of a message after a command, or empty if there isn't any.
+func policyArgument(m *slack.Message, command string) string {
+	messageWithoutMentions := util.String.TrimWhitespace(core.LessMentions(m.Text))
+	parts := core.ExtractSubMatches(messageWithoutMentions, fmt.Sprintf("(?i)^%s(.*)$", regexp.QuoteMeta(command)))
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+func filterStrings(values []string, remove ...string) []string {
+	filtered := []string{}
+	for _, value := range values {
+		if !core.EqualsAny(value, remove...) {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,128 @@
+package modules
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func TestChannelPolicyBannedKeyword(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := ChannelPolicy{BannedKeywords: []string{"prod", "c++"}}
+	assert.Equal("prod", policy.BannedKeyword("who broke PROD?"))
+	assert.Equal("c++", policy.BannedKeyword("rewrite it in c++"))
+	assert.Empty(policy.BannedKeyword("the product is fine"))
+	assert.False(policy.IsEmpty())
+	assert.True(ChannelPolicy{Channel: "C01"}.IsEmpty())
+}
+
+func TestPolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigDataPath, dir)
+	mb.Directory().SetUsers([]slack.User{{ID: "U01", Name: "alice"}, {ID: "U02", Name: "bob"}})
+
+	var said []*slack.Message
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		said = append(said, m)
+		return nil
+	})
+
+	p := NewPolicies()
+	p.now = func() time.Time { return time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC) }
+	assert.Nil(p.Init(mb))
+
+	assert.Nil(p.handleKeep(mb, &slack.Message{Channel: "C01", User: "U02", Text: "keep <@U01> <@UNKNOWN>"}))
+	assert.Nil(p.handleBan(mb, &slack.Message{Channel: "C01", User: "U02", Text: "policy:ban prod"}))
+	assert.Nil(p.handleProtect(mb, &slack.Message{Channel: "C01", User: "U02", Text: "policy:protect"}))
+
+	policy := p.Policy("C01")
+	assert.Equal([]string{"U01"}, policy.RequiredMembers)
+	assert.Equal([]string{"prod"}, policy.BannedKeywords)
+	assert.True(policy.ProtectArchive)
+	assert.True(p.Policy("C02").IsEmpty())
+
+	said = nil
+	var message slack.Message
+	assert.Nil(json.Unmarshal([]byte(`{"type":"message","channel":"C01","user":"U01","text":"deploying to prod","ts":"1476900000.000001"}`), &message))
+	assert.Nil(p.enforceBannedKeywords(mb, &message))
+	assert.Len(said, 1)
+	assert.Contains("`prod`", said[0].Text)
+
+	message.SubType = "bot_message"
+	assert.Nil(p.enforceBannedKeywords(mb, &message))
+	assert.Len(said, 1)
+
+	log := p.Log("C01")
+	assert.Len(log, 4)
+	assert.Equal(PolicyBannedKeywords, log[0].Policy)
+	assert.Equal("U01", log[0].User)
+	assert.Equal(PolicyRequiredMembers, log[3].Policy)
+
+	reloaded := NewPolicies()
+	assert.Nil(reloaded.Init(mb))
+	assert.Equal([]string{"U01"}, reloaded.Policy("C01").RequiredMembers)
+	assert.Len(reloaded.Log("C01"), 4)
+
+	assert.Nil(p.handleUnkeep(mb, &slack.Message{Channel: "C01", User: "U02", Text: "unkeep <@U01>"}))
+	assert.Nil(p.handleUnban(mb, &slack.Message{Channel: "C01", User: "U02", Text: "policy:unban prod"}))
+	assert.Nil(p.handleUnprotect(mb, &slack.Message{Channel: "C01", User: "U02", Text: "policy:unprotect"}))
+	assert.True(p.Policy("C01").IsEmpty())
+	assert.Empty(p.state.Policies)
+}
+
+func TestPolicyArgument(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("deploys only", policyArgument(&slack.Message{Text: "<@U00> policy:topic deploys only"}, "policy:topic"))
+	assert.Equal("deploys only", policyArgument(&slack.Message{Text: "Policy:Topic  deploys only "}, "policy:topic"))
+	assert.Equal("", policyArgument(&slack.Message{Text: "<@U00>: policy:topic"}, "policy:topic"))
+	assert.Equal("", policyArgument(&slack.Message{Text: "policy"}, "policy:topic"), "short messages don't panic")
+}
+
+func TestPoliciesTopic(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	defer mb.Close()
+	mb.SetConfig(ConfigDataPath, dir)
+
+	p := NewPolicies()
+	assert.Nil(p.Init(mb))
+	assert.Nil(p.handleTopic(mb, &slack.Message{Channel: "C01", User: "U01", Text: "policy:topic deploys only"}))
+	assert.Equal("deploys only", p.Policy("C01").Topic)
+	assert.True(mb.WaitForOutbound())
+	assert.Equal(int64(1), mb.OutboundQueue().Stats().Sent, "topic changes go through the outbound queue")
+
+	calls := mb.Outbox().Calls("channels.setTopic")
+	assert.Len(calls, 1)
+	assert.Equal("C01", calls[0].Channel)
+	assert.Equal("deploys only", calls[0].Args.Get("topic"))
+
+	changed := &slack.Message{Type: "message", SubType: "channel_topic", Channel: "C01", User: "U02", Raw: []byte(`{"topic":"anything goes"}`)}
+	assert.Nil(p.enforceTopic(mb, changed))
+	assert.True(mb.WaitForOutbound())
+	assert.Equal(int64(2), mb.OutboundQueue().Stats().Sent)
+	calls = mb.Outbox().Calls("channels.setTopic")
+	assert.Len(calls, 2)
+	assert.Equal("deploys only", calls[1].Args.Get("topic"))
+
+	assert.Nil(p.handleTopic(mb, &slack.Message{Channel: "C01", User: "U01", Text: "policy:topic off"}))
+	assert.Equal([]string{"no longer keeping the topic."}, mb.Outbox().Kind(core.MockOutboundReply).Texts())
+}
@@ -0,0 +1,515 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/blendlabs/go-exception"
+	"github.com/blendlabs/go-util"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/jobs"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// ModuleReminders is the name of the reminders module.
+	ModuleReminders = "reminders"
+
+	// ActionRemind is the create reminder action id.
+	ActionRemind = "remind"
+
+	// ActionReminders is the list reminders action id.
+	ActionReminders = "reminders"
+
+	// ActionReminderCancel is the cancel reminder action id.
+	ActionReminderCancel = "reminder.cancel"
+
+	// ActionReminderSnooze is the snooze reminder action id.
+	ActionReminderSnooze = "reminder.snooze"
+
+	// JobReminders is the name of the job that sends due reminders.
+	JobReminders = "reminders"
+
+	// DefaultReminderSnooze is how long `reminder:snooze` snoozes for by default.
+	DefaultReminderSnooze = 10 * time.Minute
+
+	remindersFile        = "reminders.json"
+	reminderTimeFormat   = "Mon Jan 2 3:04pm MST"
+	reminderRemindUsage  = "usage: `remind <me|@user|#channel> <when> to <what>`, e.g. `remind me in 2h to deploy` or `remind #team every friday at 4pm to fill in timesheets`"
+	reminderSnoozeNotice = "_(`reminder:snooze` to be reminded again in 10 minutes)_"
+)
+
+// Reminder is a message to send at a given time, optionally on a recurring schedule.
+type Reminder struct {
+	ID          string    `json:"id"`
+	CreatedBy   string    `json:"created_by"`
+	Created     time.Time `json:"created"`
+	Destination string    `json:"destination"`
+	Personal    bool      `json:"personal"`
+	Message     string    `json:"message"`
+	Due         time.Time `json:"due"`
+	Every       string    `json:"every,omitempty"`
+	Recurrence  string    `json:"recurrence,omitempty"`
+}
+
+// Recipient returns the user a reminder is for; for channel reminders this is whoever created it.
+func (r Reminder) Recipient() string {
+	if r.Personal {
+		return r.Destination
+	}
+	return r.CreatedBy
+}
+
+// Next returns the next time a recurring reminder is due after a given time, or nil if it doesn't recur.
+func (r Reminder) Next(after time.Time) *time.Time {
+	if len(r.Recurrence) == 0 {
+		return nil
+	}
+	schedule, err := jobs.ParseCron(r.Recurrence)
+	if err != nil {
+		return nil
+	}
+	return schedule.GetNextRunTime(&after)
+}
+
+// NewReminders returns a new reminders module.
+func NewReminders() *Reminders {
+	return &Reminders{
+		lastSent: map[string]Reminder{},
+		now:      time.Now,
+	}
+}
+
+// Reminders is the module that sends people (and channels) reminders.
+// Reminders are persisted to `reminders.json` in the data path and sent by the `reminders` job.
+type Reminders struct {
+	lock      sync.Mutex
+	store     *core.JSONFileStore
+	reminders []Reminder
+	lastSent  map[string]Reminder
+	now       func() time.Time
+}
+
+// Init loads the stored reminders and the reminders job.
+func (r *Reminders) Init(b core.Bot) error {
+	r.lock.Lock()
+	r.store = core.NewJSONFileStore(DataFilePath(b, remindersFile))
+	r.reminders = []Reminder{}
+	err := r.store.Load(&r.reminders)
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !b.JobManager().HasJob(JobReminders) {
+		return b.LoadJob(&remindersJob{bot: b, module: r})
+	}
+	return nil
+}
+
+// Name returns the name of the module.
+func (r *Reminders) Name() string {
+	return ModuleReminders
+}
+
+// Actions returns the actions for the module.
+func (r *Reminders) Actions() []core.Action {
+	return []core.Action{
+		{ID: ActionRemind, MessagePattern: "^remind ", Description: "Sets a reminder, e.g. `remind me in 2h to deploy`.", Handler: r.handleRemind,
+			Command:  "remind me in {duration} to {text}",
+			Slots:    map[string]core.SlotType{"duration": core.SlotDuration, "text": core.SlotText},
+			Examples: []string{"set a reminder in {duration} to {text}", "ping me in {duration} to {text}", "in {duration} tell me to {text}"}},
+		{ID: ActionReminders, MessagePattern: "^reminders$", Description: "Lists your reminders.", Handler: r.handleReminders,
+			Command: "reminders", Examples: []string{"show my reminders", "list my reminders", "what reminders do I have"}},
+		{ID: ActionReminderCancel, MessagePattern: "^reminder:cancel", Description: "Cancels a reminder by id.", Handler: r.handleReminderCancel},
+		{ID: ActionReminderSnooze, MessagePattern: "^reminder:snooze", Description: "Snoozes the last reminder you were sent, e.g. `reminder:snooze 1h`.", Handler: r.handleReminderSnooze},
+	}
+}
+
+// Reminders returns the pending reminders.
+func (r *Reminders) Reminders() []Reminder {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]Reminder{}, r.reminders...)
+}
+
+func (r *Reminders) handleRemind(b core.Bot, m *slack.Message) error {
+	messageText := util.String.TrimWhitespace(core.LessSpecificMention(m.Text, b.ID()))
+	pieces := core.ExtractSubMatches(messageText, "(?i)^remind (\\S+) (.+)$")
+	if len(pieces) < 3 {
+		return exception.New(reminderRemindUsage)
+	}
+
+	location := core.UserLocation(b.FindUser(m.User))
+	now := r.now().In(location)
+
+	reminder := Reminder{ID: newReminderID(), CreatedBy: m.User, Created: now.UTC()}
+	target := pieces[1]
+	switch {
+	case strings.ToLower(target) == "me":
+		reminder.Destination = m.User
+		reminder.Personal = true
+	case strings.ToLower(target) == "here" || strings.ToLower(target) == "us":
+		reminder.Destination = m.Channel
+	case strings.HasPrefix(target, "<@"):
+		reminder.Destination = core.MentionID(target)
+		reminder.Personal = true
+	case strings.HasPrefix(target, "<#"):
+		reminder.Destination = core.MentionID(target)
+	case strings.HasPrefix(target, "#"):
+		channel := b.FindChannelByName(target)
+		if channel == nil {
+			return b.Replyf(m, "I don't know the channel `%s`", target)
+		}
+		reminder.Destination = channel.ID
+	default:
+		return exception.New(reminderRemindUsage)
+	}
+
+	message, due, every, recurrence, err := parseReminder(pieces[2], now)
+	if err != nil {
+		return b.Replyf(m, "%v; %s", err, reminderRemindUsage)
+	}
+	reminder.Message = message
+	reminder.Due = due.UTC()
+	reminder.Every = every
+	reminder.Recurrence = recurrence
+
+	err = r.add(reminder)
+	if err != nil {
+		return err
+	}
+
+	who := reminderDestinationLabel(reminder, m.User)
+	if len(reminder.Every) != 0 {
+		return b.Replyf(m, "ok, I'll remind %s %s to %s, starting %s (id: `%s`)", who, reminder.Every, reminder.Message, due.Format(reminderTimeFormat), reminder.ID)
+	}
+	return b.Replyf(m, "ok, I'll remind %s to %s on %s (id: `%s`)", who, reminder.Message, due.Format(reminderTimeFormat), reminder.ID)
+}
+
+func (r *Reminders) handleReminders(b core.Bot, m *slack.Message) error {
+	location := core.UserLocation(b.FindUser(m.User))
+
+	var listText string
+	for _, reminder := range r.Reminders() {
+		if reminder.CreatedBy != m.User && reminder.Recipient() != m.User {
+			continue
+		}
+		listText = listText + fmt.Sprintf(">`%s` - %s - to %s on %s", reminder.ID, reminder.Message, reminderDestinationLabel(reminder, m.User), reminder.Due.In(location).Format(reminderTimeFormat))
+		if len(reminder.Every) != 0 {
+			listText = listText + fmt.Sprintf(" (%s)", reminder.Every)
+		}
+		listText = listText + "\n"
+	}
+
+	if len(listText) == 0 {
+		return b.Reply(m, "you don't have any reminders.")
+	}
+	return b.Reply(m, "your reminders:\n"+listText)
+}
+
+func (r *Reminders) handleReminderCancel(b core.Bot, m *slack.Message) error {
+	messageText := util.String.TrimWhitespace(core.LessMentions(m.Text))
+	pieces := strings.Fields(messageText)
+	if len(pieces) < 2 {
+		return exception.New("usage: `reminder:cancel <id>`")
+	}
+	reminderID := pieces[len(pieces)-1]
+
+	r.lock.Lock()
+	remaining := []Reminder{}
+	var canceled *Reminder
+	for index, reminder := range r.reminders {
+		if reminder.ID == reminderID && (reminder.CreatedBy == m.User || reminder.Recipient() == m.User) {
+			canceled = &r.reminders[index]
+			continue
+		}
+		remaining = append(remaining, reminder)
+	}
+	var err error
+	if canceled != nil {
+		r.reminders = remaining
+		err = r.store.Save(r.reminders)
+	}
+	r.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if canceled == nil {
+		return b.Replyf(m, "you don't have a reminder `%s`", reminderID)
+	}
+	return b.Replyf(m, "canceled reminder `%s` (%s)", canceled.ID, canceled.Message)
+}
+
+func (r *Reminders) handleReminderSnooze(b core.Bot, m *slack.Message) error {
+	messageText := util.String.TrimWhitespace(core.LessMentions(m.Text))
+	pieces := strings.Fields(messageText)
+
+	location := core.UserLocation(b.FindUser(m.User))
+	now := r.now().In(location)
+	due := now.Add(DefaultReminderSnooze)
+
+	if len(pieces) > 1 {
+		when := strings.Join(pieces[1:], " ")
+		var err error
+		if strings.HasPrefix(strings.ToLower(when), "until ") {
+			due, err = core.ParseWhen(when[len("until "):], now)
+		} else {
+			var snooze time.Duration
+			snooze, err = core.ParseDuration(strings.TrimPrefix(strings.ToLower(when), "for "))
+			due = now.Add(snooze)
+		}
+		if err != nil {
+			return b.Replyf(m, "%v; usage: `reminder:snooze [for <duration>|until <time>]`", err)
+		}
+	}
+
+	r.lock.Lock()
+	last, hasLast := r.lastSent[m.User]
+	if hasLast {
+		delete(r.lastSent, m.User)
+	}
+	r.lock.Unlock()
+	if !hasLast {
+		return b.Reply(m, "there's nothing to snooze.")
+	}
+
+	snoozed := last
+	snoozed.ID = newReminderID()
+	snoozed.Due = due.UTC()
+	snoozed.Every = ""
+	snoozed.Recurrence = ""
+	err := r.add(snoozed)
+	if err != nil {
+		return err
+	}
+	return b.Replyf(m, "ok, I'll remind %s to %s again on %s (id: `%s`)", reminderDestinationLabel(snoozed, m.User), snoozed.Message, due.Format(reminderTimeFormat), snoozed.ID)
+}
+
+func (r *Reminders) add(reminder Reminder) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reminders = append(r.reminders, reminder)
+	return r.store.Save(r.reminders)
+}
+
+// sendDue sends the reminders that are due, rescheduling the recurring ones.
+func (r *Reminders) sendDue(b core.Bot) error {
+	now := r.now().UTC()
+
+	r.lock.Lock()
+	due := []Reminder{}
+	remaining := []Reminder{}
+	for _, reminder := range r.reminders {
+		if reminder.Due.After(now) {
+			remaining = append(remaining, reminder)
+			continue
+		}
+		due = append(due, reminder)
+		if next := reminder.Next(now); next != nil {
+			reminder.Due = *next
+			remaining = append(remaining, reminder)
+		}
+	}
+	var err error
+	if len(due) != 0 {
+		r.reminders = remaining
+		err = r.store.Save(r.reminders)
+	}
+	for _, reminder := range due {
+		r.lastSent[reminder.Recipient()] = reminder
+	}
+	r.lock.Unlock()
+
+	for _, reminder := range due {
+		if sendErr := r.send(b, reminder); sendErr != nil {
+			b.Logf("error sending reminder `%s`: %v", reminder.ID, sendErr)
+			err = sendErr
+		}
+	}
+	return err
+}
+
+func (r *Reminders) send(b core.Bot, reminder Reminder) error {
+	if !reminder.Personal {
+		return b.Sayf(reminder.Destination, "reminder: %s", reminder.Message)
+	}
+	if reminder.CreatedBy != reminder.Destination {
+		return b.DirectMessagef(reminder.Destination, "<@%s> asked me to remind you to %s\n%s", reminder.CreatedBy, reminder.Message, reminderSnoozeNotice)
+	}
+	return b.DirectMessagef(reminder.Destination, "reminder: %s\n%s", reminder.Message, reminderSnoozeNotice)
+}
+
+// remindersJob sends due reminders.
+type remindersJob struct {
+	bot    core.Bot
+	module *Reminders
+}
+
+// Name returns the job name.
+func (rj *remindersJob) Name() string {
+	return JobReminders
+}
+
+// Schedule returns the job schedule, which defaults to every 15 seconds.
+func (rj *remindersJob) Schedule() chronometer.Schedule {
+	return jobs.ScheduleFor(rj.bot, rj.Name(), chronometer.Every(15*time.Second))
+}
+
+// Execute sends the due reminders if the module is loaded.
+func (rj *remindersJob) Execute(ct *chronometer.CancellationToken) error {
+	if !rj.bot.LoadedModules().Contains(ModuleReminders) {
+		return nil
+	}
+	return rj.module.sendDue(rj.bot)
+}
+
+// parseReminder splits reminder text of the form `<when> to <what>` or `to <what> <when>`.
+func parseReminder(text string, now time.Time) (message string, due time.Time, every, recurrence string, err error) {
+	words := strings.Fields(text)
+	for index, word := range words {
+		if index == 0 || strings.ToLower(word) != "to" || index == len(words)-1 {
+			continue
+		}
+		due, every, recurrence, err = parseReminderSchedule(strings.Join(words[:index], " "), now)
+		if err == nil {
+			message = strings.Join(words[index+1:], " ")
+			return
+		}
+	}
+
+	if len(words) > 2 && strings.ToLower(words[0]) == "to" {
+		for index := 2; index < len(words); index++ {
+			due, every, recurrence, err = parseReminderSchedule(strings.Join(words[index:], " "), now)
+			if err == nil {
+				message = strings.Join(words[1:index], " ")
+				return
+			}
+		}
+	}
+
+	err = exception.Newf("I couldn't tell when to send `%s`", text)
+	return
+}
+
+func parseReminderSchedule(text string, now time.Time) (due time.Time, every, recurrence string, err error) {
+	if !strings.HasPrefix(strings.ToLower(text), "every ") {
+		due, err = core.ParseWhen(text, now)
+		return
+	}
+
+	recurrence, err = parseRecurrence(text[len("every "):], now.Location())
+	if err != nil {
+		return
+	}
+	var schedule *jobs.CronSchedule
+	schedule, err = jobs.ParseCron(recurrence)
+	if err != nil {
+		return
+	}
+	next := schedule.GetNextRunTime(&now)
+	if next == nil {
+		err = exception.Newf("`%s` never happens", text)
+		return
+	}
+	due = next.In(now.Location())
+	every = strings.ToLower(text)
+	return
+}
+
+// parseRecurrence turns a recurrence like `friday at 4pm`, `weekday at 9:30am`, `monday and wednesday`,
+// `day`, `hour` or `15 minutes` into a cron expression in the given location.
+func parseRecurrence(text string, location *time.Location) (string, error) {
+	tokens := strings.Fields(strings.ToLower(strings.Replace(text, ",", " ", -1)))
+	hour, minute := core.DefaultHour, 0
+	hourField := ""
+	minuteInterval := 0
+	days := []string{}
+
+	for index := 0; index < len(tokens); index++ {
+		token := tokens[index]
+		switch {
+		case token == "at" || token == "on" || token == "and":
+			continue
+		case token == "day" || token == "days":
+			days = append(days, "*")
+		case token == "weekday" || token == "weekdays":
+			days = append(days, "1-5")
+		case token == "weekend" || token == "weekends":
+			days = append(days, "0,6")
+		case token == "hour":
+			hourField = "*"
+		case token == "minute":
+			minuteInterval = 1
+		case index+1 < len(tokens) && core.EqualsAny(tokens[index+1], "minutes", "mins"):
+			interval, err := strconv.Atoi(token)
+			if err != nil || interval < 1 || 60%interval != 0 {
+				return "", exception.Newf("`every %s` has to be a number of minutes that divides an hour", text)
+			}
+			minuteInterval = interval
+			index++
+		default:
+			if weekday, isWeekday := core.ParseWeekday(token); isWeekday {
+				days = append(days, strconv.Itoa(int(weekday)))
+				continue
+			}
+			clock := token
+			if index+1 < len(tokens) && core.EqualsAny(tokens[index+1], "am", "pm") {
+				clock = clock + " " + tokens[index+1]
+				index++
+			}
+			var err error
+			hour, minute, err = core.ParseClock(clock)
+			if err != nil {
+				return "", exception.Newf("I don't understand `every %s`", text)
+			}
+			if len(days) == 0 {
+				days = append(days, "*")
+			}
+		}
+	}
+
+	prefix := fmt.Sprintf("CRON_TZ=%s ", location.String())
+	switch {
+	case minuteInterval == 1:
+		return prefix + "* * * * *", nil
+	case minuteInterval > 1:
+		return prefix + fmt.Sprintf("*/%d * * * *", minuteInterval), nil
+	case len(hourField) != 0:
+		return prefix + fmt.Sprintf("0 * * * %s", daysField(days)), nil
+	case len(days) == 0:
+		return "", exception.Newf("I don't understand `every %s`", text)
+	}
+	return prefix + fmt.Sprintf("%d %d * * %s", minute, hour, daysField(days)), nil
+}
+
+func daysField(days []string) string {
+	if len(days) == 0 {
+		return "*"
+	}
+	for _, day := range days {
+		if day == "*" {
+			return "*"
+		}
+	}
+	return strings.Join(days, ",")
+}
+
+func reminderDestinationLabel(reminder Reminder, userID string) string {
+	if reminder.Personal && reminder.Destination == userID {
+		return "you"
+	}
+	if reminder.Personal {
+		return fmt.Sprintf("<@%s>", reminder.Destination)
+	}
+	return fmt.Sprintf("<#%s>", reminder.Destination)
+}
+
+func newReminderID() string {
+	return slack.UUIDv4().ToShortString()[:6]
+}
@@ -0,0 +1,152 @@
+package modules
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func mockReminders(assert *assert.Assertions, now time.Time) (*Reminders, *core.MockBot, func()) {
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigDataPath, dir)
+
+	r := NewReminders()
+	r.now = func() time.Time { return now }
+	assert.Nil(r.Init(mb))
+	return r, mb, func() { os.RemoveAll(dir) }
+}
+
+func mockUserMessage(userID, text string) *slack.Message {
+	m := core.MockMessage(text)
+	m.User = userID
+	return m
+}
+
+func TestParseReminder(t *testing.T) {
+	assert := assert.New(t)
+
+	// wednesday
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC)
+
+	message, due, every, recurrence, err := parseReminder("in 2h to deploy", now)
+	assert.Nil(err)
+	assert.Equal("deploy", message)
+	assert.Equal(now.Add(2*time.Hour), due)
+	assert.Empty(every)
+	assert.Empty(recurrence)
+
+	message, due, _, _, err = parseReminder("to deploy the thing to prod tomorrow at 10am", now)
+	assert.Nil(err)
+	assert.Equal("deploy the thing to prod", message)
+	assert.Equal(time.Date(2016, 10, 20, 10, 0, 0, 0, time.UTC), due)
+
+	message, due, every, recurrence, err = parseReminder("every Friday at 4pm to fill in timesheets", now)
+	assert.Nil(err)
+	assert.Equal("fill in timesheets", message)
+	assert.Equal(time.Date(2016, 10, 21, 16, 0, 0, 0, time.UTC), due)
+	assert.Equal("every friday at 4pm", every)
+	assert.Equal("CRON_TZ=UTC 0 16 * * 5", recurrence)
+
+	_, _, _, _, err = parseReminder("to deploy eventually", now)
+	assert.NotNil(err)
+}
+
+func TestParseRecurrence(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]string{
+		"day":                     "CRON_TZ=UTC 0 9 * * *",
+		"day at 5:30pm":           "CRON_TZ=UTC 30 17 * * *",
+		"weekday at 9:30 am":      "CRON_TZ=UTC 30 9 * * 1-5",
+		"monday and wednesday":    "CRON_TZ=UTC 0 9 * * 1,3",
+		"mondays, fridays at 4pm": "CRON_TZ=UTC 0 16 * * 1,5",
+		"hour":                    "CRON_TZ=UTC 0 * * * *",
+		"15 minutes":              "CRON_TZ=UTC */15 * * * *",
+		"noon":                    "CRON_TZ=UTC 0 12 * * *",
+	}
+	for text, expected := range cases {
+		actual, err := parseRecurrence(text, time.UTC)
+		assert.Nil(err, text)
+		assert.Equal(expected, actual, text)
+	}
+
+	for _, text := range []string{"blue moon", "7 minutes", ""} {
+		_, err := parseRecurrence(text, time.UTC)
+		assert.NotNil(err, text)
+	}
+}
+
+func TestRemindersLifecycle(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC)
+	r, mb, cleanup := mockReminders(assert, now)
+	defer cleanup()
+
+	gotMessages := []string{}
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		gotMessages = append(gotMessages, m.Text)
+		return nil
+	})
+
+	assert.Nil(r.handleRemind(mb, mockUserMessage("U01", "remind me in 2h to deploy")))
+	assert.Nil(r.handleRemind(mb, mockUserMessage("U01", "remind <#C02|team> every friday at 4pm to fill in timesheets")))
+	assert.Len(r.Reminders(), 2)
+	assert.True(r.Reminders()[0].Personal)
+	assert.Equal("U01", r.Reminders()[0].Destination)
+	assert.Equal("C02", r.Reminders()[1].Destination)
+
+	// reminders are persisted.
+	reloaded := NewReminders()
+	assert.Nil(reloaded.Init(mb))
+	assert.Len(reloaded.Reminders(), 2)
+
+	gotMessages = []string{}
+	assert.Nil(r.handleReminders(mb, mockUserMessage("U01", "reminders")))
+	assert.Len(gotMessages, 1)
+	assert.True(strings.Contains(gotMessages[0], "deploy"))
+	assert.True(strings.Contains(gotMessages[0], "every friday at 4pm"))
+
+	// nothing is due yet.
+	gotMessages = []string{}
+	assert.Nil(r.sendDue(mb))
+	assert.Empty(gotMessages)
+
+	// the personal reminder is sent and removed.
+	r.now = func() time.Time { return now.Add(2 * time.Hour) }
+	assert.Nil(r.sendDue(mb))
+	assert.Len(gotMessages, 1)
+	assert.True(strings.HasPrefix(gotMessages[0], "reminder: deploy"))
+	assert.Len(r.Reminders(), 1)
+
+	// snoozing puts it back.
+	assert.Nil(r.handleReminderSnooze(mb, mockUserMessage("U01", "reminder:snooze 1h")))
+	assert.Len(r.Reminders(), 2)
+	assert.Equal(now.Add(3*time.Hour), r.Reminders()[1].Due)
+	assert.Nil(r.handleReminderSnooze(mb, mockUserMessage("U01", "reminder:snooze")))
+	assert.True(strings.Contains(gotMessages[len(gotMessages)-1], "nothing to snooze"))
+
+	// the recurring reminder is rescheduled for the next week.
+	r.now = func() time.Time { return time.Date(2016, 10, 21, 16, 0, 0, 0, time.UTC) }
+	gotMessages = []string{}
+	assert.Nil(r.sendDue(mb))
+	assert.Len(gotMessages, 2)
+	assert.Len(r.Reminders(), 1)
+	assert.Equal(time.Date(2016, 10, 28, 16, 0, 0, 0, time.UTC), r.Reminders()[0].Due)
+
+	// only the creator or recipient can cancel.
+	reminderID := r.Reminders()[0].ID
+	assert.Nil(r.handleReminderCancel(mb, mockUserMessage("U02", "reminder:cancel "+reminderID)))
+	assert.Len(r.Reminders(), 1)
+	assert.Nil(r.handleReminderCancel(mb, mockUserMessage("U01", "reminder:cancel "+reminderID)))
+	assert.Empty(r.Reminders())
+}
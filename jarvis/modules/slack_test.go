@@ -0,0 +1,45 @@
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func mockDeleteReaction(b core.Bot, userID string) *slack.Message {
+	var reaction slack.Message
+	json.Unmarshal([]byte(`{"type":"reaction_added","reaction":"x","item":{"type":"message","channel":"C01","ts":"1476900001.000001"}}`), &reaction)
+	reaction.User = userID
+	reaction.ItemUser = b.ID()
+	reaction.Message = &slack.Message{User: b.ID()}
+	return &reaction
+}
+
+func TestSlackDeleteReply(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	defer mb.Close()
+	mb.AddUser(slack.User{ID: "U01", Name: "alice"})
+	mb.AddUser(slack.User{ID: "U02", Name: "bob"})
+	mb.AddUser(slack.User{ID: "U03", Name: "carol", IsAdmin: true})
+
+	reaction := mockDeleteReaction(mb, "U01")
+	mb.Correlations().Add("C01", "1476900000.000001", "U01", *reaction.Item.Timestamp)
+
+	s := NewSlack()
+	assert.Nil(s.handleDeleteReply(mb, mockDeleteReaction(mb, "U02")))
+	assert.Empty(mb.Outbox().Calls("chat.delete"), "people can't delete replies to other people")
+
+	assert.Nil(s.handleDeleteReply(mb, reaction))
+	mb.WaitForOutbound()
+	assert.Len(mb.Outbox().Calls("chat.delete"), 1)
+
+	mb.Correlations().Remove("C01", "1476900000.000001")
+	assert.Nil(s.handleDeleteReply(mb, mockDeleteReaction(mb, "U03")))
+	mb.WaitForOutbound()
+	assert.Len(mb.Outbox().Calls("chat.delete"), 2, "admins can delete any message")
+}
@@ -0,0 +1,460 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/jobs"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// ModuleStandup is the name of the standup module.
+	ModuleStandup = "standup"
+
+	// ActionStandup is the standup status action id.
+	ActionStandup = "standup"
+
+	// ActionStandupStart is the start standup action id.
+	ActionStandupStart = "standup.start"
+
+	// ActionStandupSummary is the post standup summary action id.
+	ActionStandupSummary = "standup.summary"
+
+	// JobStandup is the name of the job that starts the standup.
+	JobStandup = "standup"
+
+	// JobStandupSummary is the name of the job that posts the standup summary once the window closes.
+	JobStandupSummary = "standup.summary"
+
+	// ConfigStandupChannel is the config entry for the channel whose members take part in the standup.
+	ConfigStandupChannel = "standup.channel"
+
+	// ConfigStandupSummaryChannel is the config entry for the channel the summary is posted to, which defaults to the standup channel.
+	ConfigStandupSummaryChannel = "standup.summary_channel"
+
+	// ConfigStandupQuestions is the config entry for the `|` separated standup questions.
+	ConfigStandupQuestions = "standup.questions"
+
+	// ConfigStandupWindow is the config entry for how long people have to answer (e.g. `2h`) before the summary is posted.
+	ConfigStandupWindow = "standup.window"
+
+	// DefaultStandupWindow is the default time people have to answer.
+	DefaultStandupWindow = 2 * time.Hour
+
+	// DefaultStandupSchedule is the default standup schedule, 9:30am UTC on weekdays.
+	DefaultStandupSchedule = "30 9 * * 1-5"
+)
+
+var (
+	// DefaultStandupQuestions are the questions asked if none are configured.
+	DefaultStandupQuestions = []string{
+		"What did you do yesterday?",
+		"What are you working on today?",
+		"Is anything blocking you?",
+	}
+)
+
+// NewStandup returns a new standup module.
+func NewStandup() *Standup {
+	return &Standup{
+		members: channelMembers,
+		now:     time.Now,
+	}
+}
+
+// Standup is the module that runs an async standup; it asks each member of the standup channel
+// the standup questions over dm and posts a summary of their answers when everyone's answered or the window closes.
+type Standup struct {
+	lock    sync.Mutex
+	round   *standupRound
+	members func(b core.Bot, channelID string) ([]string, error)
+	now     func() time.Time
+}
+
+type standupRound struct {
+	Channel        string
+	SummaryChannel string
+	Questions      []string
+	Members        []string
+	Answers        map[string][]string
+	Skipped        map[string]bool
+	Started        time.Time
+	Deadline       time.Time
+	Summarized     bool
+
+	sessions map[string]*core.Session
+}
+
+func (sr *standupRound) isFinished(userID string) bool {
+	return sr.Skipped[userID] || len(sr.Answers[userID]) >= len(sr.Questions)
+}
+
+func (sr *standupRound) isComplete() bool {
+	for _, member := range sr.Members {
+		if !sr.isFinished(member) {
+			return false
+		}
+	}
+	return true
+}
+
+// Init loads the standup jobs.
+func (s *Standup) Init(b core.Bot) error {
+	if !b.JobManager().HasJob(JobStandup) {
+		if err := b.LoadJob(&standupJob{bot: b, module: s}); err != nil {
+			return err
+		}
+	}
+	if !b.JobManager().HasJob(JobStandupSummary) {
+		return b.LoadJob(&standupSummaryJob{bot: b, module: s})
+	}
+	return nil
+}
+
+// Name returns the name of the module.
+func (s *Standup) Name() string {
+	return ModuleStandup
+}
+
+// Actions returns the actions for the module.
+func (s *Standup) Actions() []core.Action {
+	return []core.Action{
+		{ID: ActionStandup, MessagePattern: "^standup$", Description: "Prints who has answered the current standup.", Handler: s.handleStandupStatus},
+		{ID: ActionStandupStart, MessagePattern: "^standup:start", Description: "Starts the standup now.", Handler: s.handleStandupStart},
+		{ID: ActionStandupSummary, MessagePattern: "^standup:summary", Description: "Posts the standup summary now.", Handler: s.handleStandupSummary},
+	}
+}
+
+func (s *Standup) handleStandupStatus(b core.Bot, m *slack.Message) error {
+	s.lock.Lock()
+	round := s.round
+	if round == nil || round.Summarized {
+		s.lock.Unlock()
+		return b.Reply(m, "there's no standup running.")
+	}
+	var answered, waiting []string
+	for _, member := range round.Members {
+		if round.isFinished(member) {
+			answered = append(answered, fmt.Sprintf("<@%s>", member))
+		} else {
+			waiting = append(waiting, fmt.Sprintf("<@%s>", member))
+		}
+	}
+	deadline := round.Deadline
+	s.lock.Unlock()
+
+	statusText := fmt.Sprintf("standup for <#%s> closes at %s UTC\n", round.Channel, deadline.UTC().Format(time.Kitchen))
+	statusText = statusText + fmt.Sprintf(">answered: %s\n", strings.Join(answered, ", "))
+	statusText = statusText + fmt.Sprintf(">waiting on: %s", strings.Join(waiting, ", "))
+	return b.Reply(m, statusText)
+}
+
+func (s *Standup) handleStandupStart(b core.Bot, m *slack.Message) error {
+	if err := s.start(b); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	memberCount := len(s.round.Members)
+	s.lock.Unlock()
+	return b.Replyf(m, "started the standup with %d people.", memberCount)
+}
+
+func (s *Standup) handleStandupSummary(b core.Bot, m *slack.Message) error {
+	s.lock.Lock()
+	running := s.round != nil && !s.round.Summarized
+	s.lock.Unlock()
+	if !running {
+		return b.Reply(m, "there's no standup running.")
+	}
+	return s.summarize(b)
+}
+
+// start starts a standup round, posting the summary of any unfinished previous round first.
+func (s *Standup) start(b core.Bot) error {
+	channelValue := b.Configuration()[ConfigStandupChannel]
+	if core.IsEmpty(channelValue) {
+		return exception.Newf("`%s` isn't set; set it with `config:%s <channel>`", ConfigStandupChannel, ConfigStandupChannel)
+	}
+	channelID := b.ResolveChannelID(channelValue)
+	summaryChannelID := channelID
+	if summaryValue := b.Configuration()[ConfigStandupSummaryChannel]; !core.IsEmpty(summaryValue) {
+		summaryChannelID = b.ResolveChannelID(summaryValue)
+	}
+
+	err := s.summarize(b)
+	if err != nil {
+		b.Logf("error posting the previous standup summary: %v", err)
+	}
+
+	memberIDs, err := s.members(b, channelID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	round := &standupRound{
+		Channel:        channelID,
+		SummaryChannel: summaryChannelID,
+		Questions:      standupQuestions(b),
+		Answers:        map[string][]string{},
+		Skipped:        map[string]bool{},
+		Started:        now,
+		Deadline:       now.Add(standupWindow(b)),
+		sessions:       map[string]*core.Session{},
+	}
+	for _, memberID := range memberIDs {
+		user := b.FindUser(memberID)
+		if memberID == b.ID() || user == nil || user.IsBot || user.Deleted {
+			continue
+		}
+		round.Members = append(round.Members, memberID)
+
+		session := core.NewSession(memberID, core.SessionDirectMessages, s.handleAnswer)
+		session.Timeout = round.Deadline.Sub(now)
+		session.PassThrough = core.PassCommands
+		round.sessions[memberID] = session
+	}
+
+	s.lock.Lock()
+	s.round = round
+	s.lock.Unlock()
+
+	for _, memberID := range round.Members {
+		b.Sessions().Start(round.sessions[memberID])
+
+		err = b.DirectMessagef(memberID, "it's time for the <#%s> standup! (reply `skip` to skip today)\n*%s*", round.Channel, round.Questions[0])
+		if err != nil {
+			b.Logf("error starting standup with `%s`: %v", memberID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Standup) handleAnswer(b core.Bot, m *slack.Message, session *core.Session) error {
+	s.lock.Lock()
+	round := s.round
+	if round == nil || round.Summarized {
+		s.lock.Unlock()
+		session.End()
+		return b.Reply(m, "sorry, the standup is already over.")
+	}
+
+	answer := strings.TrimSpace(m.Text)
+	if strings.ToLower(answer) == "skip" {
+		round.Skipped[m.User] = true
+		delete(round.Answers, m.User)
+		complete := round.isComplete()
+		s.lock.Unlock()
+		session.End()
+		if err := b.Reply(m, "ok, skipping you today."); err != nil {
+			return err
+		}
+		if complete {
+			return s.summarize(b)
+		}
+		return nil
+	}
+
+	round.Answers[m.User] = append(round.Answers[m.User], answer)
+	if !round.isFinished(m.User) {
+		nextQuestion := round.Questions[len(round.Answers[m.User])]
+		s.lock.Unlock()
+		return b.Replyf(m, "*%s*", nextQuestion)
+	}
+	complete := round.isComplete()
+	s.lock.Unlock()
+
+	session.End()
+	if err := b.Reply(m, "thanks! that's everything."); err != nil {
+		return err
+	}
+	if complete {
+		return s.summarize(b)
+	}
+	return nil
+}
+
+// summarize posts the summary for the current round, if it hasn't been posted already.
+func (s *Standup) summarize(b core.Bot) error {
+	s.lock.Lock()
+	round := s.round
+	if round == nil || round.Summarized {
+		s.lock.Unlock()
+		return nil
+	}
+	round.Summarized = true
+	message := standupSummary(b, round)
+	s.lock.Unlock()
+
+	// end the round's own sessions, rather than whatever the members are in the middle of right now.
+	for _, session := range round.sessions {
+		session.End()
+	}
+	err := b.PostMessage(message)
+	return err
+}
+
+// summarizeIfDue posts the summary for the current round if its window has closed.
+func (s *Standup) summarizeIfDue(b core.Bot) error {
+	s.lock.Lock()
+	due := s.round != nil && !s.round.Summarized && s.now().UTC().After(s.round.Deadline)
+	s.lock.Unlock()
+	if !due {
+		return nil
+	}
+	return s.summarize(b)
+}
+
+// standupSummary builds the summary message for a round; there's an attachment per person who
+// answered and one listing the people who didn't.
+func standupSummary(b core.Bot, round *standupRound) *slack.ChatMessage {
+	message := slack.NewChatMessage(round.SummaryChannel, fmt.Sprintf("standup summary for <#%s> (%s)", round.Channel, round.Started.Format("Monday, January 2")))
+	message.AsUser = slack.OptionalBool(true)
+	message.UnfurlLinks = slack.OptionalBool(false)
+
+	var noResponse, skipped []string
+	for _, member := range round.Members {
+		answers := round.Answers[member]
+		if round.Skipped[member] {
+			skipped = append(skipped, fmt.Sprintf("<@%s>", member))
+			continue
+		}
+		if len(answers) == 0 {
+			noResponse = append(noResponse, fmt.Sprintf("<@%s>", member))
+			continue
+		}
+
+		item := slack.ChatMessageAttachment{
+			Color:      slack.OptionalString("#36a64f"),
+			AuthorName: slack.OptionalString(userDisplayName(b, member)),
+			Fallback:   slack.OptionalString(fmt.Sprintf("%s: %s", userDisplayName(b, member), strings.Join(answers, " / "))),
+		}
+		for index, question := range round.Questions {
+			value := "_no answer_"
+			if index < len(answers) {
+				value = answers[index]
+			}
+			item.Fields = append(item.Fields, slack.Field{Title: question, Value: value})
+		}
+		message.Attachments = append(message.Attachments, item)
+	}
+
+	if len(noResponse) != 0 {
+		noResponseText := strings.Join(noResponse, ", ")
+		message.Attachments = append(message.Attachments, slack.ChatMessageAttachment{
+			Color:    slack.OptionalString("#FF0000"),
+			Title:    slack.OptionalString("No response"),
+			Text:     slack.OptionalString(noResponseText),
+			Fallback: slack.OptionalString("no response: " + noResponseText),
+		})
+	}
+	if len(skipped) != 0 {
+		skippedText := strings.Join(skipped, ", ")
+		message.Attachments = append(message.Attachments, slack.ChatMessageAttachment{
+			Title:    slack.OptionalString("Skipped"),
+			Text:     slack.OptionalString(skippedText),
+			Fallback: slack.OptionalString("skipped: " + skippedText),
+		})
+	}
+	return message
+}
+
+func standupQuestions(b core.Bot) []string {
+	configured := b.Configuration()[ConfigStandupQuestions]
+	if core.IsEmpty(configured) {
+		return DefaultStandupQuestions
+	}
+	questions := []string{}
+	for _, question := range strings.Split(configured, "|") {
+		if !core.IsEmpty(question) {
+			questions = append(questions, strings.TrimSpace(question))
+		}
+	}
+	if len(questions) == 0 {
+		return DefaultStandupQuestions
+	}
+	return questions
+}
+
+func standupWindow(b core.Bot) time.Duration {
+	configured := b.Configuration()[ConfigStandupWindow]
+	if core.IsEmpty(configured) {
+		return DefaultStandupWindow
+	}
+	window, err := core.ParseDuration(configured)
+	if err != nil {
+		b.Logf("invalid `%s`: %v", ConfigStandupWindow, err)
+		return DefaultStandupWindow
+	}
+	return window
+}
+
+func userDisplayName(b core.Bot, userID string) string {
+	user := b.FindUser(userID)
+	if user == nil {
+		return userID
+	}
+	if user.Profile != nil && len(user.Profile.RealName) != 0 {
+		return user.Profile.RealName
+	}
+	return user.Name
+}
+
+func channelMembers(b core.Bot, channelID string) ([]string, error) {
+	channel, err := b.Client().ChannelsInfo(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return channel.Members, nil
+}
+
+// standupJob starts the standup.
+type standupJob struct {
+	bot    core.Bot
+	module *Standup
+}
+
+// Name returns the job name.
+func (sj *standupJob) Name() string {
+	return JobStandup
+}
+
+// Schedule returns the job schedule, which defaults to 9:30am UTC on weekdays.
+func (sj *standupJob) Schedule() chronometer.Schedule {
+	return jobs.ScheduleFor(sj.bot, sj.Name(), jobs.MustParseCron(DefaultStandupSchedule))
+}
+
+// Execute starts the standup if the module is loaded and a standup channel is configured.
+func (sj *standupJob) Execute(ct *chronometer.CancellationToken) error {
+	if !sj.bot.LoadedModules().Contains(ModuleStandup) || core.IsEmpty(sj.bot.Configuration()[ConfigStandupChannel]) {
+		return nil
+	}
+	return sj.module.start(sj.bot)
+}
+
+// standupSummaryJob posts the standup summary when the window closes.
+type standupSummaryJob struct {
+	bot    core.Bot
+	module *Standup
+}
+
+// Name returns the job name.
+func (ssj *standupSummaryJob) Name() string {
+	return JobStandupSummary
+}
+
+// Schedule returns the job schedule.
+func (ssj *standupSummaryJob) Schedule() chronometer.Schedule {
+	return chronometer.EveryMinute()
+}
+
+// Execute posts the summary if the standup window has closed.
+func (ssj *standupSummaryJob) Execute(ct *chronometer.CancellationToken) error {
+	return ssj.module.summarizeIfDue(ssj.bot)
+}
@@ -0,0 +1,157 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func mockDirectMessage(userID, text string) *slack.Message {
+	return &slack.Message{Channel: "DTESTDM", User: userID, Text: text}
+}
+
+func TestStandupRound(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigStandupChannel, "CTEAM")
+	mb.SetConfig(ConfigStandupQuestions, "What did you do? | What will you do?")
+
+	gotMessages := []string{}
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		gotMessages = append(gotMessages, m.Text)
+		return nil
+	})
+
+	now := time.Date(2016, 10, 19, 9, 30, 0, 0, time.UTC)
+	s := NewStandup()
+	s.now = func() time.Time { return now }
+	s.members = func(b core.Bot, channelID string) ([]string, error) {
+		assert.Equal("CTEAM", channelID)
+		return []string{"U01", "U02", "U03", mb.ID()}, nil
+	}
+
+	assert.Nil(s.start(mb))
+	assert.Len(s.round.Members, 3)
+	assert.Len(gotMessages, 3)
+	assert.True(strings.Contains(gotMessages[0], "What did you do?"))
+	assert.Len(mb.Sessions().Active(), 3)
+
+	// commands still go to their actions rather than being taken as answers.
+	mb.AddAction(core.Action{ID: "remind", MessagePattern: "^remind ", Handler: func(b core.Bot, m *slack.Message) error { return nil }})
+	handled, err := mb.Sessions().Handle(mb, mockDirectMessage("U01", "remind me in 1h to deploy"))
+	assert.False(handled)
+	assert.Nil(err)
+	assert.Empty(s.round.Answers["U01"])
+
+	handled, err = mb.Sessions().Handle(mb, mockDirectMessage("U01", "fixed the build"))
+	assert.True(handled)
+	assert.Nil(err)
+	assert.True(strings.Contains(gotMessages[len(gotMessages)-1], "What will you do?"))
+
+	handled, err = mb.Sessions().Handle(mb, mockDirectMessage("U01", "break the build"))
+	assert.True(handled)
+	assert.Nil(err)
+	assert.Equal([]string{"fixed the build", "break the build"}, s.round.Answers["U01"])
+	assert.Nil(mb.Sessions().Get("U01", "DTESTDM"))
+
+	handled, err = mb.Sessions().Handle(mb, mockDirectMessage("U02", "skip"))
+	assert.True(handled)
+	assert.Nil(err)
+	assert.True(s.round.Skipped["U02"])
+	assert.False(s.round.isComplete())
+
+	// messages from people without a session aren't handled.
+	handled, _ = mb.Sessions().Handle(mb, mockDirectMessage("U04", "hello"))
+	assert.False(handled)
+
+	summary := standupSummary(mb, s.round)
+	assert.Equal("CTEAM", summary.Channel)
+	assert.Len(summary.Attachments, 3)
+	assert.Len(summary.Attachments[0].Fields, 2)
+	assert.Equal("What did you do?", summary.Attachments[0].Fields[0].Title)
+	assert.Equal("fixed the build", summary.Attachments[0].Fields[0].Value)
+	assert.Equal("<@U03>", *summary.Attachments[1].Text)
+	assert.Equal("<@U02>", *summary.Attachments[2].Text)
+
+	// the summary isn't due until the window closes.
+	assert.Nil(s.summarizeIfDue(mb))
+	assert.False(s.round.Summarized)
+}
+
+func TestStandupInterruptedByConfirm(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigStandupChannel, "CTEAM")
+	mb.SetConfig(ConfigStandupQuestions, "What did you do?")
+
+	s := NewStandup()
+	s.members = func(b core.Bot, channelID string) ([]string, error) {
+		return []string{"U01"}, nil
+	}
+	assert.Nil(s.start(mb))
+
+	confirmed := false
+	err := core.NewConversation(mockDirectMessage("U01", "module:unload jira")).Confirm(mb, "are you sure?", func(b core.Bot, m *slack.Message) error {
+		confirmed = true
+		return nil
+	})
+	assert.Nil(err)
+
+	// the confirm gets the first reply, then the standup picks back up.
+	handled, err := mb.Sessions().Handle(mb, mockDirectMessage("U01", "yes"))
+	assert.True(handled)
+	assert.Nil(err)
+	assert.True(confirmed)
+	assert.Empty(s.round.Answers["U01"])
+
+	handled, err = mb.Sessions().Handle(mb, mockDirectMessage("U01", "fixed the build"))
+	assert.True(handled)
+	assert.Nil(err)
+	assert.Equal([]string{"fixed the build"}, s.round.Answers["U01"])
+	assert.Nil(mb.Sessions().Get("U01", "DTESTDM"))
+}
+
+func TestStandupSummaryLeavesOtherSessions(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigStandupChannel, "CTEAM")
+
+	s := NewStandup()
+	s.members = func(b core.Bot, channelID string) ([]string, error) {
+		return []string{"U01"}, nil
+	}
+	assert.Nil(s.start(mb))
+	assert.Nil(core.NewConversation(mockDirectMessage("U01", "module:unload jira")).Confirm(mb, "are you sure?", func(b core.Bot, m *slack.Message) error {
+		return nil
+	}))
+
+	assert.Nil(s.summarize(mb))
+	assert.Len(mb.Sessions().Active(), 1)
+	assert.NotNil(mb.Sessions().Get("U01", "DTESTDM"))
+}
+
+func TestStandupConfiguration(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	assert.Equal(DefaultStandupQuestions, standupQuestions(mb))
+	assert.Equal(DefaultStandupWindow, standupWindow(mb))
+
+	mb.SetConfig(ConfigStandupQuestions, "one|two | |three")
+	mb.SetConfig(ConfigStandupWindow, "90 minutes")
+	assert.Equal([]string{"one", "two", "three"}, standupQuestions(mb))
+	assert.Equal(90*time.Minute, standupWindow(mb))
+
+	assert.Equal("CTESTCHANNEL", mb.ResolveChannelID("#test-channel"))
+	assert.Equal("C02", mb.ResolveChannelID("<#C02|team>"))
+	assert.Equal("C03", mb.ResolveChannelID("C03"))
+
+	assert.NotNil(NewStandup().start(mb))
+}
@@ -9,9 +9,9 @@ import (
 
 	"github.com/blendlabs/go-exception"
 	"github.com/blendlabs/go-util"
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
 	"github.com/wcharczuk/jarvis/jarvis/external"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
@@ -39,8 +39,24 @@ func (s *Stocks) Name() string {
 // Actions returns the actions for the module.
 func (s *Stocks) Actions() []core.Action {
 	return []core.Action{
-		core.Action{ID: ActionStockPrice, MessagePattern: "^stock:price", Description: "Fetches the current price and volume for a given ticker.", Handler: s.handleStockPrice},
-		core.Action{ID: ActionStockChart, MessagePattern: "^stock:chart", Description: "Fetches the current price chart for a given ticker.", Handler: s.handleStockChart},
+		core.Action{
+			ID:             ActionStockPrice,
+			MessagePattern: "^stock:price",
+			Description:    "Fetches the current price and volume for a given ticker.",
+			Handler:        s.handleStockPrice,
+			Command:        "stock:price {ticker}",
+			Slots:          map[string]core.SlotType{"ticker": core.SlotTicker},
+			Examples:       []string{"what's {ticker} trading at", "what is the stock price of {ticker}", "how much is {ticker} stock", "price check on {ticker}"},
+		},
+		core.Action{
+			ID:             ActionStockChart,
+			MessagePattern: "^stock:chart",
+			Description:    "Fetches the current price chart for a given ticker.",
+			Handler:        s.handleStockChart,
+			Command:        "stock:chart {ticker}",
+			Slots:          map[string]core.SlotType{"ticker": core.SlotTicker},
+			Examples:       []string{"show me a chart of {ticker}", "graph {ticker} stock", "how has {ticker} been doing"},
+		},
 	}
 }
 
@@ -64,19 +80,20 @@ func (s *Stocks) handleStockPrice(b core.Bot, m *slack.Message) error {
 		return err
 	}
 	if len(stockInfo) == 0 {
-		return b.Sayf(m.Channel, "No stock information returned for: `%s`", strings.Join(tickers, ", "))
+		return b.Replyf(m, "No stock information returned for: `%s`", strings.Join(tickers, ", "))
 	}
-	return s.announceStocks(b, m.Channel, stockInfo)
+	return s.announceStocks(b, m, stockInfo)
 }
 
-func (s *Stocks) announceStocks(b core.Bot, destinationID string, stockInfo []external.StockInfo) error {
+func (s *Stocks) announceStocks(b core.Bot, m *slack.Message, stockInfo []external.StockInfo) error {
 	tickersLabels := []string{}
 	for _, stock := range stockInfo {
 		tickersLabels = append(tickersLabels, fmt.Sprintf("`%s`", stock.Ticker))
 	}
 	tickersLabel := strings.Join(tickersLabels, " ")
 	leadText := fmt.Sprintf("current equity price info for %s", tickersLabel)
-	message := slack.NewChatMessage(destinationID, leadText)
+	message := slack.NewChatMessage(m.Channel, leadText)
+	message.ThreadTimestamp = core.ThreadTimestamp(m)
 	message.AsUser = slack.OptionalBool(true)
 	message.UnfurlLinks = slack.OptionalBool(false)
 	message.Parse = util.OptionalString("full")
@@ -110,7 +127,7 @@ func (s *Stocks) announceStocks(b core.Bot, destinationID string, stockInfo []ex
 
 		message.Attachments = append(message.Attachments, item)
 	}
-	_, err := b.Client().ChatPostMessage(message)
+	err := b.ReplyMessage(m, message)
 	return err
 }
 
@@ -141,6 +158,7 @@ func (s *Stocks) handleStockChart(b core.Bot, m *slack.Message) error {
 
 	leadText := fmt.Sprintf("Historical Chart for `%s`", ticker)
 	message := slack.NewChatMessage(m.Channel, leadText)
+	message.ThreadTimestamp = core.ThreadTimestamp(m)
 	message.AsUser = slack.OptionalBool(true)
 	message.UnfurlLinks = slack.OptionalBool(false)
 	message.Parse = util.OptionalString("full")
@@ -150,6 +168,6 @@ func (s *Stocks) handleStockChart(b core.Bot, m *slack.Message) error {
 			ImageURL: util.OptionalString(imageURL),
 		},
 	}
-	_, err := b.Client().ChatPostMessage(message)
+	err := b.ReplyMessage(m, message)
 	return err
 }
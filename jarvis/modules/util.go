@@ -3,8 +3,8 @@ package modules
 import (
 	"fmt"
 
-	"github.com/wcharczuk/go-slack"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
 )
 
 const (
@@ -40,8 +40,12 @@ func (u Util) handleUserID(b core.Bot, m *slack.Message) error {
 	outputText := "I looked up the following users:\n"
 	for _, userID := range mentionedUserIDs {
 		user := b.FindUser(userID)
-		outputText = outputText + fmt.Sprintf("> %s : %s %s", userID, user.Profile.FirstName, user.Profile.LastName)
+		if user == nil {
+			outputText = outputText + fmt.Sprintf("> %s : unknown user\n", userID)
+			continue
+		}
+		outputText = outputText + fmt.Sprintf("> %s : %s (@%s)\n", userID, userDisplayName(b, userID), user.Name)
 	}
 
-	return b.Say(m.Channel, outputText)
+	return b.Reply(m, outputText)
 }
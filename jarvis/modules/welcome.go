@@ -0,0 +1,356 @@
+package modules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/jobs"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// ModuleWelcome is the name of the welcome module.
+	ModuleWelcome = "welcome"
+
+	// ActionWelcomePreview is the preview welcome message action id.
+	ActionWelcomePreview = "welcome.preview"
+
+	// ActionWelcomeSummary is the joins summary action id.
+	ActionWelcomeSummary = "welcome.summary"
+
+	// SubscriptionWelcomeTeamJoin is the id of the subscription to people joining the team.
+	SubscriptionWelcomeTeamJoin = "welcome.team_join"
+
+	// SubscriptionWelcomeChannelJoin is the id of the subscription to people joining channels.
+	SubscriptionWelcomeChannelJoin = "welcome.channel_join"
+
+	// JobWelcomeSummary is the name of the job that sends admins the summary of who joined this week.
+	JobWelcomeSummary = "welcome.summary"
+
+	// ConfigWelcomeMessage is the config entry for the message people are sent when they join the team.
+	ConfigWelcomeMessage = "welcome.message"
+
+	// ConfigWelcomeChannelMessage is the config entry (by channel name) for the message people are sent when they join a channel.
+	ConfigWelcomeChannelMessage = "welcome.channel.%s"
+
+	// ConfigWelcomeChannels is the config entry for the comma separated channels people are invited to when they join the team.
+	ConfigWelcomeChannels = "welcome.channels"
+
+	// ConfigWelcomeAdmins is the config entry for the comma separated users sent the weekly summary; it defaults to the team admins.
+	ConfigWelcomeAdmins = "welcome.admins"
+
+	// DefaultWelcomeSummarySchedule is the default schedule for the weekly summary, 9am UTC on mondays.
+	DefaultWelcomeSummarySchedule = "0 9 * * 1"
+
+	// WelcomeSummaryWindow is how far back the summary goes.
+	WelcomeSummaryWindow = 7 * 24 * time.Hour
+
+	welcomeFile  = "welcome.json"
+	welcomeUsage = "templates can use `{name}`, `{user}`, `{channel}`, `{channel_name}` and `{team}`"
+)
+
+// WelcomeJoin is someone joining the team, or (if it has a channel) a channel.
+type WelcomeJoin struct {
+	User    string    `json:"user"`
+	Channel string    `json:"channel,omitempty"`
+	Joined  time.Time `json:"joined"`
+}
+
+// NewWelcome returns a new welcome module.
+func NewWelcome() *Welcome {
+	return &Welcome{
+		now: time.Now,
+	}
+}
+
+// Welcome is the module that welcomes people to the team and to channels over dm, invites new people to
+// the default channels, and sends admins a weekly summary of who joined.
+// Joins are persisted to `welcome.json` in the data path.
+type Welcome struct {
+	lock  sync.Mutex
+	store *core.JSONFileStore
+	joins []WelcomeJoin
+	now   func() time.Time
+}
+
+// Init loads the stored joins and the summary job.
+func (w *Welcome) Init(b core.Bot) error {
+	w.lock.Lock()
+	w.store = core.NewJSONFileStore(DataFilePath(b, welcomeFile))
+	w.joins = []WelcomeJoin{}
+	err := w.store.Load(&w.joins)
+	w.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !b.JobManager().HasJob(JobWelcomeSummary) {
+		return b.LoadJob(&welcomeSummaryJob{bot: b, module: w})
+	}
+	return nil
+}
+
+// Name returns the name of the module.
+func (w *Welcome) Name() string {
+	return ModuleWelcome
+}
+
+// Actions returns the actions for the module.
+func (w *Welcome) Actions() []core.Action {
+	return []core.Action{
+		{ID: ActionWelcomePreview, MessagePattern: "^welcome:preview", Description: "Previews the welcome message for the team, or a channel (`welcome:preview #channel`).", Handler: w.handleWelcomePreview},
+		{ID: ActionWelcomeSummary, MessagePattern: "^welcome:summary", Description: "Prints who joined this week.", Handler: w.handleWelcomeSummary, ReplyInThread: true},
+	}
+}
+
+// Subscriptions returns the events the module subscribes to.
+func (w *Welcome) Subscriptions() []core.EventSubscription {
+	return []core.EventSubscription{
+		{ID: SubscriptionWelcomeTeamJoin, Event: slack.EventTeamJoin, Handler: w.handleTeamJoin},
+		{ID: SubscriptionWelcomeChannelJoin, Event: slack.EventSubtypeChannelJoin, Handler: w.handleChannelJoin},
+	}
+}
+
+// Joins returns the joins since a given time.
+func (w *Welcome) Joins(since time.Time) []WelcomeJoin {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	joins := []WelcomeJoin{}
+	for _, join := range w.joins {
+		if !join.Joined.Before(since) {
+			joins = append(joins, join)
+		}
+	}
+	return joins
+}
+
+func (w *Welcome) handleTeamJoin(b core.Bot, m *slack.Message) error {
+	var event struct {
+		User slack.User `json:"user"`
+	}
+	if err := core.DecodeEvent(m, &event); err != nil {
+		return err
+	}
+	user := event.User
+	if user.IsBot || len(user.ID) == 0 {
+		return nil
+	}
+
+	if err := w.record(WelcomeJoin{User: user.ID}); err != nil {
+		b.Logf("error saving the join for `%s`: %v", user.ID, err)
+	}
+
+	for _, channel := range welcomeChannels(b) {
+		if err := b.InviteUser(channel, user.ID); err != nil {
+			b.Logf("error inviting `%s` to `%s`: %v", user.ID, channel, err)
+		}
+	}
+
+	template := b.Configuration()[ConfigWelcomeMessage]
+	if core.IsEmpty(template) {
+		return nil
+	}
+	return b.DirectMessage(user.ID, renderWelcome(b, template, &user, nil))
+}
+
+func (w *Welcome) handleChannelJoin(b core.Bot, m *slack.Message) error {
+	if m.User == b.ID() {
+		return nil
+	}
+	user := b.FindUser(m.User)
+	if user == nil || user.IsBot {
+		return nil
+	}
+
+	if err := w.record(WelcomeJoin{User: m.User, Channel: m.Channel}); err != nil {
+		b.Logf("error saving the join for `%s`: %v", m.User, err)
+	}
+
+	channel := b.FindChannel(m.Channel)
+	template := welcomeChannelMessage(b, channel)
+	if core.IsEmpty(template) {
+		return nil
+	}
+	return b.DirectMessage(m.User, renderWelcome(b, template, user, channel))
+}
+
+func (w *Welcome) handleWelcomePreview(b core.Bot, m *slack.Message) error {
+	user := b.FindUser(m.User)
+	channels := core.Extract(m.Text, "(<#[^>]+>)")
+	if len(channels) != 0 {
+		channel := b.FindChannel(core.MentionID(channels[0]))
+		if channel == nil {
+			return b.Replyf(m, "I don't know the channel %s", channels[0])
+		}
+		template := welcomeChannelMessage(b, channel)
+		if core.IsEmpty(template) {
+			return b.Replyf(m, "there's no welcome message for <#%s>; set one with `config:%s <message>` (%s).", channel.ID, fmt.Sprintf(ConfigWelcomeChannelMessage, channel.Name), welcomeUsage)
+		}
+		return b.Replyf(m, "people joining <#%s> get:\n%s", channel.ID, renderWelcome(b, template, user, channel))
+	}
+
+	template := b.Configuration()[ConfigWelcomeMessage]
+	if core.IsEmpty(template) {
+		return b.Replyf(m, "there's no welcome message; set one with `config:%s <message>` (%s).", ConfigWelcomeMessage, welcomeUsage)
+	}
+	return b.Replyf(m, "people joining the team get:\n%s", renderWelcome(b, template, user, nil))
+}
+
+func (w *Welcome) handleWelcomeSummary(b core.Bot, m *slack.Message) error {
+	return b.Reply(m, w.summary(b))
+}
+
+// summary returns the summary of who joined the team (and which channels) in the last week.
+func (w *Welcome) summary(b core.Bot) string {
+	since := w.now().UTC().Add(-WelcomeSummaryWindow)
+	joins := w.Joins(since)
+
+	newPeople := []string{}
+	channelJoins := map[string]int{}
+	for _, join := range joins {
+		if len(join.Channel) == 0 {
+			newPeople = append(newPeople, fmt.Sprintf("<@%s> (%s)", join.User, join.Joined.Format("Mon Jan 2")))
+		} else {
+			channelJoins[join.Channel]++
+		}
+	}
+
+	if len(newPeople) == 0 && len(channelJoins) == 0 {
+		return "nobody joined this week."
+	}
+
+	summaryText := fmt.Sprintf("%d new people joined the team this week", len(newPeople))
+	if len(newPeople) != 0 {
+		summaryText = summaryText + ":\n>" + strings.Join(newPeople, "\n>")
+	}
+	if len(channelJoins) != 0 {
+		channels := []string{}
+		for channel, count := range channelJoins {
+			channels = append(channels, fmt.Sprintf("<#%s> (%d)", channel, count))
+		}
+		sort.Strings(channels)
+		summaryText = summaryText + "\nchannel joins: " + strings.Join(channels, ", ")
+	}
+	return summaryText
+}
+
+// sendSummary sends the summary to the admins.
+func (w *Welcome) sendSummary(b core.Bot) error {
+	summaryText := w.summary(b)
+	for _, admin := range welcomeAdmins(b) {
+		if err := b.DirectMessage(admin, summaryText); err != nil {
+			b.Logf("error sending the welcome summary to `%s`: %v", admin, err)
+		}
+	}
+	return nil
+}
+
+// record saves a join, forgetting joins from before the summary window.
+func (w *Welcome) record(join WelcomeJoin) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	now := w.now().UTC()
+	join.Joined = now
+	joins := []WelcomeJoin{}
+	for _, existing := range w.joins {
+		if now.Sub(existing.Joined) <= WelcomeSummaryWindow {
+			joins = append(joins, existing)
+		}
+	}
+	w.joins = append(joins, join)
+	return w.store.Save(w.joins)
+}
+
+// renderWelcome fills in the variables in a welcome message template.
+func renderWelcome(b core.Bot, template string, user *slack.User, channel *slack.Channel) string {
+	var userID, channelID, channelName string
+	if user != nil {
+		userID = user.ID
+	}
+	if channel != nil {
+		channelID = channel.ID
+		channelName = channel.Name
+	}
+	return strings.NewReplacer(
+		"{name}", core.FirstName(user),
+		"{user}", fmt.Sprintf("<@%s>", userID),
+		"{channel}", fmt.Sprintf("<#%s>", channelID),
+		"{channel_name}", channelName,
+		"{team}", b.OrganizationName(),
+	).Replace(template)
+}
+
+func welcomeChannelMessage(b core.Bot, channel *slack.Channel) string {
+	if channel == nil {
+		return ""
+	}
+	if template := b.Configuration()[fmt.Sprintf(ConfigWelcomeChannelMessage, channel.Name)]; !core.IsEmpty(template) {
+		return template
+	}
+	return b.Configuration()[fmt.Sprintf(ConfigWelcomeChannelMessage, channel.ID)]
+}
+
+func welcomeChannels(b core.Bot) []string {
+	channels := []string{}
+	for _, channel := range strings.Split(b.Configuration()[ConfigWelcomeChannels], ",") {
+		if !core.IsEmpty(channel) {
+			channels = append(channels, b.ResolveChannelID(channel))
+		}
+	}
+	return channels
+}
+
+// welcomeAdmins returns the users to send the summary to; the configured admins or the team's admins.
+func welcomeAdmins(b core.Bot) []string {
+	admins := []string{}
+	if configured := b.Configuration()[ConfigWelcomeAdmins]; !core.IsEmpty(configured) {
+		for _, admin := range strings.Split(configured, ",") {
+			admin = strings.TrimSpace(admin)
+			if strings.HasPrefix(admin, "<@") {
+				admins = append(admins, core.MentionID(admin))
+			} else if user := b.Directory().UserByName(admin); user != nil {
+				admins = append(admins, user.ID)
+			} else if len(admin) != 0 {
+				admins = append(admins, admin)
+			}
+		}
+		return admins
+	}
+	for _, user := range b.Directory().Users() {
+		if user.IsAdmin && !user.IsBot && !user.Deleted {
+			admins = append(admins, user.ID)
+		}
+	}
+	sort.Strings(admins)
+	return admins
+}
+
+// welcomeSummaryJob sends admins the weekly summary of who joined.
+type welcomeSummaryJob struct {
+	bot    core.Bot
+	module *Welcome
+}
+
+// Name returns the job name.
+func (wsj *welcomeSummaryJob) Name() string {
+	return JobWelcomeSummary
+}
+
+// Schedule returns the job schedule, which defaults to 9am UTC on mondays.
+func (wsj *welcomeSummaryJob) Schedule() chronometer.Schedule {
+	return jobs.ScheduleFor(wsj.bot, wsj.Name(), jobs.MustParseCron(DefaultWelcomeSummarySchedule))
+}
+
+// Execute sends the summary if the module is loaded.
+func (wsj *welcomeSummaryJob) Execute(ct *chronometer.CancellationToken) error {
+	if !wsj.bot.LoadedModules().Contains(ModuleWelcome) {
+		return nil
+	}
+	return wsj.module.sendSummary(wsj.bot)
+}
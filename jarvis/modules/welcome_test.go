@@ -0,0 +1,70 @@
+package modules
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+func mockWelcome(assert *assert.Assertions, now time.Time) (*Welcome, *core.MockBot, func()) {
+	dir, err := ioutil.TempDir("", "jarvis")
+	assert.Nil(err)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	mb.SetConfig(ConfigDataPath, dir)
+
+	w := NewWelcome()
+	w.now = func() time.Time { return now }
+	assert.Nil(w.Init(mb))
+	return w, mb, func() { os.RemoveAll(dir) }
+}
+
+func TestRenderWelcome(t *testing.T) {
+	assert := assert.New(t)
+
+	mb := core.NewMockBot(slack.UUIDv4().ToShortString())
+	user := &slack.User{ID: "U01", Name: "will", Profile: &slack.UserProfile{FirstName: "Will"}}
+	channel := &slack.Channel{ID: "C01", Name: "general"}
+
+	rendered := renderWelcome(mb, "hi {name} ({user}), welcome to {channel} (#{channel_name}) at {team}! see https://wiki", user, channel)
+	assert.Equal("hi Will (<@U01>), welcome to <#C01> (#general) at Test Organization! see https://wiki", rendered)
+}
+
+func TestWelcomeJoins(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2016, 10, 19, 14, 30, 0, 0, time.UTC)
+	w, mb, cleanup := mockWelcome(assert, now)
+	defer cleanup()
+
+	mb.SetConfig(ConfigWelcomeMessage, "welcome to {team}, {name}!")
+	mb.SetConfig("welcome.channel.test-channel", "welcome to {channel}!")
+
+	said := []string{}
+	mb.MockMessageHandler(func(b core.Bot, m *slack.Message) error {
+		said = append(said, m.Text)
+		return nil
+	})
+
+	teamJoin := &slack.Message{Type: slack.EventTeamJoin, Raw: []byte(`{"type":"team_join","user":{"id":"U01","name":"newhire","profile":{"first_name":"New"}}}`)}
+	assert.Nil(w.handleTeamJoin(mb, teamJoin))
+	assert.Nil(w.handleChannelJoin(mb, &slack.Message{Type: slack.EventMessage, SubType: string(slack.EventSubtypeChannelJoin), User: "U01", Channel: "CTESTCHANNEL"}))
+	assert.Equal([]string{"welcome to Test Organization, New!", "welcome to <#CTESTCHANNEL>!"}, said)
+
+	// the bot joining channels isn't a join.
+	assert.Nil(w.handleChannelJoin(mb, &slack.Message{User: mb.ID(), Channel: "CTESTCHANNEL"}))
+	assert.Len(w.Joins(now.Add(-time.Hour)), 2)
+
+	summary := w.summary(mb)
+	assert.True(strings.HasPrefix(summary, "1 new people joined the team this week:\n><@U01>"), summary)
+	assert.True(strings.Contains(summary, "channel joins: <#CTESTCHANNEL> (1)"), summary)
+
+	w.now = func() time.Time { return now.Add(8 * 24 * time.Hour) }
+	assert.Equal("nobody joined this week.", w.summary(mb))
+}
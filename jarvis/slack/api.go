@@ -1,11 +1,39 @@
 package slack
 
 import (
+	"fmt"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/blendlabs/go-exception"
+	"github.com/blendlabs/go-request"
 )
 
+// RateLimitedError is returned when slack responds `429 Too Many Requests`.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (rle *RateLimitedError) Error() string {
+	return fmt.Sprintf("ratelimited (retry after %v)", rle.RetryAfter)
+}
+
+// rateLimited returns a `RateLimitedError` if the response is a 429, using the `Retry-After` header.
+func rateLimited(meta *request.ResponseMeta) error {
+	if meta == nil || meta.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	retryAfter := time.Second
+	if meta.Headers != nil {
+		if seconds, err := strconv.Atoi(meta.Headers.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return &RateLimitedError{RetryAfter: retryAfter}
+}
+
 //--------------------------------------------------------------------------------
 // API METHODS
 //--------------------------------------------------------------------------------
@@ -15,8 +43,8 @@ func (rtm *Client) AuthTest() (*AuthTestResponse, error) {
 	res := AuthTestResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/auth.test").
 		WithPostData("token", rtm.Token).
 		JSON(&res)
@@ -54,12 +82,12 @@ func (rtm *Client) ChannelsHistory(channelID string, latest, oldest *time.Time,
 	res := ChannelsHistoryResponse{}
 	req := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/channels.history").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
-		WithPostData("count", string(count)).
+		WithPostData("count", strconv.Itoa(count)).
 		WithPostData("unreads", unreadsValue)
 
 	if latest != nil {
@@ -67,7 +95,7 @@ func (rtm *Client) ChannelsHistory(channelID string, latest, oldest *time.Time,
 	}
 
 	if oldest != nil {
-		req = req.WithPostData("oldest", Timestamp{time: *latest}.String())
+		req = req.WithPostData("oldest", Timestamp{time: *oldest}.String())
 	}
 
 	err := req.JSON(&res)
@@ -91,8 +119,8 @@ func (rtm *Client) ChannelsInfo(channelID string) (*Channel, error) {
 	res := channelsInfoResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/channels.info").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
@@ -118,8 +146,8 @@ func (rtm *Client) ChannelsList(excludeArchived bool) ([]Channel, error) {
 	res := channelsListResponse{}
 	req := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/channels.list").
 		WithPostData("token", rtm.Token)
 
@@ -149,8 +177,8 @@ func (rtm *Client) ChannelsMark(channelID string, ts Timestamp) error {
 	res := basicResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/chat.mark").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
@@ -176,8 +204,8 @@ func (rtm *Client) ChannelsSetPurpose(channelID, purpose string) error {
 	res := basicResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/channels.setPurpose").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
@@ -204,8 +232,8 @@ func (rtm *Client) ChannelsSetTopic(channelID, topic string) error {
 	res := basicResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/channels.setTopic").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
@@ -227,18 +255,49 @@ func (rtm *Client) ChannelsSetTopic(channelID, topic string) error {
 	return nil
 }
 
+// ChannelsUnarchive unarchives a given Slack channel.
+func (rtm *Client) ChannelsUnarchive(channelID string) error {
+	res := basicResponse{}
+	err := NewExternalRequest().
+		AsPost().
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
+		WithPath("api/channels.unarchive").
+		WithPostData("token", rtm.Token).
+		WithPostData("channel", channelID).
+		JSON(&res)
+
+	if err != nil {
+		return err
+	}
+
+	if !IsEmpty(res.Error) {
+		return exception.New(res.Error)
+	}
+
+	if !res.OK {
+		return exception.New("slack response `ok` is false.")
+	}
+
+	return nil
+}
+
 // ChatDelete deletes a message.
 func (rtm *Client) ChatDelete(channelID string, ts Timestamp) error {
 	res := basicResponse{}
-	err := NewExternalRequest().
+	meta, err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/chat.delete").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
 		WithPostData("ts", ts.String()).
-		JSON(&res)
+		JSONWithMeta(&res)
+
+	if rateErr := rateLimited(meta); rateErr != nil {
+		return rateErr
+	}
 
 	if err != nil {
 		return err
@@ -257,14 +316,18 @@ func (rtm *Client) ChatDelete(channelID string, ts Timestamp) error {
 // ChatPostMessage posts a message to Slack using the chat api.
 func (rtm *Client) ChatPostMessage(m *ChatMessage) (*ChatMessageResponse, error) { //the response version of the message is returned for verification
 	res := ChatMessageResponse{}
-	err := NewExternalRequest().
+	meta, err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/chat.postMessage").
 		WithPostData("token", rtm.Token).
 		WithPostDataFromObject(m).
-		JSON(&res)
+		JSONWithMeta(&res)
+
+	if rateErr := rateLimited(meta); rateErr != nil {
+		return nil, rateErr
+	}
 
 	if err != nil {
 		return nil, err
@@ -284,15 +347,19 @@ func (rtm *Client) ChatPostMessage(m *ChatMessage) (*ChatMessageResponse, error)
 // ChatUpdate updates a chat message.
 func (rtm *Client) ChatUpdate(ts Timestamp, m *ChatMessage) (*ChatMessageResponse, error) { //the response version of the message is returned for verification
 	res := ChatMessageResponse{}
-	err := NewExternalRequest().
+	meta, err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/chat.update").
 		WithPostData("token", rtm.Token).
 		WithPostData("ts", ts.String()).
 		WithPostDataFromObject(m).
-		JSON(&res)
+		JSONWithMeta(&res)
+
+	if rateErr := rateLimited(meta); rateErr != nil {
+		return nil, rateErr
+	}
 
 	if err != nil {
 		return nil, err
@@ -314,8 +381,8 @@ func (rtm *Client) EmojiList() (map[string]string, error) {
 	res := emojiResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/emoji.list").
 		WithPostData("token", rtm.Token).
 		JSON(&res)
@@ -339,8 +406,8 @@ func (rtm *Client) ReactionsAdd(name string, fileID, fileCommentID, channelID *s
 	res := basicResponse{}
 	req := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/reactions.add").
 		WithPostData("token", rtm.Token).
 		WithPostData("name", name)
@@ -377,8 +444,8 @@ func (rtm *Client) ReactionsGet(fileID, fileCommentID, channelID *string, ts *Ti
 	res := ChatMessageResponse{}
 	req := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/reactions.get").
 		WithPostData("token", rtm.Token)
 
@@ -414,8 +481,8 @@ func (rtm *Client) ReactionsRemove(name string, fileID, fileCommentID, channelID
 	res := basicResponse{}
 	req := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/reactions.remove").
 		WithPostData("token", rtm.Token).
 		WithPostData("name", name)
@@ -452,8 +519,8 @@ func (rtm *Client) UsersList() ([]User, error) {
 	res := usersListResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/users.list").
 		WithPostData("token", rtm.Token).
 		JSON(&res)
@@ -474,8 +541,8 @@ func (rtm *Client) UsersInfo(userID string) (*User, error) {
 	res := usersInfoResponse{}
 	err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/users.info").
 		WithPostData("token", rtm.Token).
 		WithPostData("user", userID).
@@ -495,15 +562,19 @@ func (rtm *Client) UsersInfo(userID string) (*User, error) {
 // InviteUser invites a user to a channel.
 func (rtm *Client) InviteUser(channelID, userID string) (*Channel, error) {
 	res := channelsInfoResponse{}
-	err := NewExternalRequest().
+	meta, err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/channels.invite").
 		WithPostData("token", rtm.Token).
 		WithPostData("channel", channelID).
 		WithPostData("user", userID).
-		JSON(&res)
+		JSONWithMeta(&res)
+
+	if rateErr := rateLimited(meta); rateErr != nil {
+		return nil, rateErr
+	}
 
 	if err != nil {
 		return nil, err
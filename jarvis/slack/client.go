@@ -8,7 +8,7 @@
 //  import (
 //      "fmt"
 //      "os"
-//      "github.com/wcharczuk/go-slack"
+//      "github.com/wcharczuk/jarvis/jarvis/slack"
 //  )
 //
 //  func main() {
@@ -90,7 +90,11 @@ func NewClient(token string) *Client {
 
 // Client is the mechanism with which the package consumer interacts with Slack.
 type Client struct {
-	Token          string
+	Token string
+
+	// EventListeners are the listeners for each event; use `AddEventListener` and `RemoveEventListeners`, which
+	// are safe to call while the client is running.
+	listenersLock  sync.RWMutex
 	EventListeners map[Event][]EventListener
 
 	activeLock     sync.Mutex
@@ -101,6 +105,9 @@ type Client struct {
 
 	connected bool
 
+	// writeLock serializes writes, the websocket connection only supports one writer at a time.
+	writeLock sync.Mutex
+
 	pingTimeout      time.Duration
 	pingMaxInFlight  int
 	pingMaxFails     int
@@ -109,7 +116,14 @@ type Client struct {
 	pingInFlightLock sync.Mutex
 	pingInterval     time.Duration
 
+	pongHandler      func(rtt time.Duration)
+	reconnectHandler func()
+
 	isDebug bool
+
+	// scheme and host override `APIScheme` and `APIEndpoint` for this client.
+	scheme string
+	host   string
 }
 
 // SetDebug turns on debug logging.
@@ -117,15 +131,40 @@ func (rtm *Client) SetDebug(value bool) {
 	rtm.isDebug = value
 }
 
+// SetAPIEndpoint points the client's api calls at a different scheme and host, like a fake slack in tests.
+func (rtm *Client) SetAPIEndpoint(scheme, host string) {
+	rtm.scheme = scheme
+	rtm.host = host
+}
+
+// IsConnected returns if the client has an open websocket connection.
+func (rtm *Client) IsConnected() bool {
+	return rtm.connection() != nil
+}
+
+// OnPong sets a function that's called with the round trip time of each ping that gets a pong.
+func (rtm *Client) OnPong(handler func(rtt time.Duration)) {
+	rtm.pongHandler = handler
+}
+
+// OnReconnect sets a function that's called each time the client reconnects after the connection fails.
+func (rtm *Client) OnReconnect(handler func()) {
+	rtm.reconnectHandler = handler
+}
+
 // AddEventListener attaches a new Listener to the given event.
 // There can be multiple listeners to an event.
 // If an event is already being listened for, calling Listen will add a new listener to that event.
 func (rtm *Client) AddEventListener(event Event, handler EventListener) {
+	rtm.listenersLock.Lock()
+	defer rtm.listenersLock.Unlock()
 	rtm.EventListeners[event] = append(rtm.EventListeners[event], handler)
 }
 
 // RemoveEventListeners removes all listeners for an event.
 func (rtm *Client) RemoveEventListeners(event Event) {
+	rtm.listenersLock.Lock()
+	defer rtm.listenersLock.Unlock()
 	delete(rtm.EventListeners, event)
 }
 
@@ -137,8 +176,8 @@ func (rtm *Client) Connect() (*Session, error) {
 	res := Session{}
 	meta, err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/rtm.start").
 		WithPostData("token", rtm.Token).
 		WithPostData("no_unreads", "false").
@@ -192,14 +231,13 @@ func (rtm *Client) Stop() error {
 		return nil
 	}
 
+	rtm.writeLock.Lock()
 	closeErr := rtm.socketConnection.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-	if closeErr != nil {
-		return closeErr
-	}
+	rtm.writeLock.Unlock()
 	rtm.socketConnection.Close()
 	rtm.socketConnection = nil
 	rtm.connected = false
-	return nil
+	return closeErr
 }
 
 // SendMessage sends a basic message over the open web socket connection to slack.
@@ -211,31 +249,17 @@ func (rtm *Client) SendMessage(m *Message) error {
 		return exception.New("Connection is closed.")
 	}
 
-	return rtm.socketConnection.WriteJSON(m)
+	return rtm.writeJSON(m)
 }
 
 // Say sends a basic message to a given channelID.
 func (rtm *Client) Say(channelID string, messageComponents ...interface{}) error {
-	rtm.socketLock.RLock()
-	defer rtm.socketLock.RUnlock()
-
-	if rtm.socketConnection == nil {
-		return exception.New("Connection is closed.")
-	}
-
 	m := &Message{Type: "message", Text: fmt.Sprint(messageComponents...), Channel: channelID}
 	return rtm.SendMessage(m)
 }
 
 // Sayf is an overload that uses Printf style replacements for a basic message to a given channelID.
 func (rtm *Client) Sayf(channelID, format string, messageComponents ...interface{}) error {
-	rtm.socketLock.RLock()
-	defer rtm.socketLock.RUnlock()
-
-	if rtm.socketConnection == nil {
-		return exception.New("Connection is closed.")
-	}
-
 	m := &Message{Type: "message", Text: fmt.Sprintf(format, messageComponents...), Channel: channelID}
 	return rtm.SendMessage(m)
 }
@@ -253,16 +277,49 @@ func (rtm *Client) Ping() error {
 	p := &Message{ID: time.Now().UTC().UnixNano(), Type: "ping"}
 	rtm.dispatch(p)
 	rtm.pingInFlight[p.ID] = time.Now().UTC()
-	return rtm.socketConnection.WriteJSON(p)
+	return rtm.writeJSON(p)
 }
 
 //--------------------------------------------------------------------------------
 // INTERNAL METHODS
 //--------------------------------------------------------------------------------
 
+// writeJSON writes a message to the websocket; callers must hold the socket lock.
+func (rtm *Client) writeJSON(v interface{}) error {
+	rtm.writeLock.Lock()
+	defer rtm.writeLock.Unlock()
+	return rtm.socketConnection.WriteJSON(v)
+}
+
+// apiScheme returns the scheme for api calls, from `SetAPIEndpoint` or the `APIScheme` default.
+func (rtm *Client) apiScheme() string {
+	if len(rtm.scheme) != 0 {
+		return rtm.scheme
+	}
+	return APIScheme
+}
+
+// apiHost returns the host for api calls, from `SetAPIEndpoint` or the `APIEndpoint` default.
+func (rtm *Client) apiHost() string {
+	if len(rtm.host) != 0 {
+		return rtm.host
+	}
+	return APIEndpoint
+}
+
+// connection returns the open websocket connection, or nil if the client is stopped or couldn't reconnect.
+func (rtm *Client) connection() *websocket.Conn {
+	rtm.socketLock.RLock()
+	defer rtm.socketLock.RUnlock()
+	if !rtm.connected {
+		return nil
+	}
+	return rtm.socketConnection
+}
+
 func (rtm *Client) pingLoop() {
 	var err error
-	for rtm.connected {
+	for rtm.connection() != nil {
 		time.Sleep(rtm.pingInterval)
 		err = rtm.doPing()
 		if err != nil {
@@ -306,19 +363,16 @@ func (rtm *Client) doPing() error {
 	return nil
 }
 
-func (rtm *Client) resetPingMetadata() {
-	rtm.pingInFlightLock.Lock()
-	defer rtm.pingInFlightLock.Unlock()
-
-	rtm.pingInFlight = map[int64]time.Time{}
-}
-
 func (rtm *Client) handlePong(client *Client, message *Message) {
 	rtm.pingInFlightLock.Lock()
 	defer rtm.pingInFlightLock.Unlock()
+	if sent, hasPing := rtm.pingInFlight[message.ReplyTo]; hasPing && rtm.pongHandler != nil {
+		rtm.pongHandler(time.Now().UTC().Sub(sent))
+	}
 	delete(rtm.pingInFlight, message.ReplyTo)
 }
 
+// cycleConnection replaces the websocket connection with a new one; callers must hold the ping lock.
 func (rtm *Client) cycleConnection() error {
 	rtm.socketLock.Lock()
 	defer rtm.socketLock.Unlock()
@@ -326,8 +380,8 @@ func (rtm *Client) cycleConnection() error {
 	res := Session{}
 	meta, err := NewExternalRequest().
 		AsPost().
-		WithScheme(APIScheme).
-		WithHost(APIEndpoint).
+		WithScheme(rtm.apiScheme()).
+		WithHost(rtm.apiHost()).
 		WithPath("api/rtm.start").
 		WithPostData("token", rtm.Token).
 		WithPostData("no_unreads", "true").
@@ -342,13 +396,19 @@ func (rtm *Client) cycleConnection() error {
 		return exception.New("Non-200 Status from Slack, aborting.")
 	}
 
-	rtm.resetPingMetadata()
+	rtm.pingInFlight = map[int64]time.Time{}
 
 	u, err := url.Parse(res.URL)
 	if err != nil {
 		return err
 	}
+	if rtm.socketConnection != nil {
+		rtm.socketConnection.Close()
+	}
 	rtm.socketConnection, _, err = websocket.DefaultDialer.Dial(u.String(), nil)
+	if err == nil && rtm.reconnectHandler != nil {
+		go rtm.reconnectHandler()
+	}
 	return err
 }
 
@@ -357,12 +417,20 @@ func (rtm *Client) listenLoop() {
 	var messageBytes []byte
 	var err error
 
-	for rtm.connected {
-		_, messageBytes, err = rtm.socketConnection.ReadMessage()
+	for {
+		connection := rtm.connection()
+		if connection == nil {
+			return
+		}
+		_, messageBytes, err = connection.ReadMessage()
 		if err != nil {
+			// the connection is closed or broken; wait for it to be stopped or cycled.
 			rtm.logf("listenLoop() :: error => %v", err)
+			time.Sleep(time.Second)
+			continue
 		}
 
+		mt = MessageType{}
 		err = json.Unmarshal(messageBytes, &mt)
 		if err == nil {
 			m := Message{}
@@ -371,8 +439,13 @@ func (rtm *Client) listenLoop() {
 				if len(mt.Type) == 0 && m.OK != nil { //special situation where acks don't have types and we have to sniff.
 					rtm.dispatch(&Message{Type: EventMessageACK, ReplyTo: m.ReplyTo, Timestamp: m.Timestamp, Text: m.Text})
 				} else {
+					m.Raw = messageBytes
 					rtm.dispatch(&m)
 				}
+			} else if len(mt.Type) != 0 {
+				// events like `team_join` have fields (e.g. `user`) that are objects rather than ids,
+				// so they're dispatched with just their type and raw payload.
+				rtm.dispatch(&Message{Type: mt.Type, Raw: messageBytes})
 			} else {
 				rtm.logf("listenLoop() :: error => %v", err)
 			}
@@ -383,7 +456,10 @@ func (rtm *Client) listenLoop() {
 }
 
 func (rtm *Client) dispatch(m *Message) {
-	if listeners, hasListeners := rtm.EventListeners[m.Type]; hasListeners {
+	rtm.listenersLock.RLock()
+	listeners, hasListeners := rtm.EventListeners[m.Type]
+	rtm.listenersLock.RUnlock()
+	if hasListeners {
 		for index := range listeners {
 			go func(listener EventListener) {
 				defer func() {
@@ -399,9 +475,17 @@ func (rtm *Client) dispatch(m *Message) {
 }
 
 func (rtm *Client) handleChannelJoined(client *Client, message *Message) {
+	// `channel_joined` events have the whole channel object rather than just the id.
+	var joined struct {
+		Channel Channel `json:"channel"`
+	}
+	if err := json.Unmarshal(message.Raw, &joined); err != nil || len(joined.Channel.ID) == 0 {
+		return
+	}
+
 	rtm.activeLock.Lock()
 	defer rtm.activeLock.Unlock()
-	rtm.ActiveChannels = append(rtm.ActiveChannels, message.Channel)
+	rtm.ActiveChannels = append(rtm.ActiveChannels, joined.Channel.ID)
 }
 
 func (rtm *Client) handleChannelUnarchive(client *Client, message *Message) {
@@ -0,0 +1,26 @@
+package slack
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestClientAddEventListenerWhileDispatching(t *testing.T) {
+	client := NewClient("xoxb-test")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for index := 0; index < 100; index++ {
+			client.AddEventListener(Event("test"), func(c *Client, m *Message) {})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for index := 0; index < 100; index++ {
+			client.dispatch(&Message{Type: Event("test")})
+		}
+	}()
+	wg.Wait()
+}
@@ -67,6 +67,8 @@ const (
 	EventUserTyping Event = "user_typing"
 	// EventChannelMarked is an enumerated event.
 	EventChannelMarked Event = "channel_marked"
+	// EventChannelCreated is an enumerated event.
+	EventChannelCreated Event = "channel_created"
 	// EventChannelJoined is an enumerated event.
 	EventChannelJoined Event = "channel_joined"
 	// EventChannelLeft is an enumerated event.
@@ -9,7 +9,7 @@ func NewChatMessage(channelID, text string) *ChatMessage {
 type User struct {
 	ID                string       `json:"id"`
 	Name              string       `json:"name"`
-	Deleted           bool         `json:"deletd"`
+	Deleted           bool         `json:"deleted"`
 	Color             string       `json:"color"`
 	Profile           *UserProfile `json:"profile"`
 	IsBot             bool         `json:"is_bot"`
@@ -21,6 +21,9 @@ type User struct {
 	Has2FA            bool         `json:"has_2fa"`
 	TwoFactorType     string       `json:"two_factor_type"`
 	HasFiles          bool         `json:"has_files"`
+	TZ                string       `json:"tz"`
+	TZLabel           string       `json:"tz_label"`
+	TZOffset          int          `json:"tz_offset"`
 }
 
 // UserProfile represents additional information about a Slack user.
@@ -129,6 +132,39 @@ type Message struct {
 	Text      string     `json:"text"`
 	Reactions []Reaction `json:"reactions,omitempty"`
 	Error     *Error     `json:"error,omitempty"`
+
+	// ThreadTimestamp is the timestamp of the parent message for messages in a thread.
+	ThreadTimestamp *Timestamp `json:"thread_ts,omitempty"`
+
+	// Message is the message after the edit for `message_changed` events.
+	Message *Message `json:"message,omitempty"`
+
+	// PreviousMessage is the message before the change for `message_changed` and `message_deleted` events.
+	PreviousMessage *Message `json:"previous_message,omitempty"`
+
+	// DeletedTimestamp is the timestamp of the deleted message for `message_deleted` events.
+	DeletedTimestamp *Timestamp `json:"deleted_ts,omitempty"`
+
+	// Reaction is the name of the emoji for `reaction_added` and `reaction_removed` events.
+	Reaction string `json:"reaction,omitempty"`
+
+	// Item is what was reacted to for `reaction_added` and `reaction_removed` events.
+	Item *ReactionItem `json:"item,omitempty"`
+
+	// ItemUser is the user that created the item that was reacted to.
+	ItemUser string `json:"item_user,omitempty"`
+
+	// Raw is the raw json of the event the message was read from.
+	Raw []byte `json:"-"`
+}
+
+// ReactionItem is the message, file or file comment a reaction was added to or removed from.
+type ReactionItem struct {
+	Type        string     `json:"type"`
+	Channel     string     `json:"channel,omitempty"`
+	Timestamp   *Timestamp `json:"ts,omitempty"`
+	File        string     `json:"file,omitempty"`
+	FileComment string     `json:"file_comment,omitempty"`
 }
 
 // Error is a *sometimes* common datatype.
@@ -215,6 +251,12 @@ type ChatMessage struct {
 	// NOTES: as_user must be set to false or omitted.
 	IconEmoji *string `json:"icon_emoji,omitempty"`
 
+	// ThreadTimestamp is the timestamp of the message to reply in the thread of (optional).
+	ThreadTimestamp *string `json:"thread_ts,omitempty"`
+
+	// ReplyBroadcast also shows a thread reply in the channel (optional, default false).
+	ReplyBroadcast *bool `json:"reply_broadcast,omitempty"`
+
 	// Attachments are the chat message attachments for the message.
 	Attachments []ChatMessageAttachment `json:"attachments,omitempty"`
 }
@@ -356,7 +398,8 @@ type usersInfoResponse struct {
 // ChatMessageResponse is a response to chat.postMessage
 type ChatMessageResponse struct {
 	OK          bool      `json:"ok"`
-	Timestamp   Timestamp `json:"timestamp"`
+	Channel     string    `json:"channel"`
+	Timestamp   Timestamp `json:"ts"`
 	Message     *Message  `json:"message,omitempty"`
 	File        *File     `json:"file,omitempty"`
 	FileComment *File     `json:"file_comment,omitempty"`
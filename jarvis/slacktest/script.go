@@ -0,0 +1,93 @@
+package slacktest
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+)
+
+// Step is a step of a script: a user says something in a channel, and the bot is expected to reply with a message
+// matching a regular expression, or not checked if `Expect` is empty.
+type Step struct {
+	User    string `json:"user"`
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+	Expect  string `json:"expect,omitempty"`
+}
+
+// Script is a conversation with the bot.
+type Script []Step
+
+// ReadScript reads a json script from a file.
+func ReadScript(path string) (Script, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var script Script
+	if err := json.Unmarshal(contents, &script); err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return script, nil
+}
+
+// ScriptFromTranscript turns a recorded transcript into a script that expects the same first reply to each message
+// a user said, so a conversation can be recorded once and replayed as a test.
+func ScriptFromTranscript(transcript []Message) Script {
+	script := Script{}
+	for index, m := range transcript {
+		if len(m.Method) != 0 {
+			continue
+		}
+		step := Step{User: m.User, Channel: m.Channel, Text: m.Text}
+		for _, reply := range transcript[index+1:] {
+			if reply.Channel != m.Channel {
+				continue
+			}
+			if len(reply.Method) != 0 {
+				step.Expect = "^" + regexp.QuoteMeta(reply.Text) + "$"
+			}
+			break
+		}
+		script = append(script, step)
+	}
+	return script
+}
+
+// WriteTranscript writes a transcript as json.
+func WriteTranscript(w io.Writer, transcript []Message) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(transcript)
+}
+
+// SaveTranscript writes the server's transcript to a file as json.
+func (s *Server) SaveTranscript(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return WriteTranscript(f, s.Transcript())
+}
+
+// Run runs a script, waiting up to `timeout` for each expected reply.
+func (s *Server) Run(script Script, timeout time.Duration) error {
+	for index, step := range script {
+		if _, err := s.Say(step.User, step.Channel, step.Text); err != nil {
+			return exception.Newf("step %d (%q): %v", index+1, step.Text, err)
+		}
+		if len(step.Expect) == 0 {
+			continue
+		}
+		if _, err := s.Expect(step.Channel, step.Expect, timeout); err != nil {
+			return exception.Newf("step %d (%q): %v", index+1, step.Text, err)
+		}
+	}
+	return nil
+}
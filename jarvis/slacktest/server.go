@@ -0,0 +1,430 @@
+package slacktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/gorilla/websocket"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+const (
+	// DefaultBotID is the user id of the bot the fake slack serves.
+	DefaultBotID = "UJARVIS"
+
+	// DefaultBotName is the name of the bot the fake slack serves.
+	DefaultBotName = "jarvis"
+
+	// DefaultTeamName is the name of the fake slack's team.
+	DefaultTeamName = "slacktest"
+
+	// MethodRTM is the method recorded for messages the bot sends over the rtm websocket.
+	MethodRTM = "rtm"
+)
+
+// Message is a message in the fake slack, said by a user or sent by the bot.
+type Message struct {
+	Timestamp       string `json:"ts"`
+	Channel         string `json:"channel"`
+	User            string `json:"user"`
+	Text            string `json:"text"`
+	ThreadTimestamp string `json:"thread_ts,omitempty"`
+
+	// Method is how the bot sent the message, `rtm` or a web api method like `chat.postMessage`; it's empty for
+	// messages users said.
+	Method string `json:"method,omitempty"`
+}
+
+// Call is a web api call the bot made.
+type Call struct {
+	Method string
+	Args   url.Values
+}
+
+// NewServer starts a new fake slack, serving the rtm websocket and the web api methods the bot uses.
+func NewServer() *Server {
+	s := &Server{
+		botID:    DefaultBotID,
+		users:    map[string]slack.User{DefaultBotID: {ID: DefaultBotID, Name: DefaultBotName, IsBot: true}},
+		channels: map[string]slack.Channel{},
+		sockets:  map[*websocket.Conn]*sync.Mutex{},
+		changed:  make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rtm", s.handleRTM)
+	mux.HandleFunc("/api/", s.handleAPI)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// Server is a fake slack for end to end tests. Point the slack client at it with `Use`, or get a client for it with
+// `Client`, start a bot, then have users `Say` things and `Expect` the bot's replies, or `Run` a whole script of them.
+type Server struct {
+	server *httptest.Server
+
+	lock       sync.Mutex
+	botID      string
+	users      map[string]slack.User
+	channels   map[string]slack.Channel
+	sockets    map[*websocket.Conn]*sync.Mutex
+	sequence   int
+	transcript []Message
+	outbox     []Message
+	consumed   map[int]bool
+	calls      []Call
+	onCall     func(Call)
+	changed    chan struct{}
+}
+
+// Client returns a slack client whose api calls go to the server.
+func (s *Server) Client(token string) *slack.Client {
+	client := slack.NewClient(token)
+	client.SetDebug(false)
+	client.SetAPIEndpoint("http", strings.TrimPrefix(s.server.URL, "http://"))
+	return client
+}
+
+// OnCall sets a function that's called with each web api call the bot makes, as it's made.
+func (s *Server) OnCall(handler func(Call)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onCall = handler
+}
+
+// Close disconnects any bots and stops the server.
+func (s *Server) Close() {
+	s.lock.Lock()
+	for socket := range s.sockets {
+		socket.Close()
+	}
+	s.lock.Unlock()
+	s.server.Close()
+}
+
+// BotID returns the bot's user id.
+func (s *Server) BotID() string {
+	return s.botID
+}
+
+// AddUser adds a user to the team.
+func (s *Server) AddUser(id, name string) {
+	s.PutUser(slack.User{ID: id, Name: name})
+}
+
+// PutUser adds or replaces a user.
+func (s *Server) PutUser(user slack.User) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.users[user.ID] = user
+}
+
+// AddChannel adds a public channel the bot is a member of.
+func (s *Server) AddChannel(id, name string) {
+	s.PutChannel(slack.Channel{ID: id, Name: name, IsChannel: true, IsMember: true})
+}
+
+// PutChannel adds or replaces a channel.
+func (s *Server) PutChannel(channel slack.Channel) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.channels[channel.ID] = channel
+}
+
+// WaitForConnection waits for a bot to connect to the rtm websocket.
+func (s *Server) WaitForConnection(timeout time.Duration) error {
+	return s.wait(timeout, func() bool { return len(s.sockets) != 0 })
+}
+
+// Say sends a message from a user to a channel over the rtm websocket, returning its timestamp.
+func (s *Server) Say(user, channel, text string) (string, error) {
+	s.lock.Lock()
+	m := Message{Timestamp: s.nextTimestamp(), Channel: channel, User: user, Text: text}
+	s.transcript = append(s.transcript, m)
+	s.lock.Unlock()
+
+	return m.Timestamp, s.Send(map[string]string{"type": "message", "channel": channel, "user": user, "text": text, "ts": m.Timestamp})
+}
+
+// Send sends a raw event, like a `reaction_added` or `message_changed`, over the rtm websocket.
+func (s *Server) Send(event interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.sockets) == 0 {
+		return exception.New("no bot is connected")
+	}
+	for socket, writeLock := range s.sockets {
+		writeLock.Lock()
+		err := socket.WriteJSON(event)
+		writeLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expect waits for the bot to send a message to a channel that matches a regular expression, and returns it.
+// Each message the bot sends is only matched once, so two identical replies need two expectations.
+func (s *Server) Expect(channel, pattern string, timeout time.Duration) (*Message, error) {
+	expr, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Message
+	err = s.wait(timeout, func() bool {
+		for index, m := range s.outbox {
+			if !s.consumed[index] && m.Channel == channel && expr.MatchString(m.Text) {
+				s.consumed[index] = true
+				found = &m
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, exception.Newf("no message in `%s` matched `%s`; sent: %s", channel, pattern, s.describeOutbox(channel))
+	}
+	return found, nil
+}
+
+// Messages returns the messages the bot sent, in order.
+func (s *Server) Messages() []Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Message{}, s.outbox...)
+}
+
+// Transcript returns every message users said and the bot sent, in order.
+func (s *Server) Transcript() []Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Message{}, s.transcript...)
+}
+
+// Calls returns the web api calls the bot made to a method, like `chat.postMessage`, in order.
+func (s *Server) Calls(method string) []Call {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	calls := []Call{}
+	for _, call := range s.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// wait waits for a condition, checked with the lock held, to be true.
+func (s *Server) wait(timeout time.Duration, condition func() bool) error {
+	deadline := time.After(timeout)
+	for {
+		s.lock.Lock()
+		done, changed := condition(), s.changed
+		s.lock.Unlock()
+		if done {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return exception.New("timed out")
+		}
+	}
+}
+
+// notify wakes up anything waiting; the caller must hold the lock.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// nextTimestamp returns a new, increasing message timestamp; the caller must hold the lock.
+func (s *Server) nextTimestamp() string {
+	s.sequence++
+	return fmt.Sprintf("%d.%06d", time.Now().UTC().Unix(), s.sequence)
+}
+
+// sent records a message the bot sent, returning its timestamp; the caller must hold the lock.
+func (s *Server) sent(m Message) string {
+	if len(m.Timestamp) == 0 {
+		m.Timestamp = s.nextTimestamp()
+	}
+	m.User = s.botID
+	if s.consumed == nil {
+		s.consumed = map[int]bool{}
+	}
+	s.outbox = append(s.outbox, m)
+	s.transcript = append(s.transcript, m)
+	s.notify()
+	return m.Timestamp
+}
+
+func (s *Server) describeOutbox(channel string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	texts := []string{}
+	for _, m := range s.outbox {
+		if m.Channel == channel {
+			texts = append(texts, fmt.Sprintf("%q", m.Text))
+		}
+	}
+	if len(texts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(texts, ", ")
+}
+
+func (s *Server) handleRTM(w http.ResponseWriter, r *http.Request) {
+	socket, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	writeLock := &sync.Mutex{}
+	writeLock.Lock()
+	err = socket.WriteJSON(map[string]string{"type": "hello"})
+	writeLock.Unlock()
+	if err != nil {
+		socket.Close()
+		return
+	}
+
+	s.lock.Lock()
+	s.sockets[socket] = writeLock
+	s.notify()
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.sockets, socket)
+		s.notify()
+		s.lock.Unlock()
+		socket.Close()
+	}()
+
+	for {
+		var event struct {
+			ID      int64  `json:"id"`
+			Type    string `json:"type"`
+			Channel string `json:"channel"`
+			Text    string `json:"text"`
+		}
+		if err := socket.ReadJSON(&event); err != nil {
+			return
+		}
+
+		var reply interface{}
+		switch event.Type {
+		case "ping":
+			reply = map[string]interface{}{"type": "pong", "reply_to": event.ID}
+		case "message":
+			s.lock.Lock()
+			ts := s.sent(Message{Channel: event.Channel, Text: event.Text, Method: MethodRTM})
+			s.lock.Unlock()
+			reply = map[string]interface{}{"ok": true, "reply_to": event.ID, "ts": ts, "text": event.Text}
+		default:
+			continue
+		}
+
+		writeLock.Lock()
+		err := socket.WriteJSON(reply)
+		writeLock.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+
+	call := Call{Method: method, Args: r.Form}
+	s.lock.Lock()
+	onCall := s.onCall
+	s.lock.Unlock()
+	if onCall != nil {
+		onCall(call)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = append(s.calls, call)
+
+	response := map[string]interface{}{"ok": true}
+	switch method {
+	case "rtm.start":
+		response["url"] = "ws" + strings.TrimPrefix(s.server.URL, "http") + "/rtm"
+		response["self"] = slack.Self{ID: s.botID, Name: DefaultBotName}
+		response["team"] = slack.Team{ID: "T" + s.botID, Name: DefaultTeamName}
+		response["users"] = s.sortedUsers()
+		response["channels"] = s.sortedChannels()
+	case "users.list":
+		response["members"] = s.sortedUsers()
+	case "channels.list":
+		response["channels"] = s.sortedChannels()
+	case "channels.info":
+		channel, hasChannel := s.channels[r.Form.Get("channel")]
+		if !hasChannel {
+			response = map[string]interface{}{"ok": false, "error": "channel_not_found"}
+			break
+		}
+		response["channel"] = channel
+	case "channels.history":
+		response["messages"] = s.history(r.Form.Get("channel"), r.Form.Get("oldest"))
+	case "chat.postMessage", "chat.update":
+		m := Message{Channel: r.Form.Get("channel"), Text: r.Form.Get("text"), ThreadTimestamp: r.Form.Get("thread_ts"), Method: method}
+		if method == "chat.update" {
+			m.Timestamp = r.Form.Get("ts")
+		}
+		ts := s.sent(m)
+		response["channel"] = m.Channel
+		response["ts"] = ts
+		response["message"] = map[string]string{"text": m.Text, "ts": ts}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// history returns the messages in a channel since `oldest`, newest first, like `channels.history`.
+func (s *Server) history(channel, oldest string) []Message {
+	messages := []Message{}
+	for index := len(s.transcript) - 1; index >= 0; index-- {
+		m := s.transcript[index]
+		if m.Channel == channel && m.Timestamp >= oldest {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func (s *Server) sortedUsers() []slack.User {
+	users := []slack.User{}
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (s *Server) sortedChannels() []slack.Channel {
+	channels := []slack.Channel{}
+	for _, channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].ID < channels[j].ID })
+	return channels
+}
@@ -0,0 +1,98 @@
+package slacktest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/wcharczuk/jarvis/jarvis/slack"
+)
+
+// echo connects a raw slack client that says back whatever it hears, prefixed with `echo: `.
+func echo(assert *assert.Assertions, s *Server) *slack.Client {
+	client := s.Client("xoxb-test")
+	client.AddEventListener(slack.EventMessage, func(c *slack.Client, m *slack.Message) {
+		if m.User != s.BotID() {
+			c.Sayf(m.Channel, "echo: %s", m.Text)
+		}
+	})
+	session, err := client.Connect()
+	assert.Nil(err)
+	assert.Equal(DefaultBotID, session.Self.ID)
+	assert.Nil(s.WaitForConnection(5 * time.Second))
+	return client
+}
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+	s := NewServer()
+	defer s.Close()
+	s.AddUser("U1", "alice")
+	s.AddChannel("C1", "general")
+
+	client := echo(assert, s)
+	defer client.Stop()
+
+	ts, err := s.Say("U1", "C1", "hello")
+	assert.Nil(err)
+	assert.NotEmpty(ts)
+
+	m, err := s.Expect("C1", "^echo: hello$", 5*time.Second)
+	assert.Nil(err)
+	assert.Equal(MethodRTM, m.Method)
+	assert.Equal(DefaultBotID, m.User)
+
+	_, err = s.Expect("C1", "^echo: hello$", 50*time.Millisecond)
+	assert.NotNil(err, "messages are only matched once")
+
+	res, err := client.ChatPostMessage(slack.NewChatMessage("C1", "posted"))
+	assert.Nil(err)
+	assert.NotEmpty(res.Timestamp.String())
+	m, err = s.Expect("C1", "posted", time.Second)
+	assert.Nil(err)
+	assert.Equal("chat.postMessage", m.Method)
+	assert.Len(s.Calls("chat.postMessage"), 1)
+
+	history, err := client.ChannelsHistory("C1", nil, nil, 100, false)
+	assert.Nil(err)
+	assert.Len(history.Messages, 3)
+	assert.Equal("posted", history.Messages[0].Text)
+
+	users, err := client.UsersList()
+	assert.Nil(err)
+	assert.Len(users, 2)
+}
+
+func TestServerRunScript(t *testing.T) {
+	assert := assert.New(t)
+	s := NewServer()
+	defer s.Close()
+	s.AddUser("U1", "alice")
+	s.AddChannel("C1", "general")
+
+	client := echo(assert, s)
+	defer client.Stop()
+
+	assert.Nil(s.Run(Script{
+		{User: "U1", Channel: "C1", Text: "one", Expect: "^echo: one$"},
+		{User: "U1", Channel: "C1", Text: "two"},
+		{User: "U1", Channel: "C1", Text: "three", Expect: "three"},
+	}, 5*time.Second))
+
+	err := s.Run(Script{{User: "U1", Channel: "C1", Text: "four", Expect: "^five$"}}, 50*time.Millisecond)
+	assert.NotNil(err)
+	assert.Contains("step 1", err.Error())
+
+	_, err = s.Expect("C1", "^echo: two$", time.Second)
+	assert.Nil(err, "unchecked replies can still be expected later")
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(WriteTranscript(buffer, s.Transcript()))
+	assert.Contains(`"text": "echo: one"`, buffer.String())
+
+	script := ScriptFromTranscript(s.Transcript())
+	assert.Len(script, 4)
+	assert.Equal(`^echo: one$`, script[0].Expect)
+	assert.Equal(`^echo: four$`, script[3].Expect)
+}
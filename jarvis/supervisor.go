@@ -0,0 +1,409 @@
+package jarvis
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-exception"
+	logger "github.com/blendlabs/go-logger"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+)
+
+const (
+	// DefaultSupervisorMinBackoff is how long the supervisor waits before restarting a bot that failed once.
+	DefaultSupervisorMinBackoff = 5 * time.Second
+
+	// DefaultSupervisorMaxBackoff is the most the supervisor waits between restarts, however often a bot has failed.
+	DefaultSupervisorMaxBackoff = 5 * time.Minute
+
+	// DefaultSupervisorCheckInterval is how often the supervisor checks that running bots are still connected.
+	DefaultSupervisorCheckInterval = 30 * time.Second
+)
+
+// WorkspaceState is where a supervised workspace's bot is in its lifecycle.
+type WorkspaceState string
+
+const (
+	// WorkspaceStateStarting means the bot is connecting to slack.
+	WorkspaceStateStarting WorkspaceState = "starting"
+	// WorkspaceStateRunning means the bot is connected and handling messages.
+	WorkspaceStateRunning WorkspaceState = "running"
+	// WorkspaceStateBackoff means the bot failed to start or lost its connection, and is waiting to restart.
+	WorkspaceStateBackoff WorkspaceState = "backoff"
+	// WorkspaceStateStopped means the workspace was removed or the supervisor was stopped.
+	WorkspaceStateStopped WorkspaceState = "stopped"
+)
+
+// WorkspaceStatus is a point in time report of a supervised workspace.
+type WorkspaceStatus struct {
+	Name        string          `json:"name"`
+	State       WorkspaceState  `json:"state"`
+	Since       time.Time       `json:"since"`
+	Restarts    int             `json:"restarts"`
+	LastError   string          `json:"last_error,omitempty"`
+	NextAttempt *time.Time      `json:"next_attempt,omitempty"`
+	Readiness   *core.Readiness `json:"readiness,omitempty"`
+}
+
+// NewSupervisor returns a new supervisor with no workspaces.
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		workspaces:    map[string]*supervisedWorkspace{},
+		minBackoff:    DefaultSupervisorMinBackoff,
+		maxBackoff:    DefaultSupervisorMaxBackoff,
+		checkInterval: DefaultSupervisorCheckInterval,
+		pongTimeout:   core.DefaultPongTimeout,
+		agent:         logger.New(logger.NewEventFlagSetNone()),
+	}
+}
+
+// Supervisor owns the bots for a set of workspaces. Each workspace's bot is started on its own, and restarted with
+// exponential backoff if it fails to start or loses its connection to slack, without affecting the others.
+type Supervisor struct {
+	lock       sync.Mutex
+	workspaces map[string]*supervisedWorkspace
+
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	checkInterval time.Duration
+	pongTimeout   time.Duration
+	agent         *logger.Agent
+
+	// start and check are overridden by tests; they default to starting a real bot and checking its readiness.
+	start func(w Workspace) (*Bot, error)
+	check func(b *Bot) error
+}
+
+// SetLogger sets the logger the supervisor logs restarts to, and that its bots log to.
+func (s *Supervisor) SetLogger(agent *logger.Agent) {
+	s.agent = agent
+}
+
+// SetPongTimeout sets how long a bot can go without a pong from slack before it's restarted.
+func (s *Supervisor) SetPongTimeout(timeout time.Duration) {
+	s.pongTimeout = timeout
+}
+
+// PongTimeout returns how long a bot can go without a pong from slack before it's restarted.
+func (s *Supervisor) PongTimeout() time.Duration {
+	return s.pongTimeout
+}
+
+// Add starts supervising a workspace.
+func (s *Supervisor) Add(w Workspace) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, hasWorkspace := s.workspaces[w.Name]; hasWorkspace {
+		return exception.Newf("workspace `%s` is already running", w.Name)
+	}
+	s.startWorkspace(w)
+	return nil
+}
+
+// Remove stops a workspace's bot and stops supervising it.
+func (s *Supervisor) Remove(name string) error {
+	s.lock.Lock()
+	sw, hasWorkspace := s.workspaces[name]
+	delete(s.workspaces, name)
+	s.lock.Unlock()
+
+	if !hasWorkspace {
+		return exception.Newf("workspace `%s` isn't running", name)
+	}
+	sw.shutdown()
+	return nil
+}
+
+// Reload brings the supervised workspaces in line with `workspaces`: workspaces that are gone are stopped, new ones
+// are started and ones whose configuration changed are restarted. Workspaces that didn't change are left alone.
+func (s *Supervisor) Reload(workspaces []Workspace) {
+	wanted := map[string]Workspace{}
+	for _, w := range workspaces {
+		wanted[w.Name] = w
+	}
+
+	s.lock.Lock()
+	stopping := []*supervisedWorkspace{}
+	for name, sw := range s.workspaces {
+		if w, isWanted := wanted[name]; !isWanted || !reflect.DeepEqual(w, sw.workspace) {
+			stopping = append(stopping, sw)
+			delete(s.workspaces, name)
+		}
+	}
+	s.lock.Unlock()
+
+	// a workspace whose configuration changed has to be stopped before it's started again, or there'd be two
+	// bots answering in it for a moment.
+	s.shutdown(stopping)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for name, w := range wanted {
+		if _, hasWorkspace := s.workspaces[name]; !hasWorkspace {
+			s.startWorkspace(w)
+		}
+	}
+}
+
+// Stop stops every workspace's bot.
+func (s *Supervisor) Stop() {
+	s.lock.Lock()
+	stopping := []*supervisedWorkspace{}
+	for name, sw := range s.workspaces {
+		stopping = append(stopping, sw)
+		delete(s.workspaces, name)
+	}
+	s.lock.Unlock()
+	s.shutdown(stopping)
+}
+
+// Bots returns the running bots, ordered by workspace name.
+func (s *Supervisor) Bots() []*Bot {
+	bots := []*Bot{}
+	for _, sw := range s.sortedWorkspaces() {
+		if b := sw.runningBot(); b != nil {
+			bots = append(bots, b)
+		}
+	}
+	return bots
+}
+
+// Status returns the status of each workspace, ordered by name.
+func (s *Supervisor) Status() []WorkspaceStatus {
+	statuses := []WorkspaceStatus{}
+	for _, sw := range s.sortedWorkspaces() {
+		status := sw.status()
+		if b := sw.runningBot(); b != nil {
+			readiness := b.Readiness(s.pongTimeout)
+			status.Readiness = &readiness
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (s *Supervisor) sortedWorkspaces() []*supervisedWorkspace {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	workspaces := []*supervisedWorkspace{}
+	for _, sw := range s.workspaces {
+		workspaces = append(workspaces, sw)
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].workspace.Name < workspaces[j].workspace.Name })
+	return workspaces
+}
+
+// startWorkspace starts supervising a workspace; the caller must hold the lock.
+func (s *Supervisor) startWorkspace(w Workspace) {
+	sw := &supervisedWorkspace{
+		workspace: w,
+		state:     WorkspaceStateStarting,
+		since:     time.Now().UTC(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.workspaces[w.Name] = sw
+	go s.supervise(sw)
+}
+
+// shutdown stops workspaces concurrently, and waits for all of them to stop.
+func (s *Supervisor) shutdown(workspaces []*supervisedWorkspace) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(workspaces))
+	for _, sw := range workspaces {
+		go func(sw *supervisedWorkspace) {
+			defer wg.Done()
+			sw.shutdown()
+		}(sw)
+	}
+	wg.Wait()
+}
+
+// supervise starts a workspace's bot and restarts it when it fails, until the workspace is stopped.
+func (s *Supervisor) supervise(sw *supervisedWorkspace) {
+	defer close(sw.done)
+	for {
+		sw.setState(WorkspaceStateStarting)
+		b, err := s.startBot(sw.workspace)
+		if err == nil {
+			sw.setRunning(b)
+			err = s.watch(sw, b)
+			sw.setRunning(nil)
+			if stopErr := b.Stop(); stopErr != nil {
+				s.agent.Errorf("workspace `%s` :: error stopping bot: %v", sw.workspace.Name, stopErr)
+			}
+			if err == nil {
+				sw.setState(WorkspaceStateStopped)
+				return
+			}
+		}
+
+		delay := sw.failed(err, s.backoff)
+		s.agent.Errorf("workspace `%s` :: bot failed, restarting in %v: %v", sw.workspace.Name, delay, err)
+		select {
+		case <-sw.stop:
+			sw.setState(WorkspaceStateStopped)
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// startBot starts a bot for a workspace, turning a panic into an error so it can be retried like any other failure.
+func (s *Supervisor) startBot(w Workspace) (b *Bot, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if b != nil {
+				b.Stop()
+			}
+			b = nil
+			err = exception.Newf("panic starting bot: %v", r)
+		}
+	}()
+
+	if s.start != nil {
+		return s.start(w)
+	}
+
+	b = NewWorkspaceBot(w)
+	b.agent = s.agent
+	if err = b.Init(); err != nil {
+		return nil, err
+	}
+	if err = b.Start(); err != nil {
+		b.Stop()
+		return nil, err
+	}
+	return b, nil
+}
+
+// watch checks a running bot every check interval, returning an error when it fails a check and nil once the
+// workspace is stopped.
+func (s *Supervisor) watch(sw *supervisedWorkspace, b *Bot) error {
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sw.stop:
+			return nil
+		case <-ticker.C:
+			if err := s.checkBot(b); err != nil {
+				return err
+			}
+			sw.healthy()
+		}
+	}
+}
+
+func (s *Supervisor) checkBot(b *Bot) error {
+	if s.check != nil {
+		return s.check(b)
+	}
+	if readiness := b.Readiness(s.pongTimeout); !readiness.Ready {
+		return exception.New(strings.Join(readiness.Problems, ", "))
+	}
+	return nil
+}
+
+// backoff returns how long to wait before restarting after `failures` consecutive failures, doubling from the
+// minimum up to the maximum.
+func (s *Supervisor) backoff(failures int) time.Duration {
+	delay := s.minBackoff
+	for attempt := 1; attempt < failures && delay < s.maxBackoff; attempt++ {
+		delay = delay * 2
+	}
+	if delay > s.maxBackoff {
+		return s.maxBackoff
+	}
+	return delay
+}
+
+// supervisedWorkspace is a workspace and the state of its bot.
+type supervisedWorkspace struct {
+	workspace Workspace
+	stop      chan struct{}
+	done      chan struct{}
+
+	lock        sync.Mutex
+	bot         *Bot
+	state       WorkspaceState
+	since       time.Time
+	restarts    int
+	failures    int
+	lastError   error
+	nextAttempt time.Time
+}
+
+func (sw *supervisedWorkspace) shutdown() {
+	close(sw.stop)
+	<-sw.done
+}
+
+func (sw *supervisedWorkspace) setState(state WorkspaceState) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	if sw.state == WorkspaceStateBackoff && state == WorkspaceStateStarting {
+		sw.restarts++
+	}
+	sw.state = state
+	sw.since = time.Now().UTC()
+	sw.nextAttempt = time.Time{}
+}
+
+// setRunning records the running bot, or that it's no longer running if `b` is nil.
+func (sw *supervisedWorkspace) setRunning(b *Bot) {
+	sw.lock.Lock()
+	sw.bot = b
+	sw.lock.Unlock()
+	if b != nil {
+		sw.setState(WorkspaceStateRunning)
+	}
+}
+
+// healthy records that the running bot passed a check, which resets the backoff.
+func (sw *supervisedWorkspace) healthy() {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.failures = 0
+}
+
+// failed records a failure and returns how long to wait before restarting.
+func (sw *supervisedWorkspace) failed(err error, backoff func(failures int) time.Duration) time.Duration {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.failures++
+	delay := backoff(sw.failures)
+	sw.state = WorkspaceStateBackoff
+	sw.since = time.Now().UTC()
+	sw.lastError = err
+	sw.nextAttempt = sw.since.Add(delay)
+	return delay
+}
+
+func (sw *supervisedWorkspace) runningBot() *Bot {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	return sw.bot
+}
+
+func (sw *supervisedWorkspace) status() WorkspaceStatus {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	status := WorkspaceStatus{
+		Name:     sw.workspace.Name,
+		State:    sw.state,
+		Since:    sw.since,
+		Restarts: sw.restarts,
+	}
+	if sw.lastError != nil {
+		status.LastError = sw.lastError.Error()
+	}
+	if !sw.nextAttempt.IsZero() {
+		nextAttempt := sw.nextAttempt
+		status.NextAttempt = &nextAttempt
+	}
+	return status
+}
@@ -0,0 +1,147 @@
+package jarvis
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blendlabs/go-assert"
+	"github.com/blendlabs/go-exception"
+)
+
+// testStarter starts bots without connecting to slack, failing each workspace a set number of times first; the
+// `panics` token panics on the first start.
+type testStarter struct {
+	lock     sync.Mutex
+	failures map[string]int
+	starts   map[string]int
+}
+
+func (ts *testStarter) start(w Workspace) (*Bot, error) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.starts[w.Name]++
+	if ts.failures[w.Name] > 0 {
+		ts.failures[w.Name]--
+		return nil, exception.New("invalid_auth")
+	}
+	if w.Token == "panics" && ts.starts[w.Name] == 1 {
+		panic("this is only a test")
+	}
+	return NewWorkspaceBot(w), nil
+}
+
+func (ts *testStarter) startCount(name string) int {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return ts.starts[name]
+}
+
+func testSupervisor(failures map[string]int) (*Supervisor, *testStarter) {
+	starter := &testStarter{failures: failures, starts: map[string]int{}}
+	s := NewSupervisor()
+	s.minBackoff = time.Millisecond
+	s.maxBackoff = 4 * time.Millisecond
+	s.checkInterval = time.Millisecond
+	s.start = starter.start
+	s.check = func(b *Bot) error { return nil }
+	return s, starter
+}
+
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func workspaceState(s *Supervisor, name string) WorkspaceState {
+	for _, status := range s.Status() {
+		if status.Name == name {
+			return status.State
+		}
+	}
+	return ""
+}
+
+func TestSupervisorRestartsFailedWorkspaces(t *testing.T) {
+	assert := assert.New(t)
+	s, starter := testSupervisor(map[string]int{"flaky": 3})
+	defer s.Stop()
+
+	s.Reload([]Workspace{{Name: "flaky", Token: "flaky"}, {Name: "good", Token: "good"}, {Name: "panicky", Token: "panics"}})
+	assert.True(waitFor(func() bool { return len(s.Bots()) == 3 }))
+	assert.Equal(WorkspaceStateRunning, workspaceState(s, "flaky"))
+	assert.Equal(2, starter.startCount("panicky"), "panics are retried")
+	assert.Equal(1, starter.startCount("good"), "other workspaces are left alone")
+	assert.Equal(4, starter.startCount("flaky"))
+
+	statuses := s.Status()
+	assert.Len(statuses, 3)
+	assert.Equal("flaky", statuses[0].Name)
+	assert.Equal(3, statuses[0].Restarts)
+	assert.Equal("invalid_auth", statuses[0].LastError)
+	assert.NotNil(statuses[0].Readiness)
+	assert.Nil(statuses[0].NextAttempt)
+	assert.Equal(1, statuses[2].Restarts)
+	assert.Contains("this is only a test", statuses[2].LastError)
+}
+
+func TestSupervisorRestartsUnhealthyBots(t *testing.T) {
+	assert := assert.New(t)
+	s, starter := testSupervisor(map[string]int{})
+	defer s.Stop()
+
+	var unhealthy sync.Once
+	s.check = func(b *Bot) error {
+		var err error
+		unhealthy.Do(func() { err = exception.New("not connected to slack") })
+		return err
+	}
+
+	assert.Nil(s.Add(Workspace{Name: "test", Token: "test"}))
+	assert.NotNil(s.Add(Workspace{Name: "test", Token: "test"}))
+	assert.True(waitFor(func() bool { return starter.startCount("test") == 2 }))
+	assert.True(waitFor(func() bool { return workspaceState(s, "test") == WorkspaceStateRunning }))
+	assert.Equal("not connected to slack", s.Status()[0].LastError)
+}
+
+func TestSupervisorReload(t *testing.T) {
+	assert := assert.New(t)
+	s, starter := testSupervisor(map[string]int{})
+	defer s.Stop()
+
+	s.Reload([]Workspace{{Name: "one", Token: "one"}, {Name: "two", Token: "two"}})
+	assert.True(waitFor(func() bool { return len(s.Bots()) == 2 }))
+	one := s.Bots()[0]
+
+	s.Reload([]Workspace{
+		{Name: "one", Token: "one"},
+		{Name: "three", Token: "three", Configuration: map[string]string{"option.passive": "true"}},
+	})
+	assert.True(waitFor(func() bool { return len(s.Bots()) == 2 }))
+	assert.True(one == s.Bots()[0], "unchanged workspaces keep their bot")
+	assert.Equal("three", s.Status()[1].Name)
+	assert.Equal("true", s.Bots()[1].Configuration()["option.passive"])
+
+	s.Reload([]Workspace{{Name: "one", Token: "one"}, {Name: "three", Token: "three"}})
+	assert.True(waitFor(func() bool { return starter.startCount("three") == 2 }), "changed workspaces are restarted")
+	assert.Equal(1, starter.startCount("one"))
+
+	assert.NotNil(s.Remove("two"))
+	assert.Nil(s.Remove("one"))
+	assert.Len(s.Status(), 1)
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	assert := assert.New(t)
+	s := NewSupervisor()
+	assert.Equal(DefaultSupervisorMinBackoff, s.backoff(1))
+	assert.Equal(2*DefaultSupervisorMinBackoff, s.backoff(2))
+	assert.Equal(4*DefaultSupervisorMinBackoff, s.backoff(3))
+	assert.Equal(DefaultSupervisorMaxBackoff, s.backoff(100))
+}
@@ -0,0 +1,153 @@
+package jarvis
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/blendlabs/go-chronometer"
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/core"
+)
+
+// newTrackedJob wraps a job so its runs are recorded in the bot's job history.
+// Jobs that provide a timeout are wrapped in a type that forwards it, so jobs without one aren't timed out.
+func newTrackedJob(b *Bot, job chronometer.Job) chronometer.Job {
+	tracked := &trackedJob{Job: job, bot: b}
+	if _, hasTimeout := job.(chronometer.TimeoutProvider); hasTimeout {
+		return &timeoutTrackedJob{tracked}
+	}
+	return tracked
+}
+
+// trackedJob records each run of the job it wraps and reports failures to the job's owner.
+type trackedJob struct {
+	chronometer.Job
+
+	bot *Bot
+
+	lock    sync.Mutex
+	started time.Time
+}
+
+// Execute runs the job, turning panics into errors unless the job was canceled. `CancellationPanic` is an error
+// interface, so any error (runtime errors included) matches it; the token has to have been canceled too.
+func (tj *trackedJob) Execute(ct *chronometer.CancellationToken) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			_, isRuntimeError := r.(runtime.Error)
+			if _, isCancellation := r.(chronometer.CancellationPanic); isCancellation && !isRuntimeError && isCanceled(ct) {
+				panic(r)
+			}
+			err = exception.Newf("panic: %v", r)
+		}
+	}()
+	return tj.Job.Execute(ct)
+}
+
+// isCanceled returns if a cancellation token has been signaled to cancel.
+func isCanceled(ct *chronometer.CancellationToken) (canceled bool) {
+	if ct == nil {
+		return false
+	}
+	defer func() {
+		canceled = recover() != nil
+	}()
+	ct.CheckCancellation()
+	return
+}
+
+// OnStart implements chronometer.OnStartReceiver.
+func (tj *trackedJob) OnStart() {
+	tj.lock.Lock()
+	tj.started = time.Now().UTC()
+	tj.lock.Unlock()
+
+	if receiver, isReceiver := tj.Job.(chronometer.OnStartReceiver); isReceiver {
+		receiver.OnStart()
+	}
+}
+
+// OnComplete implements chronometer.OnCompleteReceiver.
+func (tj *trackedJob) OnComplete(err error) {
+	if receiver, isReceiver := tj.Job.(chronometer.OnCompleteReceiver); isReceiver {
+		receiver.OnComplete(err)
+	}
+	tj.record(err)
+}
+
+// OnCancellation implements chronometer.OnCancellationReceiver.
+func (tj *trackedJob) OnCancellation() {
+	if receiver, isReceiver := tj.Job.(chronometer.OnCancellationReceiver); isReceiver {
+		receiver.OnCancellation()
+	}
+	tj.record(exception.New("canceled"))
+}
+
+// Schedule returns a schedule that asks the job for its schedule each time it's used, as the job manager only asks
+// when the job is loaded; this lets a `job.<name>.schedule` config entry apply to jobs that are already loaded.
+func (tj *trackedJob) Schedule() chronometer.Schedule {
+	return trackedSchedule{tj.Job}
+}
+
+// Status implements chronometer.StatusProvider.
+func (tj *trackedJob) Status() string {
+	if provider, isProvider := tj.Job.(chronometer.StatusProvider); isProvider {
+		return provider.Status()
+	}
+	return ""
+}
+
+// ShowMessages implements chronometer.ShowMessagesProvider.
+func (tj *trackedJob) ShowMessages() bool {
+	if provider, isProvider := tj.Job.(chronometer.ShowMessagesProvider); isProvider {
+		return provider.ShowMessages()
+	}
+	return true
+}
+
+func (tj *trackedJob) record(err error) {
+	tj.lock.Lock()
+	started := tj.started
+	tj.lock.Unlock()
+
+	run := core.JobRun{
+		Job:     tj.Name(),
+		Started: started,
+		Elapsed: time.Now().UTC().Sub(started),
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	tj.bot.Metrics().JobsRun.Inc(run.Job)
+	if err != nil {
+		tj.bot.Metrics().JobsFailed.Inc(run.Job)
+		tj.bot.Errors().Add(fmt.Sprintf("job %s", run.Job), err)
+	}
+	tj.bot.JobHistory().Add(run)
+
+	if err != nil {
+		tj.bot.notifyJobFailure(run)
+	}
+}
+
+// trackedSchedule is the current schedule of a job.
+type trackedSchedule struct {
+	job chronometer.Job
+}
+
+// GetNextRunTime implements chronometer.Schedule.
+func (ts trackedSchedule) GetNextRunTime(after *time.Time) *time.Time {
+	return ts.job.Schedule().GetNextRunTime(after)
+}
+
+// timeoutTrackedJob is a trackedJob for jobs that provide a timeout.
+type timeoutTrackedJob struct {
+	*trackedJob
+}
+
+// Timeout implements chronometer.TimeoutProvider.
+func (ttj *timeoutTrackedJob) Timeout() time.Duration {
+	return ttj.Job.(chronometer.TimeoutProvider).Timeout()
+}
@@ -0,0 +1,46 @@
+package jarvis
+
+import (
+	"os"
+
+	"github.com/blendlabs/go-exception"
+	"github.com/wcharczuk/jarvis/jarvis/modules"
+)
+
+const (
+	// DefaultWorkspaceName is the name of the workspace configured by environment variables.
+	DefaultWorkspaceName = "default"
+)
+
+// Workspace is the configuration for the bot in one slack workspace.
+type Workspace struct {
+	Name          string
+	Token         string
+	Configuration map[string]string
+}
+
+// WorkspaceFromEnvironment returns the workspace configured by environment variables.
+func WorkspaceFromEnvironment() (Workspace, error) {
+	envToken := os.Getenv(EnvironmentSlackAPIToken)
+	if len(envToken) == 0 {
+		return Workspace{}, exception.Newf("`%s` is empty, cannot start bot.", EnvironmentSlackAPIToken)
+	}
+	envModules := os.Getenv(modules.EnvironmentModules)
+	if len(envModules) == 0 {
+		envModules = "all"
+	}
+	return Workspace{
+		Name:          DefaultWorkspaceName,
+		Token:         envToken,
+		Configuration: map[string]string{modules.ConfigModules: envModules},
+	}, nil
+}
+
+// NewWorkspaceBot returns a new bot for a workspace; it still needs to be initialized and started.
+func NewWorkspaceBot(w Workspace) *Bot {
+	b := NewBot(w.Token)
+	for key, value := range w.Configuration {
+		b.SetConfig(key, value)
+	}
+	return b
+}
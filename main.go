@@ -1,18 +1,25 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/blendlabs/go-exception"
 	logger "github.com/blendlabs/go-logger"
 	"github.com/blendlabs/go-util"
 	"github.com/dlintw/goconf"
 	"github.com/wcharczuk/jarvis/jarvis"
+	"github.com/wcharczuk/jarvis/jarvis/api"
 	"github.com/wcharczuk/jarvis/jarvis/core"
+	"github.com/wcharczuk/jarvis/jarvis/dashboard"
 )
 
 func key() []byte {
@@ -25,6 +32,15 @@ func key() []byte {
 	return key
 }
 
+// pongTimeout returns how long a bot can go without a pong from slack before `/readyz` fails and the bot is
+// restarted, from `JARVIS_PONG_TIMEOUT` (in seconds).
+func pongTimeout() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("JARVIS_PONG_TIMEOUT")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return core.DefaultPongTimeout
+}
+
 func port() string {
 	envPort := os.Getenv("PORT")
 	if len(envPort) == 0 {
@@ -34,90 +50,120 @@ func port() string {
 }
 
 func main() {
-	var bots []*jarvis.Bot
 	var configFile = flag.String("config", "", "config file to read from")
+	flag.Parse()
+
+	supervisor := jarvis.NewSupervisor()
+	supervisor.SetLogger(logger.NewFromEnvironment())
+	supervisor.SetPongTimeout(pongTimeout())
+
 	if configFile != nil && len(*configFile) != 0 {
-		bots = initializeBotsFromConfig(*configFile)
+		workspaces, err := workspacesFromConfig(*configFile)
+		if err != nil {
+			fmt.Printf("error reading config: %v\n", err)
+			os.Exit(1)
+		}
+		supervisor.Reload(workspaces)
+		go reloadOnHangup(*configFile, supervisor)
 	} else {
-		bot, err := intializeBotFromEnvironment()
+		workspace, err := jarvis.WorkspaceFromEnvironment()
 		if err != nil {
 			fmt.Printf("Error Initializing Bot From Environment: %v\n", err)
 			os.Exit(1)
 		}
-
-		bots = []*jarvis.Bot{bot}
+		supervisor.Reload([]jarvis.Workspace{workspace})
 	}
 
-	startStatusServer(bots)
+	startStatusServer(supervisor)
 }
 
-func intializeBotFromEnvironment() (*jarvis.Bot, error) {
-	b, err := jarvis.NewBotFromEnvironment()
-	if err != nil {
-		return nil, err
-	}
-	err = b.Init()
-	if err != nil {
-		return nil, err
-	}
-	err = b.Start()
-	if err != nil {
-		return nil, err
+// reloadOnHangup re-reads the config file on SIGHUP, starting, stopping and restarting only the workspaces that changed.
+func reloadOnHangup(configPath string, supervisor *jarvis.Supervisor) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		workspaces, err := workspacesFromConfig(configPath)
+		if err != nil {
+			fmt.Printf("error reloading config, keeping the running workspaces: %v\n", err)
+			continue
+		}
+		supervisor.Reload(workspaces)
 	}
-	return b, nil
 }
 
-func initializeBotsFromConfig(configPath string) []*jarvis.Bot {
-	bots := []*jarvis.Bot{}
+// workspacesFromConfig reads a workspace from each section of the config file that has a `SLACK_API_TOKEN`.
+func workspacesFromConfig(configPath string) ([]jarvis.Workspace, error) {
 	config, err := goconf.ReadConfigFile(configPath)
 	if err != nil {
-		fmt.Printf("error reading config: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
+	workspaces := []jarvis.Workspace{}
 	for _, section := range config.GetSections() {
 		tokenRaw, err := config.GetString(section, "SLACK_API_TOKEN")
-		if err == nil {
-			decryptedToken, err := decryptValue(tokenRaw)
-			if err != nil {
-				fmt.Printf("error decrypting slack token: %v\n", err)
-				os.Exit(1)
-			}
-			j := jarvis.NewBot(decryptedToken)
-
-			options, _ := config.GetOptions(section)
-			for _, option := range options {
-				if value, err := config.GetString(section, option); err == nil {
-					decryptedValue, err := decryptValue(value)
-					if err == nil {
-						j.Configuration()[strings.ToUpper(option)] = decryptedValue
-					} else {
-						j.Configuration()[strings.ToUpper(option)] = value
-					}
+		if err != nil {
+			fmt.Printf("Error Reading `SLACK_API_TOKEN` for `%s`: %v\n", section, err)
+			continue
+		}
+		decryptedToken, err := decryptValue(tokenRaw)
+		if err != nil {
+			return nil, exception.Newf("error decrypting slack token for `%s`: %v", section, err)
+		}
+
+		workspace := jarvis.Workspace{Name: section, Token: decryptedToken, Configuration: map[string]string{}}
+		options, _ := config.GetOptions(section)
+		for _, option := range options {
+			if value, err := config.GetString(section, option); err == nil {
+				decryptedValue, err := decryptValue(value)
+				if err == nil {
+					workspace.Configuration[strings.ToUpper(option)] = decryptedValue
+				} else {
+					workspace.Configuration[strings.ToUpper(option)] = value
 				}
 			}
-
-			j.Init()
-			j.Start()
-			bots = append(bots, j)
-		} else {
-			fmt.Printf("Error Reading `SLACK_API_TOKEN`: %v\n", err)
 		}
+		workspaces = append(workspaces, workspace)
 	}
-	return bots
+	return workspaces, nil
 }
 
-func startStatusServer(bots []*jarvis.Bot) {
-	http.HandleFunc("/", injectBots(bots, statusHandler))
+func startStatusServer(supervisor *jarvis.Supervisor) {
+	http.HandleFunc("/", injectBots(supervisor, statusHandler))
+	http.HandleFunc("/jobs", injectBots(supervisor, jobsHandler))
+	http.HandleFunc("/audit", injectBots(supervisor, auditHandler))
+	http.HandleFunc("/metrics", injectBots(supervisor, metricsHandler))
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readyzHandler(supervisor, w, r)
+	})
+	http.Handle(api.Prefix+"/", api.New(coreBots(supervisor), os.Getenv(api.EnvironmentToken)).Handler())
+
+	dashboardToken := os.Getenv(dashboard.EnvironmentToken)
+	if len(dashboardToken) == 0 {
+		dashboardToken = os.Getenv(api.EnvironmentToken)
+	}
+	dashboardHandler := dashboard.New(coreBots(supervisor), dashboardToken).Handler()
+	http.Handle(dashboard.Prefix, dashboardHandler)
+	http.Handle(dashboard.Prefix+"/", dashboardHandler)
 	label := logger.ColorBlue.Apply("jarvis-cli")
 	ts := logger.ColorLightBlack.Apply(time.Now().UTC().Format(time.RFC3339))
 	fmt.Printf("%s - %s - starting status server, listening on: %s\n", label, ts, port())
 	http.ListenAndServe(":"+port(), nil)
 }
 
-func injectBots(bots []*jarvis.Bot, h botAwareHTTPHandlerFunc) http.HandlerFunc {
+func coreBots(supervisor *jarvis.Supervisor) func() []core.Bot {
+	return func() []core.Bot {
+		coreBots := []core.Bot{}
+		for _, bot := range supervisor.Bots() {
+			coreBots = append(coreBots, bot)
+		}
+		return coreBots
+	}
+}
+
+func injectBots(supervisor *jarvis.Supervisor, h botAwareHTTPHandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		h(bots, w, r)
+		h(supervisor.Bots(), w, r)
 	}
 }
 
@@ -126,12 +172,109 @@ type botAwareHTTPHandlerFunc func(bots []*jarvis.Bot, w http.ResponseWriter, r *
 func statusHandler(bots []*jarvis.Bot, w http.ResponseWriter, r *http.Request) {
 	for _, bot := range bots {
 		statusText := fmt.Sprintf("Jarvis is running and listening to the following channels (%s):\n", bot.OrganizationName())
-		for _, channelID := range bot.Client().ActiveChannels {
-			channel := bot.FindChannel(channelID)
-			statusText = statusText + fmt.Sprintf("> #%s (%s)\n", channel.Name, channel.ID)
+		for _, channelID := range bot.ActiveChannels() {
+			if channel := bot.FindChannel(channelID); channel != nil {
+				statusText = statusText + fmt.Sprintf("> #%s (%s)\n", channel.Name, channel.ID)
+			} else {
+				statusText = statusText + fmt.Sprintf("> %s\n", channelID)
+			}
 		}
 		statusText = statusText + "\n"
-		fmt.Fprintf(w, statusText)
+		fmt.Fprint(w, statusText)
+	}
+}
+
+type jobStatus struct {
+	Name       string        `json:"name"`
+	State      string        `json:"state"`
+	RunningFor string        `json:"running_for,omitempty"`
+	History    []core.JobRun `json:"history"`
+}
+
+type botJobs struct {
+	Organization string      `json:"organization"`
+	Jobs         []jobStatus `json:"jobs"`
+}
+
+func jobsHandler(bots []*jarvis.Bot, w http.ResponseWriter, r *http.Request) {
+	results := []botJobs{}
+	for _, bot := range bots {
+		result := botJobs{Organization: bot.OrganizationName(), Jobs: []jobStatus{}}
+		for _, status := range bot.JobManager().Status() {
+			result.Jobs = append(result.Jobs, jobStatus{
+				Name:       status.Name,
+				State:      status.State,
+				RunningFor: status.RunningFor,
+				History:    bot.JobHistory().Runs(status.Name),
+			})
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// healthzHandler reports that the process is up.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// readyzHandler reports each workspace's state and bot readiness as json; it fails with a 503 unless every
+// workspace's bot is running, connected, has had a pong from slack recently and is running its jobs.
+func readyzHandler(supervisor *jarvis.Supervisor, w http.ResponseWriter, r *http.Request) {
+	workspaces := supervisor.Status()
+	ready := len(workspaces) != 0
+	for _, workspace := range workspaces {
+		ready = ready && workspace.Readiness != nil && workspace.Readiness.Ready
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Ready      bool                     `json:"ready"`
+		Workspaces []jarvis.WorkspaceStatus `json:"workspaces"`
+	}{Ready: ready, Workspaces: workspaces})
+}
+
+// auditHandler writes the audit log entries for each bot as json, keyed by organization name.
+// The `user` query parameter filters to a user id and `limit` sets the number of entries (defaulting to 100).
+func auditHandler(bots []*jarvis.Bot, w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if limitValue := r.URL.Query().Get("limit"); len(limitValue) != 0 {
+		parsed, err := strconv.Atoi(limitValue)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %s", limitValue), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	userID := r.URL.Query().Get("user")
+
+	entries := map[string][]core.AuditEntry{}
+	for _, bot := range bots {
+		if len(userID) != 0 {
+			entries[bot.OrganizationName()] = bot.AuditLog().ByUser(userID, limit)
+		} else {
+			entries[bot.OrganizationName()] = bot.AuditLog().Recent(limit)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// metricsHandler writes each bot's metrics, and the shared external request metrics, in the prometheus text format.
+func metricsHandler(bots []*jarvis.Bot, w http.ResponseWriter, r *http.Request) {
+	collectors := []core.MetricsCollector{core.ExternalRequestMetrics()}
+	for _, bot := range bots {
+		collectors = append(collectors, bot)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := core.WriteMetrics(w, collectors...); err != nil {
+		fmt.Printf("error writing metrics: %v\n", err)
 	}
 }
 